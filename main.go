@@ -7,18 +7,26 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // SCHEDULE_TZ names must resolve in minimal images
 
 	"signal-sideband/pkg/ai"
 	"signal-sideband/pkg/api"
+	"signal-sideband/pkg/ask"
 	"signal-sideband/pkg/cerebro"
 	"signal-sideband/pkg/digest"
+	"signal-sideband/pkg/directory"
 	"signal-sideband/pkg/extract"
 	"signal-sideband/pkg/llm"
 	"signal-sideband/pkg/media"
+	"signal-sideband/pkg/redact"
+	"signal-sideband/pkg/schedule"
 	sig "signal-sideband/pkg/signal"
 	"signal-sideband/pkg/store"
+	"signal-sideband/pkg/usage"
 
 	"github.com/joho/godotenv"
 )
@@ -63,15 +71,84 @@ func main() {
 		defer storage.Close()
 	}
 
+	// Text search languages (first is the default; listing more enables per-message detection)
+	var searchLangs []string
+	for _, l := range strings.Split(os.Getenv("SEARCH_LANGUAGES"), ",") {
+		if l = strings.ToLower(strings.TrimSpace(l)); l != "" {
+			searchLangs = append(searchLangs, l)
+		}
+	}
+	if storage != nil {
+		kept, err := storage.SetSearchLanguages(ctx, searchLangs)
+		if err != nil {
+			log.Printf("Warning: %v. Using english text search.", err)
+			kept = nil
+		} else if len(kept) > 0 {
+			log.Printf("Text search languages: %s", strings.Join(kept, ", "))
+		}
+		searchLangs = kept
+	}
+
 	// 3. Setup Embedder
 	var embedder ai.Embedder
-	openAiKey := os.Getenv("OPENAI_API_KEY")
-	if openAiKey != "" {
-		log.Println("Using OpenAI for embeddings")
-		embedder = ai.NewOpenAIEmbedder(openAiKey)
+	embeddingKey := os.Getenv("EMBEDDING_API_KEY")
+	if embeddingKey == "" {
+		embeddingKey = os.Getenv("OPENAI_API_KEY")
+	}
+	embeddingDims, _ := strconv.Atoi(os.Getenv("EMBEDDING_DIMENSIONS"))
+	embedder, err = ai.NewEmbedder(ai.EmbedderConfig{
+		Provider:   os.Getenv("EMBEDDING_PROVIDER"),
+		BaseURL:    os.Getenv("EMBEDDING_BASE_URL"),
+		APIKey:     embeddingKey,
+		Model:      os.Getenv("EMBEDDING_MODEL"),
+		Dimensions: embeddingDims,
+	})
+	if err != nil {
+		log.Fatalf("Embedder setup failed: %v", err)
+	}
+	_, mockEmbedder := embedder.(*ai.MockEmbedder)
+	if mockEmbedder {
+		log.Println("Warning: no embedding provider configured. Using mock embedder.")
 	} else {
-		log.Println("Warning: OPENAI_API_KEY not set. Using mock embedder.")
-		embedder = &ai.MockEmbedder{}
+		log.Printf("Embeddings: %s (%d dims)", embedder.Model(), embedder.Dimensions())
+	}
+	if storage != nil {
+		// A vector that doesn't fit its column can't be stored or compared.
+		// The mock embedder's zero vectors are never stored.
+		if colDims, err := storage.EmbeddingColumnDimensions(ctx); err != nil {
+			log.Printf("Warning: could not read embedding column sizes: %v", err)
+		} else if !mockEmbedder {
+			for _, kind := range store.EmbeddingKinds {
+				if n := colDims[kind]; n > 0 && n != embedder.Dimensions() {
+					log.Fatalf("Embedder produces %d dims but %s.embedding is vector(%d); resize the column or set EMBEDDING_DIMENSIONS",
+						embedder.Dimensions(), kind, n)
+				}
+			}
+		}
+		storage.SetEmbeddingModel(embedder.Model())
+	}
+
+	// PII redaction in front of every external model call (REDACT_MODE=off disables)
+	var redactor *redact.Redactor
+	if mode := os.Getenv("REDACT_MODE"); mode != "off" {
+		patterns, err := redact.ParsePatterns(os.Getenv("REDACT_PATTERNS"), os.Getenv("REDACT_CUSTOM"))
+		if err != nil {
+			log.Printf("Warning: %v. Using default redaction patterns.", err)
+			patterns, _ = redact.ParsePatterns("", "")
+		}
+		redactor = redact.New(patterns, os.Getenv("REDACT_KEY"), mode == "audit")
+		if storage != nil {
+			// Refreshed after each contact sync and alias edit
+			if aliases, err := storage.ContactAliases(ctx); err != nil {
+				log.Printf("Warning: could not load contact aliases for redaction: %v", err)
+			} else {
+				redactor.SetAliases(aliases)
+			}
+		}
+		log.Printf("PII redaction enabled (%d patterns, audit=%v)", len(patterns), mode == "audit")
+	}
+	if !mockEmbedder {
+		embedder = redactor.Embedder(embedder)
 	}
 
 	// 4. Setup Signal REST API client
@@ -85,21 +162,31 @@ func main() {
 	}
 	signalAPI := sig.NewAPIClient(signalAPIURL, signalNumber)
 
-	// 5. Setup LLM provider (optional)
-	var llmProvider llm.Provider
-	var digestGen *digest.Generator
-	llmProviderName := os.Getenv("LLM_PROVIDER")
-	if llmProviderName != "" {
-		p, err := llm.NewProvider(llmProviderName)
+	// 5. Setup LLM providers (optional, per use case), metered by the usage ledger
+	var ledger *usage.Ledger
+	if storage != nil {
+		prices, err := usage.ParsePrices(os.Getenv("LLM_PRICES"))
 		if err != nil {
-			log.Printf("Warning: LLM provider setup failed: %v", err)
-		} else {
-			llmProvider = p
-			log.Printf("LLM provider: %s", llmProvider.Name())
+			log.Printf("Warning: %v. Using default prices.", err)
+			prices, _ = usage.ParsePrices("")
+		}
+		budgets, err := usage.ParseBudgets(os.Getenv("LLM_BUDGETS"))
+		if err != nil {
+			log.Printf("Warning: %v. Budgets disabled.", err)
+			budgets = nil
+		}
+		ledger = usage.NewLedger(storage, prices, budgets)
+	}
+
+	var digestGen *digest.Generator
+	if storage != nil {
+		if err := storage.SeedLenses(ctx, digest.BuiltinLenses()); err != nil {
+			log.Printf("Warning: failed to seed digest lenses: %v", err)
 		}
 	}
-	if llmProvider != nil && storage != nil {
-		digestGen = digest.NewGenerator(storage, llmProvider)
+	digestProvider := ledger.Meter(llm.UseDigest, redactor.Wrap(llm.UseDigest, newLLMProvider(llm.UseDigest)))
+	if digestProvider != nil && storage != nil {
+		digestGen = digest.NewGenerator(storage, digestProvider)
 	}
 
 	// 6. Group chat filter
@@ -135,38 +222,63 @@ func main() {
 	// Setup Gemini pic-of-the-day generator
 	var picGen *media.PicOfDayGenerator
 	if geminiKey := os.Getenv("GEMINI_API_KEY"); geminiKey != "" {
-		picGen = media.NewPicOfDayGenerator(geminiKey, mediaPath)
+		picGen = media.NewPicOfDayGenerator(geminiKey, mediaPath, ledger, redactor)
 		log.Println("Gemini PicOfDay generator enabled")
 	}
 
 	// Setup insights generator (needed by API and scheduler)
 	var insightsGen *digest.InsightsGenerator
-	if llmProvider != nil && storage != nil {
-		insightsGen = digest.NewInsightsGenerator(storage, llmProvider, picGen)
+	if p := ledger.Meter(llm.UseInsights, redactor.Wrap(llm.UseInsights, newLLMProvider(llm.UseInsights))); p != nil && storage != nil {
+		insightsGen = digest.NewInsightsGenerator(storage, p, picGen)
 	}
 
 	// Setup Cerebro knowledge graph
 	var cerebroExtractor *cerebro.Extractor
 	var cerebroEnricher *cerebro.Enricher
-	if llmProvider != nil && storage != nil {
-		cerebroExtractor = cerebro.NewExtractor(storage, llmProvider)
+	if p := ledger.Meter(llm.UseExtraction, redactor.Wrap(llm.UseExtraction, newLLMProvider(llm.UseExtraction))); p != nil && storage != nil {
+		cerebroExtractor = cerebro.NewExtractor(storage, p)
 	}
+	// LLM_PROVIDER=replay serves every LLM feature from cassettes (offline CI and demos)
+	replayAll := os.Getenv("LLM_PROVIDER") == "replay"
 	// Perplexity provider for enrichment
 	var perplexityProvider llm.Provider
-	if key := os.Getenv("PERPLEXITY_API_KEY"); key != "" {
-		perplexityProvider = llm.NewPerplexityProvider(key)
-		log.Println("Perplexity provider enabled for Cerebro enrichment")
+	if os.Getenv("PERPLEXITY_API_KEY") != "" || replayAll {
+		if p, err := llm.NewProvider(llm.UseEnrichment, enrichmentProvider("perplexity", replayAll)); err == nil {
+			perplexityProvider = ledger.Meter(llm.UseEnrichment, redactor.Wrap(llm.UseEnrichment, p))
+			log.Println("Perplexity provider enabled for Cerebro enrichment")
+		}
 	}
 	// Grok/XAI provider for enrichment (reuse XAI_API_KEY)
 	var grokProvider llm.Provider
-	if key := os.Getenv("XAI_API_KEY"); key != "" {
-		grokProvider = llm.NewXAIProvider(key)
+	if os.Getenv("XAI_API_KEY") != "" || replayAll {
+		if p, err := llm.NewProvider(llm.UseEnrichment, enrichmentProvider("xai", replayAll)); err == nil {
+			grokProvider = ledger.Meter(llm.UseEnrichment, redactor.Wrap(llm.UseEnrichment, p))
+		}
 	}
 	if storage != nil && (perplexityProvider != nil || grokProvider != nil) {
 		cerebroEnricher = cerebro.NewEnricher(storage, perplexityProvider, grokProvider)
 		log.Println("Cerebro enrichment enabled")
 	}
 
+	// Ask-the-archive (RAG over messages and cerebro concepts)
+	var asker *ask.Asker
+	if p := ledger.Meter(llm.UseAsk, redactor.Wrap(llm.UseAsk, newLLMProvider(llm.UseAsk))); p != nil && storage != nil {
+		asker = ask.NewAsker(storage, embedder, p)
+	}
+
+	// Link sender identities to persons; backfills messages stored before
+	// persons existed or while a resolve failed
+	if storage != nil {
+		if _, err := storage.EnsureSelf(ctx, signalNumber); err != nil {
+			log.Printf("Warning: failed to link own number: %v", err)
+		}
+		if n, err := storage.BackfillPersons(ctx); err != nil {
+			log.Printf("Warning: person backfill failed: %v", err)
+		} else if n > 0 {
+			log.Printf("Linked %d messages to persons", n)
+		}
+	}
+
 	// One-time purge of messages not matching the filter group
 	if storage != nil && filterGroupID != "" {
 		count, paths, err := storage.PurgeMessagesNotInGroup(ctx, filterGroupID)
@@ -183,7 +295,7 @@ func main() {
 	}
 
 	if storage != nil {
-		apiServer := api.NewServer(storage, embedder, digestGen, insightsGen, picGen, cerebroExtractor, cerebroEnricher, apiPort, authPassword, mediaPath, version, buildNumber, webDir)
+		apiServer := api.NewServer(storage, embedder, digestGen, insightsGen, picGen, cerebroExtractor, cerebroEnricher, asker, ledger, redactor, apiPort, authPassword, mediaPath, version, buildNumber, webDir)
 		go func() {
 			if err := apiServer.Start(); err != nil {
 				log.Printf("API server error: %v", err)
@@ -221,35 +333,68 @@ func main() {
 		mediaWorker := media.NewWorker(storage, downloader, 30*time.Second, mediaPath)
 		go mediaWorker.Start(ctx)
 
-		// AI vision analysis worker (requires XAI_API_KEY)
-		if xaiKey := os.Getenv("XAI_API_KEY"); xaiKey != "" {
-			analyzeWorker := media.NewAnalyzeWorker(storage, xaiKey, 60*time.Second, mediaPath)
+		// AI vision analysis worker (LLM_PROVIDER_VISION, or xAI when XAI_API_KEY is set)
+		if p := ledger.Meter(usage.FeatureVision, redactor.Wrap(usage.FeatureVision, newVisionProvider())); p != nil {
+			analyzeWorker := media.NewAnalyzeWorker(storage, p, 60*time.Second, mediaPath)
 			go analyzeWorker.Start(ctx)
 		}
 
+		// Embedding backfill worker (re-embeds missing, zero or outdated vectors)
+		if !mockEmbedder {
+			rpm, _ := strconv.Atoi(os.Getenv("EMBEDDING_BACKFILL_RPM"))
+			embeddingWorker := ai.NewEmbeddingWorker(storage, embedder, 5*time.Minute, rpm, 100)
+			go embeddingWorker.Start(ctx)
+		}
+
 		// Link preview worker
 		previewWorker := extract.NewPreviewWorker(storage, 60*time.Second)
 		go previewWorker.Start(ctx)
 
-		// Digest scheduler (daily at midnight)
+		// Scheduled jobs: cron expressions from SCHEDULE_* in SCHEDULE_TZ
+		loc := time.Local
+		if tz := os.Getenv("SCHEDULE_TZ"); tz != "" {
+			if l, err := time.LoadLocation(tz); err != nil {
+				log.Printf("Warning: invalid SCHEDULE_TZ %q: %v. Using %s.", tz, err, loc)
+			} else {
+				loc = l
+			}
+		}
+		scheduler := schedule.New(storage, loc)
 		if digestGen != nil {
-			scheduler := digest.NewScheduler(digestGen, insightsGen, 24*time.Hour)
-			go scheduler.Start(ctx)
+			addJob(scheduler, "SCHEDULE_DIGEST", "0 0 * * *", schedule.Job{
+				Name: "digest", Backfill: true, Run: digestGen.GenerateScheduled,
+			})
 		}
-
-		// Cerebro knowledge graph worker (every 6 hours)
+		if insightsGen != nil {
+			addJob(scheduler, "SCHEDULE_INSIGHTS", "15 0 * * *", schedule.Job{
+				Name: "insights", Run: insightsGen.GenerateScheduledInsights,
+			})
+			if picGen != nil {
+				addJob(scheduler, "SCHEDULE_POTD", "30 0 * * *", schedule.Job{
+					Name: "potd", Run: insightsGen.GenerateScheduledPics,
+				})
+			}
+		}
+		cerebroWorker := cerebro.NewWorker(cerebroExtractor, cerebroEnricher)
 		if cerebroExtractor != nil {
-			cerebroWorker := cerebro.NewWorker(cerebroExtractor, cerebroEnricher, 6*time.Hour)
-			go cerebroWorker.Start(ctx)
-			log.Println("Cerebro knowledge graph worker started")
+			addJob(scheduler, "SCHEDULE_CEREBRO", "0 */6 * * *", schedule.Job{
+				Name: "cerebro",
+				Run:  func(ctx context.Context, _ time.Time) error { return cerebroWorker.Extract(ctx) },
+			})
+		}
+		if cerebroEnricher != nil {
+			addJob(scheduler, "SCHEDULE_ENRICHMENT", "30 */6 * * *", schedule.Job{
+				Name: "enrichment",
+				Run:  func(ctx context.Context, _ time.Time) error { return cerebroWorker.Enrich(ctx) },
+			})
 		}
+		go scheduler.Start(ctx)
 	}
 
-	// 9. Initial group sync
+	// 9. Contact and group sync (names, numbers, avatars), hourly
 	if storage != nil {
-		go func() {
-			syncGroups(ctx, signalAPI, storage)
-		}()
+		directoryWorker := directory.NewWorker(storage, signalAPI, media.NewDownloader(signalAPI, mediaPath), redactor, time.Hour)
+		go directoryWorker.Start(ctx)
 	}
 
 	// 10. Connect Signal WebSocket (non-fatal: retries in background)
@@ -267,7 +412,7 @@ func main() {
 			log.Println("Signal connected")
 			// Read messages until disconnected
 			for msg := range client.Messages() {
-				handleMessage(ctx, msg, storage, embedder, signalAPI, filterGroupID)
+				handleMessage(ctx, msg, storage, embedder, signalAPI, filterGroupID, searchLangs)
 			}
 			// If we get here, the channel closed — reconnect
 			select {
@@ -289,26 +434,57 @@ func main() {
 	cancel()
 }
 
-func syncGroups(ctx context.Context, api *sig.APIClient, storage *store.Store) {
-	groups, err := api.ListGroups()
+// newLLMProvider returns the provider configured for useCase, or nil when none
+// is configured or setup fails.
+func newLLMProvider(useCase string) llm.Provider {
+	if llm.ProviderName(useCase) == "" {
+		return nil
+	}
+	p, err := llm.NewProvider(useCase, "")
 	if err != nil {
-		log.Printf("Group sync failed: %v", err)
-		return
+		log.Printf("Warning: LLM provider setup for %s failed: %v", useCase, err)
+		return nil
 	}
-	for _, g := range groups {
-		if err := storage.UpsertGroup(ctx, store.GroupRecord{
-			GroupID:     g.ID,
-			Name:        g.Name,
-			Description: g.Description,
-			MemberCount: len(g.Members),
-		}); err != nil {
-			log.Printf("Group sync upsert failed for %s: %v", g.Name, err)
-		}
+	if m, ok := llm.Base(p).(interface{ Model() string }); ok {
+		log.Printf("LLM provider for %s: %s (%s)", useCase, p.Name(), m.Model())
+	} else {
+		log.Printf("LLM provider for %s: %s", useCase, p.Name())
+	}
+	return p
+}
+
+// newVisionProvider returns the provider for image analysis. Unlike the other
+// use cases it does not fall back to LLM_PROVIDER, whose model may not accept
+// images; xAI is used when only XAI_API_KEY is set.
+func newVisionProvider() llm.Provider {
+	name := os.Getenv("LLM_PROVIDER_VISION")
+	if name == "" && os.Getenv("LLM_PROVIDER") == "replay" {
+		name = "replay"
+	}
+	if name == "" && os.Getenv("XAI_API_KEY") != "" {
+		name = "xai"
+	}
+	if name == "" {
+		return nil
+	}
+	p, err := llm.NewProvider(llm.UseVision, name)
+	if err != nil {
+		log.Printf("Warning: vision provider setup failed: %v", err)
+		return nil
+	}
+	return p
+}
+
+// enrichmentProvider returns name, or the replay provider when every
+// feature is being replayed.
+func enrichmentProvider(name string, replay bool) string {
+	if replay {
+		return "replay"
 	}
-	log.Printf("Synced %d groups", len(groups))
+	return name
 }
 
-func handleMessage(ctx context.Context, msg sig.SignalMessage, storage *store.Store, embedder ai.Embedder, signalAPI *sig.APIClient, filterGroupID string) {
+func handleMessage(ctx context.Context, msg sig.SignalMessage, storage *store.Store, embedder ai.Embedder, signalAPI *sig.APIClient, filterGroupID string, searchLangs []string) {
 	var content string
 	var expiresAt *time.Time
 	var sender string
@@ -399,7 +575,7 @@ func handleMessage(ctx context.Context, msg sig.SignalMessage, storage *store.St
 	var embedding []float32
 	if content != "" {
 		var err error
-		embedding, err = embedder.Embed(content)
+		embedding, err = embedder.Embed(ctx, content)
 		if err != nil {
 			log.Printf("Embedding error: %v", err)
 		}
@@ -415,6 +591,7 @@ func handleMessage(ctx context.Context, msg sig.SignalMessage, storage *store.St
 		SenderID:       sender,
 		Content:        content,
 		Embedding:      embedding,
+		EmbeddingModel: embedder.Model(),
 		ExpiresAt:      expiresAt,
 		GroupID:        groupID,
 		SourceUUID:     sourceUUID,
@@ -423,6 +600,14 @@ func handleMessage(ctx context.Context, msg sig.SignalMessage, storage *store.St
 		HasAttachments: hasAttachments,
 		RawJSON:        rawJSON,
 	}
+	if personID, err := storage.ResolvePerson(ctx, sender, sourceUUID, isOutgoing); err != nil {
+		log.Printf("Person resolve error: %v", err)
+	} else if personID != "" {
+		record.PersonID = &personID
+	}
+	if content != "" && len(searchLangs) > 1 {
+		record.TSConfig = extract.DetectLanguage(content, searchLangs)
+	}
 
 	messageID, err := storage.SaveMessage(ctx, record)
 	if err != nil {
@@ -460,16 +645,30 @@ func handleMessage(ctx context.Context, msg sig.SignalMessage, storage *store.St
 		}
 	}
 
-	// Upsert group if present
-	if groupID != nil && dataMsg.GroupInfo != nil {
-		_ = storage.UpsertGroup(ctx, store.GroupRecord{
-			GroupID: *groupID,
-		})
+	// Record the group if it's new; the directory worker fills in its details
+	if groupID != nil {
+		if err := storage.EnsureGroup(ctx, *groupID); err != nil {
+			log.Printf("Group save error: %v", err)
+		}
 	}
 
 	log.Println("Message stored.")
 }
 
+// addJob schedules j with the cron expression in env, or def when env is
+// unset or invalid.
+func addJob(s *schedule.Scheduler, env, def string, j schedule.Job) {
+	j.Spec = os.Getenv(env)
+	if j.Spec == "" {
+		j.Spec = def
+	}
+	if err := s.Add(j); err != nil {
+		log.Printf("Warning: %s: %v. Using %q.", env, err, def)
+		j.Spec = def
+		_ = s.Add(j)
+	}
+}
+
 func truncate(s string, n int) string {
 	if len(s) <= n {
 		return s
@@ -0,0 +1,22 @@
+-- 009_search_language.sql
+-- Per-message text-search configuration + trigram fuzzy matching
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Text-search configuration (regconfig name) used to build each message's tsv
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS ts_config text NOT NULL DEFAULT 'english';
+
+CREATE OR REPLACE FUNCTION messages_tsv_trigger() RETURNS trigger AS $$
+BEGIN
+    NEW.tsv := to_tsvector(COALESCE(NULLIF(NEW.ts_config, ''), 'english')::regconfig, COALESCE(NEW.content, ''));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_messages_tsv ON messages;
+CREATE TRIGGER trg_messages_tsv
+    BEFORE INSERT OR UPDATE OF content, ts_config ON messages
+    FOR EACH ROW EXECUTE FUNCTION messages_tsv_trigger();
+
+-- Trigram index for fuzzy fallback when the tsquery finds nothing
+CREATE INDEX IF NOT EXISTS idx_messages_content_trgm ON messages USING GIN (content gin_trgm_ops);
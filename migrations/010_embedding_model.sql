@@ -0,0 +1,16 @@
+-- 010_embedding_model.sql
+-- Record which model produced each message embedding; drop placeholder vectors
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS embedding_model text;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS embedding_dim int;
+
+-- Zero vectors written by the mock embedder match nothing; clear them so the
+-- backfill worker re-embeds those messages.
+UPDATE messages SET embedding = NULL, embedding_model = NULL, embedding_dim = NULL
+WHERE embedding IS NOT NULL AND vector_norm(embedding) = 0;
+
+-- Everything embedded before this migration came from ada-002
+UPDATE messages SET embedding_model = 'text-embedding-ada-002', embedding_dim = vector_dims(embedding)
+WHERE embedding IS NOT NULL AND embedding_model IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_embedding_model ON messages (embedding_model);
@@ -0,0 +1,13 @@
+-- 011_media_link_embeddings.sql
+-- Embeddings for vision analyses and link previews (semantic media/link search)
+
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS embedding vector(1536);
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS embedding_model text;
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS embedding_dim int;
+
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS embedding vector(1536);
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS embedding_model text;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS embedding_dim int;
+
+CREATE INDEX IF NOT EXISTS idx_attachments_embedding_model ON attachments (embedding_model);
+CREATE INDEX IF NOT EXISTS idx_urls_embedding_model ON urls (embedding_model);
@@ -0,0 +1,17 @@
+-- 012_llm_usage.sql
+-- Ledger of every LLM / vision / image-generation call for cost tracking and budgets
+
+CREATE TABLE IF NOT EXISTS llm_usage (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  feature text NOT NULL,
+  provider text NOT NULL DEFAULT '',
+  model text NOT NULL DEFAULT '',
+  input_tokens int NOT NULL DEFAULT 0,
+  output_tokens int NOT NULL DEFAULT 0,
+  cost_usd numeric(12,6) NOT NULL DEFAULT 0,
+  success boolean NOT NULL DEFAULT true,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_feature_created ON llm_usage (feature, created_at);
+CREATE INDEX IF NOT EXISTS idx_llm_usage_created ON llm_usage (created_at);
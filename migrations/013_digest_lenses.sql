@@ -0,0 +1,13 @@
+-- 013_digest_lenses.sql
+-- User-defined digest lenses (persona prompts). Built-ins are seeded at startup.
+
+CREATE TABLE IF NOT EXISTS digest_lenses (
+  name text PRIMARY KEY,
+  label text NOT NULL DEFAULT '',
+  system_prompt text NOT NULL,
+  temperature real NOT NULL DEFAULT 0.3,
+  schema_variant text NOT NULL DEFAULT 'standard',
+  builtin boolean NOT NULL DEFAULT false,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now()
+);
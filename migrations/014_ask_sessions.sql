@@ -0,0 +1,26 @@
+-- 014_ask_sessions.sql
+-- Ask-the-archive conversations: a session groups follow-up questions, each
+-- turn keeps the answer and the message IDs it cites.
+
+CREATE TABLE IF NOT EXISTS ask_sessions (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  title text NOT NULL DEFAULT '',
+  group_id text,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS ask_turns (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  session_id uuid NOT NULL REFERENCES ask_sessions(id) ON DELETE CASCADE,
+  question text NOT NULL,
+  answer text NOT NULL DEFAULT '',
+  citations jsonb NOT NULL DEFAULT '[]',
+  llm_provider text NOT NULL DEFAULT '',
+  llm_model text NOT NULL DEFAULT '',
+  token_count int NOT NULL DEFAULT 0,
+  created_at timestamptz DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ask_turns_session ON ask_turns (session_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_ask_sessions_updated ON ask_sessions (updated_at DESC);
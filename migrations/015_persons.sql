@@ -0,0 +1,30 @@
+-- 015_persons.sql
+-- One row per real person. A sender shows up as a phone number, a Signal
+-- UUID or (for the account owner's own messages) the literal "self"; every
+-- identifier is linked to its person so stats count people, not IDs.
+
+CREATE TABLE IF NOT EXISTS persons (
+  id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  is_self boolean NOT NULL DEFAULT false,
+  created_at timestamptz DEFAULT now(),
+  updated_at timestamptz DEFAULT now()
+);
+
+-- At most one person is the account owner
+CREATE UNIQUE INDEX IF NOT EXISTS idx_persons_self ON persons (is_self) WHERE is_self;
+
+-- kind is 'phone', 'uuid' or 'self'
+CREATE TABLE IF NOT EXISTS person_identifiers (
+  kind text NOT NULL,
+  value text NOT NULL,
+  person_id uuid NOT NULL REFERENCES persons(id) ON DELETE CASCADE,
+  created_at timestamptz DEFAULT now(),
+  PRIMARY KEY (kind, value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_person_identifiers_person ON person_identifiers (person_id);
+
+-- Filled on ingest; older rows are backfilled at startup
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS person_id uuid REFERENCES persons(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_messages_person ON messages (person_id, created_at);
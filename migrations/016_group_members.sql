@@ -0,0 +1,34 @@
+-- 016_group_members.sql
+-- Group membership as seen by the periodic group sync. joined_at is when the
+-- sync first saw the member (or saw them return); left_at is set when they
+-- drop out of the member list.
+
+CREATE TABLE IF NOT EXISTS group_members (
+  group_id text NOT NULL REFERENCES groups(group_id) ON DELETE CASCADE,
+  member_id text NOT NULL,  -- phone number, or UUID when signal-cli has no number
+  person_id uuid REFERENCES persons(id) ON DELETE SET NULL,
+  is_admin boolean NOT NULL DEFAULT false,
+  joined_at timestamptz NOT NULL DEFAULT now(),
+  left_at timestamptz,
+  updated_at timestamptz DEFAULT now(),
+  PRIMARY KEY (group_id, member_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_group_members_person ON group_members (person_id);
+
+-- One-time data fixes. Migrations run on every deploy, so a fix that must not
+-- repeat records its name here and only runs while the row is missing.
+CREATE TABLE IF NOT EXISTS data_fixes (
+  name text PRIMARY KEY,
+  applied_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Group sync used to key rows by the REST API's "group.<base64>" ID while
+-- messages carry the internal ID, leaving a duplicate row per group. The
+-- sync now uses the internal ID only; drop the old rows once.
+WITH fix AS (
+  INSERT INTO data_fixes (name) VALUES ('016_drop_rest_group_ids')
+  ON CONFLICT DO NOTHING
+  RETURNING name
+)
+DELETE FROM groups WHERE group_id LIKE 'group.%' AND EXISTS (SELECT 1 FROM fix);
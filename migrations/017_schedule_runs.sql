@@ -0,0 +1,12 @@
+-- 017_schedule_runs.sql
+-- Last run of each scheduled job, so a restart neither repeats a run that
+-- already happened nor skips one that was due while the process was down.
+
+CREATE TABLE IF NOT EXISTS schedule_runs (
+  job text PRIMARY KEY,
+  scheduled_for timestamptz NOT NULL,  -- the occurrence that last ran
+  started_at timestamptz NOT NULL,
+  finished_at timestamptz NOT NULL,
+  last_error text NOT NULL DEFAULT '',
+  updated_at timestamptz DEFAULT now()
+);
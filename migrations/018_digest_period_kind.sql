@@ -0,0 +1,21 @@
+-- 018_digest_period_kind.sql
+-- Digest period kinds: scheduled daily digests, weekly/monthly rollups built
+-- from them, and custom ranges requested through the API.
+
+ALTER TABLE digests ADD COLUMN IF NOT EXISTS period_kind text NOT NULL DEFAULT 'custom';
+
+-- Earlier scheduled digests covered one whole day (00:00 to 23:59:59) across
+-- all groups. Relabel them once, on the deploy that adds the column: later
+-- custom digests can cover exactly one day too, and this file runs on every
+-- deploy.
+WITH fix AS (
+  INSERT INTO data_fixes (name) VALUES ('018_label_daily_digests')
+  ON CONFLICT DO NOTHING
+  RETURNING name
+)
+UPDATE digests SET period_kind = 'daily'
+WHERE period_kind = 'custom' AND group_id IS NULL
+  AND period_end - period_start = interval '23 hours 59 minutes 59 seconds'
+  AND EXISTS (SELECT 1 FROM fix);
+
+CREATE INDEX IF NOT EXISTS idx_digests_period_kind ON digests (period_kind, period_start);
@@ -0,0 +1,8 @@
+-- 019_insight_groups.sql
+-- Scope daily insights (and their snapshots) to a group. Rows without a
+-- group cover all groups, as every insight did before.
+
+ALTER TABLE daily_insights ADD COLUMN IF NOT EXISTS group_id text;
+
+CREATE INDEX IF NOT EXISTS idx_daily_insights_group ON daily_insights (group_id, snapshot_date DESC);
+CREATE INDEX IF NOT EXISTS idx_digests_group ON digests (group_id, period_kind, period_start);
@@ -0,0 +1,6 @@
+-- 020_embedding_rejected.sql
+-- Remember rows the embedding provider refused so they stop blocking the backfill
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS embedding_rejected_model text;
+ALTER TABLE attachments ADD COLUMN IF NOT EXISTS embedding_rejected_model text;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS embedding_rejected_model text;
@@ -0,0 +1,5 @@
+-- 021_contact_avatar_updated.sql
+-- The Signal profile version (last_updated_timestamp, ms) each contact avatar
+-- was downloaded at, so the sync only fetches pictures that changed.
+
+ALTER TABLE contacts ADD COLUMN IF NOT EXISTS avatar_updated_ms bigint;
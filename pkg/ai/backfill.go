@@ -0,0 +1,168 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"signal-sideband/pkg/store"
+)
+
+// EmbeddingWorker embeds messages, media analyses and link previews whose
+// vectors are missing, zero, or from an older model. Batches are embedded in
+// one request each and paced so a model switch can backfill the whole archive
+// without tripping provider rate limits.
+type EmbeddingWorker struct {
+	store     *store.Store
+	embedder  Embedder
+	interval  time.Duration
+	pace      time.Duration
+	batchSize int
+}
+
+// NewEmbeddingWorker creates a worker that checks for work every interval and
+// makes at most perMinute batch requests per minute while draining it.
+func NewEmbeddingWorker(s *store.Store, e Embedder, interval time.Duration, perMinute, batchSize int) *EmbeddingWorker {
+	if perMinute <= 0 {
+		perMinute = 60
+	}
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	return &EmbeddingWorker{
+		store:     s,
+		embedder:  e,
+		interval:  interval,
+		pace:      time.Minute / time.Duration(perMinute),
+		batchSize: batchSize,
+	}
+}
+
+func (w *EmbeddingWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	log.Printf("Embedding worker started (model %s)", w.embedder.Model())
+	for _, kind := range store.EmbeddingKinds {
+		if n, err := w.store.CountNeedingEmbedding(ctx, kind, w.embedder.Model()); err == nil && n > 0 {
+			log.Printf("Embedding worker: %d %s need (re-)embedding", n, kind)
+		}
+	}
+	w.process(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Embedding worker stopped")
+			return
+		case <-ticker.C:
+			w.process(ctx)
+		}
+	}
+}
+
+func (w *EmbeddingWorker) process(ctx context.Context) {
+	for _, kind := range store.EmbeddingKinds {
+		if ctx.Err() != nil {
+			return
+		}
+		w.processKind(ctx, kind)
+	}
+}
+
+// processKind drains one kind's backlog batch by batch until it is empty or a
+// request fails.
+func (w *EmbeddingWorker) processKind(ctx context.Context, kind string) {
+	model := w.embedder.Model()
+	total := 0
+	seen := make(map[string]bool)
+	defer func() {
+		if total > 0 {
+			log.Printf("Embedding worker: embedded %d %s with %s", total, kind, model)
+		}
+	}()
+
+	for {
+		jobs, err := w.store.GetEmbeddingJobs(ctx, kind, model, w.batchSize)
+		if err != nil {
+			log.Printf("Embedding worker: fetch error: %v", err)
+			return
+		}
+		if len(jobs) == 0 {
+			return
+		}
+
+		var texts []string
+		for _, j := range jobs {
+			// Anything returned twice in one pass was not stored (e.g. the
+			// embedder produced a zero vector); leave it for the next tick.
+			if seen[j.ID] {
+				return
+			}
+			seen[j.ID] = true
+			texts = append(texts, j.Content)
+		}
+
+		vecs, err := w.embedder.EmbedBatch(ctx, texts)
+		if err != nil && Rejected(err) {
+			log.Printf("Embedding worker: batch of %d %s rejected (%v), embedding one at a time", len(texts), kind, err)
+			n, err := w.embedEach(ctx, kind, model, jobs)
+			total += n
+			if err != nil {
+				log.Printf("Embedding worker: %v", err)
+				return
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("Embedding worker: embed batch of %d failed: %v", len(texts), err)
+			return
+		}
+		for i, j := range jobs {
+			if err := w.store.SetEmbedding(ctx, kind, j.ID, vecs[i], model); err != nil {
+				log.Printf("Embedding worker: update %s failed: %v", j.ID, err)
+				return
+			}
+			total++
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.pace):
+		}
+	}
+}
+
+// embedEach embeds jobs one request at a time after the provider rejected
+// their batch. Rows it still rejects are marked so later passes skip them
+// instead of retrying the same batch forever; any other failure stops the
+// pass. It returns how many rows were embedded.
+func (w *EmbeddingWorker) embedEach(ctx context.Context, kind, model string, jobs []store.EmbeddingJob) (int, error) {
+	n := 0
+	for _, j := range jobs {
+		vec, err := w.embedder.Embed(ctx, j.Content)
+		if err != nil {
+			if !Rejected(err) {
+				return n, fmt.Errorf("embed %s %s failed: %w", kind, j.ID, err)
+			}
+			log.Printf("Embedding worker: %s rejected %s %s, skipping: %v", model, kind, j.ID, err)
+			if err := w.store.RejectEmbedding(ctx, kind, j.ID, model); err != nil {
+				return n, fmt.Errorf("mark %s rejected failed: %w", j.ID, err)
+			}
+			continue
+		}
+		if err := w.store.SetEmbedding(ctx, kind, j.ID, vec, model); err != nil {
+			return n, fmt.Errorf("update %s failed: %w", j.ID, err)
+		}
+		n++
+
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case <-time.After(w.pace):
+		}
+	}
+	return n, nil
+}
@@ -1,12 +1,37 @@
 package ai
 
+import "context"
+
+// EmbeddingDimensions matches the default messages.embedding vector(1536)
+// column. Models that support shortened output are asked for this size.
+const EmbeddingDimensions = 1536
+
 type Embedder interface {
-	Embed(text string) ([]float32, error)
+	Embed(ctx context.Context, text string) ([]float32, error)
+	// EmbedBatch embeds texts in as few requests as the provider allows,
+	// returning one vector per input in the same order.
+	EmbedBatch(ctx context.Context, texts []string) ([][]float32, error)
+	// Model identifies the model that produced the vectors; stored alongside
+	// each embedding so vectors from different models are never compared.
+	Model() string
+	Dimensions() int
 }
 
 type MockEmbedder struct{}
 
-func (m *MockEmbedder) Embed(text string) ([]float32, error) {
+func (m *MockEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
 	// Return a zero vector of 1536 dimensions for testing
-	return make([]float32, 1536), nil
+	return make([]float32, EmbeddingDimensions), nil
+}
+
+func (m *MockEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i := range texts {
+		out[i] = make([]float32, EmbeddingDimensions)
+	}
+	return out, nil
 }
+
+func (m *MockEmbedder) Model() string { return "mock" }
+
+func (m *MockEmbedder) Dimensions() int { return EmbeddingDimensions }
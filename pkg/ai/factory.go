@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EmbedderConfig selects and configures an embedding provider.
+type EmbedderConfig struct {
+	Provider   string // openai, compatible, ollama or mock
+	BaseURL    string
+	APIKey     string
+	Model      string
+	Dimensions int
+}
+
+// NewEmbedder builds the embedder described by cfg. An empty provider means
+// OpenAI when an API key is set, otherwise the mock embedder.
+func NewEmbedder(cfg EmbedderConfig) (Embedder, error) {
+	provider := strings.ToLower(cfg.Provider)
+	if provider == "" {
+		provider = "openai"
+		if cfg.APIKey == "" {
+			provider = "mock"
+		}
+	}
+
+	switch provider {
+	case "openai":
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("openai embeddings require OPENAI_API_KEY or EMBEDDING_API_KEY")
+		}
+		if cfg.BaseURL != "" {
+			return NewCompatibleEmbedder(cfg.BaseURL, cfg.APIKey, modelOrDefault(cfg.Model), cfg.Dimensions), nil
+		}
+		e := NewOpenAIEmbedder(cfg.APIKey, cfg.Model)
+		if cfg.Dimensions > 0 && cfg.Dimensions != e.dims {
+			// Only the v3 models accept the dimensions parameter
+			if !e.shorten {
+				return nil, fmt.Errorf("%s always produces %d dimensions; EMBEDDING_DIMENSIONS=%d needs a text-embedding-3 model",
+					e.model, e.dims, cfg.Dimensions)
+			}
+			e.dims = cfg.Dimensions
+		}
+		return e, nil
+	case "compatible":
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("compatible embeddings require EMBEDDING_BASE_URL")
+		}
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("compatible embeddings require EMBEDDING_MODEL")
+		}
+		return NewCompatibleEmbedder(cfg.BaseURL, cfg.APIKey, cfg.Model, cfg.Dimensions), nil
+	case "ollama":
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("ollama embeddings require EMBEDDING_MODEL")
+		}
+		return NewOllamaEmbedder(cfg.BaseURL, cfg.Model, cfg.Dimensions), nil
+	case "mock":
+		return &MockEmbedder{}, nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s (supported: openai, compatible, ollama, mock)", cfg.Provider)
+	}
+}
+
+func modelOrDefault(model string) string {
+	if model == "" {
+		return DefaultEmbeddingModel
+	}
+	return model
+}
@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOllamaURL is where a local Ollama server listens by default.
+const DefaultOllamaURL = "http://localhost:11434"
+
+// OllamaEmbedder calls Ollama's native /api/embeddings endpoint, which takes
+// one prompt per request.
+type OllamaEmbedder struct {
+	baseURL string
+	model   string
+	dims    int
+	client  *http.Client
+}
+
+func NewOllamaEmbedder(baseURL, model string, dims int) *OllamaEmbedder {
+	if baseURL == "" {
+		baseURL = DefaultOllamaURL
+	}
+	if dims <= 0 {
+		dims = EmbeddingDimensions
+	}
+	return &OllamaEmbedder{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		model:   model,
+		dims:    dims,
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+type ollamaEmbeddingRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+}
+
+type ollamaEmbeddingResponse struct {
+	Embedding []float32 `json:"embedding"`
+}
+
+func (o *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	body, err := json.Marshal(ollamaEmbeddingRequest{Model: o.model, Prompt: truncateInput(text, maxInputTokens)})
+	if err != nil {
+		return nil, err
+	}
+
+	var vec []float32
+	err = withRetry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/embeddings", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := o.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return &statusError{code: resp.StatusCode, msg: strings.TrimSpace(string(msg))}
+		}
+
+		var out ollamaEmbeddingResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+		vec = out.Embedding
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ollama embedding error: %w", err)
+	}
+	if len(vec) != o.dims {
+		return nil, fmt.Errorf("ollama embedding has %d dimensions, want %d", len(vec), o.dims)
+	}
+	return vec, nil
+}
+
+// EmbedBatch embeds sequentially; the native endpoint has no batch form.
+func (o *OllamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vec, err := o.Embed(ctx, t)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = vec
+	}
+	return out, nil
+}
+
+func (o *OllamaEmbedder) Model() string { return o.model }
+
+func (o *OllamaEmbedder) Dimensions() int { return o.dims }
+
+// statusError is a non-2xx response from a plain HTTP embedding endpoint.
+type statusError struct {
+	code int
+	msg  string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.code, e.msg)
+}
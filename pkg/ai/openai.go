@@ -2,40 +2,212 @@ package ai
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	openai "github.com/sashabaranov/go-openai"
 )
 
+// DefaultEmbeddingModel is used when EMBEDDING_MODEL is not set.
+const DefaultEmbeddingModel = string(openai.AdaEmbeddingV2)
+
+// OpenAI accepts at most 2048 inputs and ~300k tokens per embeddings request,
+// and 8191 tokens per input.
+const (
+	maxBatchInputs = 2048
+	maxBatchTokens = 250000
+	maxInputTokens = 8000
+	maxRetries     = 4
+)
+
 type OpenAIEmbedder struct {
 	client *openai.Client
+	model  string
+	dims   int
+	// shorten asks the API for dims-sized vectors (OpenAI v3 models only;
+	// most compatible servers reject the parameter).
+	shorten bool
 }
 
-func NewOpenAIEmbedder(apiKey string) *OpenAIEmbedder {
+func NewOpenAIEmbedder(apiKey, model string) *OpenAIEmbedder {
 	if apiKey == "" {
 		apiKey = os.Getenv("OPENAI_API_KEY")
 	}
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	return &OpenAIEmbedder{
+		client:  openai.NewClient(apiKey),
+		model:   model,
+		dims:    EmbeddingDimensions,
+		shorten: strings.HasPrefix(model, "text-embedding-3"),
+	}
+}
+
+// NewCompatibleEmbedder targets any server implementing the OpenAI
+// /v1/embeddings API (vLLM, LM Studio, llama.cpp, Ollama's /v1, ...).
+// dims is the vector size the model produces.
+func NewCompatibleEmbedder(baseURL, apiKey, model string, dims int) *OpenAIEmbedder {
+	cfg := openai.DefaultConfig(apiKey)
+	cfg.BaseURL = strings.TrimRight(baseURL, "/")
+	if dims <= 0 {
+		dims = EmbeddingDimensions
+	}
 	return &OpenAIEmbedder{
-		client: openai.NewClient(apiKey),
+		client: openai.NewClientWithConfig(cfg),
+		model:  model,
+		dims:   dims,
 	}
 }
 
-func (o *OpenAIEmbedder) Embed(text string) ([]float32, error) {
-	ctx := context.Background()
+func (o *OpenAIEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vecs, err := o.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+func (o *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for _, chunk := range chunkTexts(texts, maxBatchInputs, maxBatchTokens, maxInputTokens) {
+		vecs, err := o.embedChunk(ctx, chunk)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
+
+func (o *OpenAIEmbedder) embedChunk(ctx context.Context, texts []string) ([][]float32, error) {
 	req := openai.EmbeddingRequest{
-		Input: []string{text},
-		Model: openai.AdaEmbeddingV2,
+		Input: texts,
+		Model: openai.EmbeddingModel(o.model),
+	}
+	// ada-002 has a fixed size; the v3 models can be shortened to fit the column
+	if o.shorten {
+		req.Dimensions = o.dims
 	}
 
-	resp, err := o.client.CreateEmbeddings(ctx, req)
+	var resp openai.EmbeddingResponse
+	err := withRetry(ctx, func() error {
+		var err error
+		resp, err = o.client.CreateEmbeddings(ctx, req)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("openai embedding error: %w", err)
 	}
 
-	if len(resp.Data) == 0 {
-		return nil, fmt.Errorf("openai returned empty embedding data")
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("openai returned %d embeddings for %d inputs", len(resp.Data), len(texts))
+	}
+	vecs := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, fmt.Errorf("openai returned embedding index %d out of range", d.Index)
+		}
+		if n := len(d.Embedding); n != o.dims {
+			return nil, fmt.Errorf("openai embedding has %d dimensions, want %d", n, o.dims)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	return vecs, nil
+}
+
+func (o *OpenAIEmbedder) Model() string { return o.model }
+
+func (o *OpenAIEmbedder) Dimensions() int { return o.dims }
+
+// chunkTexts splits texts into request-sized groups, estimating ~4 characters
+// per token. Each text is first cut to maxInput tokens so one long message
+// cannot fail the request it is batched in.
+func chunkTexts(texts []string, maxInputs, maxTokens, maxInput int) [][]string {
+	var chunks [][]string
+	var cur []string
+	tokens := 0
+	for _, t := range texts {
+		t = truncateInput(t, maxInput)
+		n := len(t)/4 + 1
+		if len(cur) > 0 && (len(cur) >= maxInputs || tokens+n > maxTokens) {
+			chunks = append(chunks, cur)
+			cur, tokens = nil, 0
+		}
+		cur = append(cur, t)
+		tokens += n
+	}
+	if len(cur) > 0 {
+		chunks = append(chunks, cur)
+	}
+	return chunks
+}
+
+// truncateInput cuts text to about maxTokens tokens on a UTF-8 boundary. It
+// assumes 2 bytes per token rather than 4: non-Latin scripts tokenize far
+// denser than English, and an input over the limit is rejected outright.
+func truncateInput(text string, maxTokens int) string {
+	limit := maxTokens * 2
+	if len(text) <= limit {
+		return text
 	}
+	for limit > 0 && !utf8.RuneStart(text[limit]) {
+		limit--
+	}
+	return text[:limit]
+}
 
-	return resp.Data[0].Embedding, nil
+// withRetry retries fn with exponential backoff on rate limits (429) and
+// server errors (5xx). Other errors and context cancellation return immediately.
+func withRetry(ctx context.Context, fn func() error) error {
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxRetries || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func retryable(err error) bool {
+	status := statusCode(err)
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// Rejected reports whether the provider refused the input itself (400, 413,
+// 422), so retrying the same request is useless. Auth and not-found errors
+// are about the key or model, not the input, and do not count.
+func Rejected(err error) bool {
+	switch statusCode(err) {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	}
+	return false
+}
+
+// statusCode extracts the HTTP status from a provider error, or 0.
+func statusCode(err error) int {
+	var apiErr *openai.APIError
+	var reqErr *openai.RequestError
+	var httpErr *statusError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.HTTPStatusCode
+	case errors.As(err, &reqErr):
+		return reqErr.HTTPStatusCode
+	case errors.As(err, &httpErr):
+		return httpErr.code
+	}
+	return 0
 }
@@ -0,0 +1,62 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestChunkTexts(t *testing.T) {
+	texts := []string{"a", "b", "c", "d", "e"}
+	chunks := chunkTexts(texts, 2, 1000, 1000)
+	if len(chunks) != 3 || len(chunks[2]) != 1 {
+		t.Fatalf("input limit: got %v", chunks)
+	}
+
+	long := strings.Repeat("x", 400) // ~101 tokens
+	chunks = chunkTexts([]string{long, long, "short"}, 100, 150, 1000)
+	if len(chunks) != 2 || len(chunks[0]) != 1 || len(chunks[1]) != 2 {
+		t.Fatalf("token limit: got %d chunks", len(chunks))
+	}
+
+	chunks = chunkTexts([]string{long, "short"}, 100, 1000, 50)
+	if len(chunks) != 1 || len(chunks[0][0]) != 100 || chunks[0][1] != "short" {
+		t.Fatalf("input truncation: got %v", chunks)
+	}
+
+	if chunks := chunkTexts(nil, 10, 10, 10); len(chunks) != 0 {
+		t.Fatalf("empty input: got %v", chunks)
+	}
+}
+
+func TestTruncateInput(t *testing.T) {
+	if got := truncateInput("short", 10); got != "short" {
+		t.Errorf("short text changed: %q", got)
+	}
+	// "é" is two bytes; a 5-byte cut must not split the third one
+	if got := truncateInput("ééééé", 2); got != "éé" {
+		t.Errorf("cut mid-rune: %q", got)
+	}
+}
+
+func TestRejected(t *testing.T) {
+	for code, want := range map[int]bool{400: true, 413: true, 422: true, 401: false, 403: false, 404: false, 429: false, 500: false} {
+		err := fmt.Errorf("embed: %w", &statusError{code: code})
+		if got := Rejected(err); got != want {
+			t.Errorf("Rejected(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+func TestNewEmbedderDimensions(t *testing.T) {
+	if _, err := NewEmbedder(EmbedderConfig{APIKey: "k", Model: "text-embedding-ada-002", Dimensions: 768}); err == nil {
+		t.Error("ada-002 accepted 768 dimensions")
+	}
+	if _, err := NewEmbedder(EmbedderConfig{APIKey: "k", Model: "text-embedding-ada-002", Dimensions: 1536}); err != nil {
+		t.Errorf("ada-002 at its native size: %v", err)
+	}
+	e, err := NewEmbedder(EmbedderConfig{APIKey: "k", Model: "text-embedding-3-small", Dimensions: 768})
+	if err != nil || e.Dimensions() != 768 {
+		t.Errorf("text-embedding-3-small at 768: %v, %v", e, err)
+	}
+}
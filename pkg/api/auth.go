@@ -65,3 +65,35 @@ func validateToken(token, password string) bool {
 
 	return true
 }
+
+// avatarURLExpiry is how long a signed avatar URL stays valid.
+const avatarURLExpiry = 24 * time.Hour
+
+// signPath returns a ?t= token letting GET requests for path through auth
+// until it expires, for img tags that can't send a Bearer token. The expiry
+// is rounded to the hour so a path keeps the same URL, and browser cache
+// entry, for an hour at a time.
+func signPath(path, password string) string {
+	expires := strconv.FormatInt(time.Now().Truncate(time.Hour).Add(avatarURLExpiry).Unix(), 10)
+	return expires + "." + hex.EncodeToString(pathMAC(path, expires, password))
+}
+
+// validPathToken checks a signPath token against the request path.
+func validPathToken(token, path, password string) bool {
+	expires, sigHex, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	sig, err := hex.DecodeString(sigHex)
+	if err != nil || !hmac.Equal(sig, pathMAC(path, expires, password)) {
+		return false
+	}
+	ts, err := strconv.ParseInt(expires, 10, 64)
+	return err == nil && time.Now().Before(time.Unix(ts, 0))
+}
+
+func pathMAC(path, expires, password string) []byte {
+	mac := hmac.New(sha256.New, []byte(password))
+	mac.Write([]byte("path:" + path + "." + expires))
+	return mac.Sum(nil)
+}
@@ -3,32 +3,44 @@ package api
 import (
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"signal-sideband/pkg/ai"
+	"signal-sideband/pkg/ask"
 	"signal-sideband/pkg/cerebro"
 	"signal-sideband/pkg/digest"
+	"signal-sideband/pkg/llm"
 	"signal-sideband/pkg/media"
+	"signal-sideband/pkg/redact"
 	"signal-sideband/pkg/store"
+	"signal-sideband/pkg/usage"
 )
 
 type Handlers struct {
-	store             *store.Store
-	embedder          ai.Embedder
-	generator         *digest.Generator
-	insightsGen       *digest.InsightsGenerator
-	picGen            *media.PicOfDayGenerator
-	cerebroExtractor  *cerebro.Extractor
-	cerebroEnricher   *cerebro.Enricher
-	mediaPath         string
-	authPassword      string
+	store            *store.Store
+	embedder         ai.Embedder
+	generator        *digest.Generator
+	insightsGen      *digest.InsightsGenerator
+	picGen           *media.PicOfDayGenerator
+	cerebroExtractor *cerebro.Extractor
+	cerebroEnricher  *cerebro.Enricher
+	asker            *ask.Asker
+	ledger           *usage.Ledger
+	redactor         *redact.Redactor
+	mediaPath        string
+	authPassword     string
+	progress         *progressTracker
 }
 
-func NewHandlers(s *store.Store, e ai.Embedder, g *digest.Generator, ig *digest.InsightsGenerator, picGen *media.PicOfDayGenerator, cerebroExtractor *cerebro.Extractor, cerebroEnricher *cerebro.Enricher, mediaPath string, authPassword string) *Handlers {
-	return &Handlers{store: s, embedder: e, generator: g, insightsGen: ig, picGen: picGen, cerebroExtractor: cerebroExtractor, cerebroEnricher: cerebroEnricher, mediaPath: mediaPath, authPassword: authPassword}
+func NewHandlers(s *store.Store, e ai.Embedder, g *digest.Generator, ig *digest.InsightsGenerator, picGen *media.PicOfDayGenerator, cerebroExtractor *cerebro.Extractor, cerebroEnricher *cerebro.Enricher, asker *ask.Asker, ledger *usage.Ledger, redactor *redact.Redactor, mediaPath string, authPassword string) *Handlers {
+	return &Handlers{store: s, embedder: e, generator: g, insightsGen: ig, picGen: picGen, cerebroExtractor: cerebroExtractor, cerebroEnricher: cerebroEnricher, asker: asker, ledger: ledger, redactor: redactor, mediaPath: mediaPath, authPassword: authPassword, progress: newProgressTracker()}
 }
 
 type loginRequest struct {
@@ -111,33 +123,15 @@ func (h *Handlers) SearchMessages(w http.ResponseWriter, r *http.Request) {
 
 	mode := r.URL.Query().Get("mode")
 	limit := intParam(r, "limit", 20)
-
-	// Parse search filters
-	var filter store.SearchFilter
-	if v := r.URL.Query().Get("group_id"); v != "" {
-		filter.GroupID = &v
-	}
-	if v := r.URL.Query().Get("sender_id"); v != "" {
-		filter.SenderID = &v
-	}
-	if v := r.URL.Query().Get("after"); v != "" {
-		if t, err := time.Parse(time.RFC3339, v); err == nil {
-			filter.After = &t
-		}
-	}
-	if v := r.URL.Query().Get("before"); v != "" {
-		if t, err := time.Parse(time.RFC3339, v); err == nil {
-			filter.Before = &t
-		}
-	}
-	if r.URL.Query().Get("has_media") == "true" {
-		b := true
-		filter.HasMedia = &b
+	filter, err := h.searchFilterParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	switch mode {
 	case "semantic":
-		embedding, err := h.embedder.Embed(query)
+		embedding, err := h.embedder.Embed(r.Context(), query)
 		if err != nil {
 			writeError(w, http.StatusInternalServerError, "embedding error: "+err.Error())
 			return
@@ -165,11 +159,88 @@ func (h *Handlers) SearchMessages(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// SimilarMessages returns messages semantically close to the given one, using
+// its stored embedding ("more like this").
+func (h *Handlers) SimilarMessages(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, "id required")
+		return
+	}
+
+	filter, err := h.searchFilterParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	neighbours := 3
+	if v := r.URL.Query().Get("neighbours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "neighbours must be a non-negative integer")
+			return
+		}
+		neighbours = n
+	}
+
+	results, err := h.store.SimilarMessages(r.Context(), id, neighbours, floatParam(r, "threshold", 0.5),
+		filter, intParam(r, "limit", 20))
+	switch {
+	case errors.Is(err, store.ErrNoEmbedding):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case errors.Is(err, store.ErrMessageNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if results == nil {
+		results = []store.SearchResult{}
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// Search queries messages, media, links, digests and cerebro concepts at once
+// and returns ranked, typed hits with per-type facet counts.
+func (h *Handlers) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "query parameter 'q' is required")
+		return
+	}
+
+	var types []string
+	if v := r.URL.Query().Get("types"); v != "" {
+		types = strings.Split(v, ",")
+	}
+
+	filter, err := h.searchFilterParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result, err := h.store.UnifiedSearch(r.Context(), query, types, filter, intParam(r, "limit", 20))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, searchResponse{
+		Query:   query,
+		Results: result.Results,
+		Facets:  result.Facets,
+		Total:   result.Total,
+	})
+}
+
 type contactResponse struct {
 	SourceUUID  string `json:"source_uuid"`
 	PhoneNumber string `json:"phone_number"`
 	ProfileName string `json:"profile_name"`
 	Alias       string `json:"alias"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
 	SenderID    string `json:"sender_id"`
 }
 
@@ -214,6 +285,7 @@ func (h *Handlers) GetContacts(w http.ResponseWriter, r *http.Request) {
 			cr.PhoneNumber = c.PhoneNumber
 			cr.ProfileName = c.ProfileName
 			cr.Alias = c.Alias
+			cr.AvatarURL = h.contactAvatarURL(c.SourceUUID, c.AvatarPath)
 			delete(byUUID, s.SourceUUID)
 		}
 		result = append(result, cr)
@@ -229,6 +301,7 @@ func (h *Handlers) GetContacts(w http.ResponseWriter, r *http.Request) {
 			PhoneNumber: c.PhoneNumber,
 			ProfileName: c.ProfileName,
 			Alias:       c.Alias,
+			AvatarURL:   h.contactAvatarURL(c.SourceUUID, c.AvatarPath),
 			SenderID:    c.PhoneNumber,
 		})
 	}
@@ -258,6 +331,13 @@ func (h *Handlers) UpdateContact(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if h.redactor != nil {
+		if aliases, err := h.store.ContactAliases(r.Context()); err != nil {
+			log.Printf("Redaction: reload aliases failed: %v", err)
+		} else {
+			h.redactor.SetAliases(aliases)
+		}
+	}
 
 	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
@@ -271,14 +351,97 @@ func (h *Handlers) GetGroups(w http.ResponseWriter, r *http.Request) {
 	if groups == nil {
 		groups = []store.GroupWithCount{}
 	}
+	for i := range groups {
+		groups[i].AvatarURL = h.groupAvatarURL(groups[i].GroupID, groups[i].AvatarPath)
+	}
 	writeJSON(w, http.StatusOK, groups)
 }
 
+// GetGroup returns a group with its members. Group IDs are base64, so
+// clients should path-escape them.
+func (h *Handlers) GetGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := h.store.GetGroupDetail(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "group not found")
+		return
+	}
+	group.AvatarURL = h.groupAvatarURL(group.GroupID, group.AvatarPath)
+	for i, m := range group.Members {
+		group.Members[i].AvatarURL = h.contactAvatarURL(m.SourceUUID, m.AvatarPath)
+	}
+	writeJSON(w, http.StatusOK, group)
+}
+
+// ContactAvatar serves a contact's profile picture.
+func (h *Handlers) ContactAvatar(w http.ResponseWriter, r *http.Request) {
+	contact, err := h.store.GetContactByUUID(r.Context(), r.PathValue("uuid"))
+	if err != nil || contact.AvatarPath == "" {
+		writeError(w, http.StatusNotFound, "avatar not found")
+		return
+	}
+	serveAvatar(w, r, contact.AvatarPath)
+}
+
+// GroupAvatar serves a group's picture. Group IDs are base64, so clients
+// should path-escape them.
+func (h *Handlers) GroupAvatar(w http.ResponseWriter, r *http.Request) {
+	group, err := h.store.GetGroupByID(r.Context(), r.PathValue("id"))
+	if err != nil || group.AvatarPath == "" {
+		writeError(w, http.StatusNotFound, "avatar not found")
+		return
+	}
+	serveAvatar(w, r, group.AvatarPath)
+}
+
+func serveAvatar(w http.ResponseWriter, r *http.Request, path string) {
+	if _, err := os.Stat(path); err != nil {
+		writeError(w, http.StatusNotFound, "avatar not found")
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	http.ServeFile(w, r, path)
+}
+
+// contactAvatarURL is where the API serves a stored contact avatar, or ""
+// when there is none.
+func (h *Handlers) contactAvatarURL(uuid, path string) string {
+	if uuid == "" || path == "" {
+		return ""
+	}
+	return h.signedURL("/api/contacts/", uuid, "/avatar")
+}
+
+// groupAvatarURL is where the API serves a stored group avatar, or "".
+func (h *Handlers) groupAvatarURL(groupID, path string) string {
+	if path == "" {
+		return ""
+	}
+	return h.signedURL("/api/groups/", groupID, "/avatar")
+}
+
+// signedURL builds prefix+id+suffix with id path-escaped and, when auth is
+// on, a token for the unescaped path the middleware will see.
+func (h *Handlers) signedURL(prefix, id, suffix string) string {
+	u := prefix + url.PathEscape(id) + suffix
+	if h.authPassword == "" {
+		return u
+	}
+	return u + "?t=" + signPath(prefix+id+suffix, h.authPassword)
+}
+
 func (h *Handlers) GetDigests(w http.ResponseWriter, r *http.Request) {
 	limit := intParam(r, "limit", 20)
 	offset := intParam(r, "offset", 0)
 
-	digests, total, err := h.store.ListDigests(r.Context(), limit, offset)
+	kind := r.URL.Query().Get("period_kind")
+	switch kind {
+	case "", store.PeriodDaily, store.PeriodWeekly, store.PeriodMonthly, store.PeriodCustom:
+	default:
+		writeError(w, http.StatusBadRequest, "period_kind must be daily, weekly, monthly or custom")
+		return
+	}
+
+	digests, total, err := h.store.ListDigests(r.Context(), kind, groupIDParam(r), limit, offset)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -311,6 +474,30 @@ type generateRequest struct {
 	Lens        string  `json:"lens,omitempty"`
 }
 
+// parsePeriod accepts RFC3339 or YYYY-MM-DD; a date-only end covers the
+// whole day.
+func parsePeriod(periodStart, periodEnd string) (time.Time, time.Time, error) {
+	start, err := time.Parse(time.RFC3339, periodStart)
+	if err != nil {
+		// Try date-only format
+		start, err = time.Parse("2006-01-02", periodStart)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid period_start format (use RFC3339 or YYYY-MM-DD)")
+		}
+	}
+
+	end, err := time.Parse(time.RFC3339, periodEnd)
+	if err != nil {
+		end, err = time.Parse("2006-01-02", periodEnd)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid period_end format (use RFC3339 or YYYY-MM-DD)")
+		}
+		// Set to end of day
+		end = end.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
+	}
+	return start, end, nil
+}
+
 func (h *Handlers) GenerateDigest(w http.ResponseWriter, r *http.Request) {
 	if h.generator == nil {
 		writeError(w, http.StatusServiceUnavailable, "LLM provider not configured")
@@ -323,28 +510,16 @@ func (h *Handlers) GenerateDigest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	start, err := time.Parse(time.RFC3339, req.PeriodStart)
+	start, end, err := parsePeriod(req.PeriodStart, req.PeriodEnd)
 	if err != nil {
-		// Try date-only format
-		start, err = time.Parse("2006-01-02", req.PeriodStart)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid period_start format (use RFC3339 or YYYY-MM-DD)")
-			return
-		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	end, err := time.Parse(time.RFC3339, req.PeriodEnd)
-	if err != nil {
-		end, err = time.Parse("2006-01-02", req.PeriodEnd)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "invalid period_end format (use RFC3339 or YYYY-MM-DD)")
-			return
-		}
-		// Set to end of day
-		end = end.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
-	}
+	ctx, done := h.progress.track(r.Context(), "digest", nil)
+	defer done()
 
-	d, err := h.generator.Generate(r.Context(), start, end, req.GroupID, req.Lens)
+	d, err := h.generator.Generate(ctx, start, end, req.GroupID, req.Lens)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -361,6 +536,29 @@ func (h *Handlers) GetURLs(w http.ResponseWriter, r *http.Request) {
 		domain = &v
 	}
 
+	// With q, return the best matches (keyword, or semantic with mode=semantic)
+	if q := r.URL.Query().Get("q"); q != "" {
+		var embedding []float32
+		if r.URL.Query().Get("mode") == "semantic" {
+			var err error
+			embedding, err = h.embedder.Embed(r.Context(), q)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "embedding error: "+err.Error())
+				return
+			}
+		}
+		urls, err := h.store.SearchURLs(r.Context(), q, embedding, floatParam(r, "threshold", 0.3), domain, limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if urls == nil {
+			urls = []store.URLRecord{}
+		}
+		writePaginated(w, urls, len(urls), limit, 0)
+		return
+	}
+
 	urls, total, err := h.store.ListURLs(r.Context(), limit, offset, domain)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
@@ -416,7 +614,7 @@ func (h *Handlers) GetMedia(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.store.GetStats(r.Context())
+	stats, err := h.store.GetStats(r.Context(), groupIDParam(r))
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -424,6 +622,36 @@ func (h *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, stats)
 }
 
+// GetUsage reports LLM spend by feature, provider and model for the current
+// day or month (period=day|month, default month) along with budget status.
+func (h *Handlers) GetUsage(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period != "day" {
+		period = "month"
+	}
+	since := usage.PeriodStart(period, time.Now())
+
+	summary, err := h.store.GetUsageSummary(r.Context(), since)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if summary == nil {
+		summary = []store.UsageSummary{}
+	}
+	budgets, err := h.ledger.Status(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resp := usageResponse{Period: period, Since: since, Usage: summary, Budgets: budgets}
+	for _, u := range summary {
+		resp.TotalUSD += u.CostUSD
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
 func (h *Handlers) ServeMediaThumb(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -468,7 +696,19 @@ func (h *Handlers) SearchMedia(w http.ResponseWriter, r *http.Request) {
 	}
 	limit := intParam(r, "limit", 50)
 
-	results, err := h.store.SearchMedia(r.Context(), query, limit)
+	var results []store.MediaSearchResult
+	var err error
+	if r.URL.Query().Get("mode") == "semantic" {
+		var embedding []float32
+		embedding, err = h.embedder.Embed(r.Context(), query)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "embedding error: "+err.Error())
+			return
+		}
+		results, err = h.store.SemanticSearchMedia(r.Context(), embedding, floatParam(r, "threshold", 0.3), limit)
+	} else {
+		results, err = h.store.SearchMedia(r.Context(), query, limit)
+	}
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -481,7 +721,7 @@ func (h *Handlers) SearchMedia(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handlers) ServePicOfDay(w http.ResponseWriter, r *http.Request) {
 	// Try latest insight first, then fall back to most recent with an image
-	imagePath, err := h.store.GetLatestPicOfDay(r.Context())
+	imagePath, err := h.store.GetLatestPicOfDay(r.Context(), groupIDParam(r))
 	if err != nil || imagePath == "" {
 		writeError(w, http.StatusNotFound, "no picture of the day available")
 		return
@@ -496,18 +736,200 @@ func (h *Handlers) ServePicOfDay(w http.ResponseWriter, r *http.Request) {
 	http.ServeFile(w, r, imagePath)
 }
 
+func (h *Handlers) GetLenses(w http.ResponseWriter, r *http.Request) {
+	lenses, err := h.store.ListLenses(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if lenses == nil {
+		lenses = []store.DigestLens{}
+	}
+	writeJSON(w, http.StatusOK, lenses)
+}
+
+func (h *Handlers) GetLens(w http.ResponseWriter, r *http.Request) {
+	l, err := h.store.GetLens(r.Context(), r.PathValue("name"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "lens not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+type lensRequest struct {
+	Name          string   `json:"name"`
+	Label         string   `json:"label"`
+	SystemPrompt  string   `json:"system_prompt"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	SchemaVariant string   `json:"schema_variant"`
+}
+
+// lens applies defaults: temperature 0.3 (as digest_lenses does) and the
+// standard schema.
+func (req lensRequest) lens() store.DigestLens {
+	l := store.DigestLens{
+		Name:          req.Name,
+		Label:         req.Label,
+		SystemPrompt:  req.SystemPrompt,
+		Temperature:   0.3,
+		SchemaVariant: req.SchemaVariant,
+	}
+	if req.Temperature != nil {
+		l.Temperature = *req.Temperature
+	}
+	if l.SchemaVariant == "" {
+		l.SchemaVariant = digest.SchemaStandard
+	}
+	if l.Label == "" {
+		l.Label = l.Name
+	}
+	return l
+}
+
+func (h *Handlers) CreateLens(w http.ResponseWriter, r *http.Request) {
+	var req lensRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	l := req.lens()
+	if err := digest.ValidateLens(l); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := h.store.GetLens(r.Context(), l.Name); err == nil {
+		writeError(w, http.StatusConflict, "lens already exists")
+		return
+	}
+
+	created, err := h.store.CreateLens(r.Context(), l)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *Handlers) UpdateLens(w http.ResponseWriter, r *http.Request) {
+	var req lensRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Name = r.PathValue("name")
+	l := req.lens()
+	if err := digest.ValidateLens(l); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := h.store.UpdateLens(r.Context(), l)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "lens not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *Handlers) DeleteLens(w http.ResponseWriter, r *http.Request) {
+	err := h.store.DeleteLens(r.Context(), r.PathValue("name"))
+	switch {
+	case errors.Is(err, store.ErrBuiltinLens):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusNotFound, "lens not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+type dryRunRequest struct {
+	lensRequest
+	PeriodStart string  `json:"period_start,omitempty"`
+	PeriodEnd   string  `json:"period_end,omitempty"`
+	GroupID     *string `json:"group_id,omitempty"`
+}
+
+// DryRunLens generates a digest with a stored lens (by name) or an unsaved
+// one (system_prompt set) without saving it. The period defaults to the last
+// 24 hours and only its most recent chunk is used. A reply that isn't valid
+// digest JSON returns 422.
+func (h *Handlers) DryRunLens(w http.ResponseWriter, r *http.Request) {
+	if h.generator == nil {
+		writeError(w, http.StatusServiceUnavailable, "LLM provider not configured")
+		return
+	}
+
+	var req dryRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var l store.DigestLens
+	if req.SystemPrompt != "" {
+		if req.Name == "" {
+			req.Name = "dry-run"
+		}
+		l = req.lens()
+		if err := digest.ValidateLens(l); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	} else {
+		stored, err := h.store.GetLens(r.Context(), req.Name)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "lens not found")
+			return
+		}
+		l = *stored
+	}
+
+	end := time.Now()
+	start := end.Add(-24 * time.Hour)
+	if req.PeriodStart != "" || req.PeriodEnd != "" {
+		var err error
+		if start, end, err = parsePeriod(req.PeriodStart, req.PeriodEnd); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	d, err := h.generator.DryRun(r.Context(), l, start, end, req.GroupID)
+	var parseErr *llm.ParseError
+	var validErr *llm.ValidationError
+	switch {
+	case errors.As(err, &parseErr) || errors.As(err, &validErr):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
 func (h *Handlers) GenerateInsight(w http.ResponseWriter, r *http.Request) {
 	if h.insightsGen == nil {
 		writeError(w, http.StatusServiceUnavailable, "LLM provider not configured")
 		return
 	}
 
-	if err := h.insightsGen.GenerateDailyInsights(r.Context()); err != nil {
+	groupID := groupIDParam(r)
+	ctx, done := h.progress.track(r.Context(), "insights", nil)
+	defer done()
+
+	if err := h.insightsGen.GenerateDailyInsights(ctx, time.Now(), groupID); err != nil {
 		writeError(w, http.StatusInternalServerError, "insight generation failed: "+err.Error())
 		return
 	}
+	if err := h.insightsGen.GeneratePicOfDay(ctx, time.Now(), groupID); err != nil {
+		log.Printf("PicOfDay: generation failed: %v", err)
+	}
 
-	insight, err := h.store.GetLatestInsight(r.Context())
+	insight, err := h.store.GetLatestInsight(r.Context(), groupID)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "failed to retrieve generated insight")
 		return
@@ -523,7 +945,7 @@ func (h *Handlers) GeneratePicOfDay(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get latest insight for themes
-	insight, err := h.store.GetLatestInsight(r.Context())
+	insight, err := h.store.GetLatestInsight(r.Context(), groupIDParam(r))
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "no daily insight available — generate one first")
 		return
@@ -558,7 +980,7 @@ func (h *Handlers) GetSnapshots(w http.ResponseWriter, r *http.Request) {
 	if days > 30 {
 		days = 30
 	}
-	snapshots, err := h.store.GetDailySnapshots(r.Context(), days)
+	snapshots, err := h.store.GetDailySnapshots(r.Context(), days, groupIDParam(r))
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -569,6 +991,48 @@ func (h *Handlers) GetSnapshots(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, snapshots)
 }
 
+// searchFilterParams parses the shared search filters: group_id, sender_id,
+// after, before (RFC3339), has_media and lang. An unknown lang is an error.
+func (h *Handlers) searchFilterParams(r *http.Request) (store.SearchFilter, error) {
+	var filter store.SearchFilter
+	if v := r.URL.Query().Get("group_id"); v != "" {
+		filter.GroupID = &v
+	}
+	if v := r.URL.Query().Get("sender_id"); v != "" {
+		filter.SenderID = &v
+	}
+	if v := r.URL.Query().Get("after"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			filter.After = &t
+		}
+	}
+	if v := r.URL.Query().Get("before"); v != "" {
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			filter.Before = &t
+		}
+	}
+	if r.URL.Query().Get("has_media") == "true" {
+		b := true
+		filter.HasMedia = &b
+	}
+	if v := r.URL.Query().Get("lang"); v != "" {
+		if !h.store.IsSearchLanguage(v) {
+			return filter, errors.New("unknown text search language: " + v)
+		}
+		filter.Language = &v
+	}
+	return filter, nil
+}
+
+// groupIDParam is the optional group_id query parameter; nil means all
+// groups.
+func groupIDParam(r *http.Request) *string {
+	if v := r.URL.Query().Get("group_id"); v != "" {
+		return &v
+	}
+	return nil
+}
+
 func intParam(r *http.Request, key string, fallback int) int {
 	v := r.URL.Query().Get(key)
 	if v == "" {
@@ -581,6 +1045,18 @@ func intParam(r *http.Request, key string, fallback int) int {
 	return n
 }
 
+func floatParam(r *http.Request, key string, fallback float64) float64 {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fallback
+	}
+	return f
+}
+
 // Cerebro handlers
 
 func (h *Handlers) GetCerebroGraph(w http.ResponseWriter, r *http.Request) {
@@ -660,7 +1136,10 @@ func (h *Handlers) ExtractCerebro(w http.ResponseWriter, r *http.Request) {
 	end := time.Now()
 	start := end.Add(-24 * time.Hour)
 
-	extraction, err := h.cerebroExtractor.Extract(r.Context(), start, end)
+	ctx, done := h.progress.track(r.Context(), "cerebro", nil)
+	defer done()
+
+	extraction, err := h.cerebroExtractor.Extract(ctx, start, end)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -668,3 +1147,69 @@ func (h *Handlers) ExtractCerebro(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, extraction)
 }
+
+type askRequest struct {
+	Question  string  `json:"question"`
+	SessionID string  `json:"session_id"`
+	GroupID   *string `json:"group_id"`
+}
+
+// Ask answers a question from the archive with message citations. Pass the
+// returned session_id to ask follow-up questions.
+func (h *Handlers) Ask(w http.ResponseWriter, r *http.Request) {
+	if h.asker == nil {
+		writeError(w, http.StatusServiceUnavailable, "LLM provider not configured")
+		return
+	}
+
+	var req askRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	answer, err := h.asker.Ask(r.Context(), ask.Request{
+		Question:  req.Question,
+		SessionID: req.SessionID,
+		GroupID:   req.GroupID,
+	})
+	switch {
+	case errors.Is(err, ask.ErrEmptyQuestion):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, ask.ErrSessionNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, answer)
+	}
+}
+
+func (h *Handlers) GetAskSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := h.store.ListAskSessions(r.Context(), intParam(r, "limit", 20))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if sessions == nil {
+		sessions = []store.AskSession{}
+	}
+	writeJSON(w, http.StatusOK, sessions)
+}
+
+func (h *Handlers) GetAskSession(w http.ResponseWriter, r *http.Request) {
+	session, err := h.store.GetAskSession(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "ask session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, session)
+}
+
+func (h *Handlers) DeleteAskSession(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteAskSession(r.Context(), r.PathValue("id")); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -37,6 +37,14 @@ func isMediaServingPath(path string) bool {
 	return false
 }
 
+// isSignedPath reports whether path is served to holders of a signPath
+// token: avatars are keyed by contact UUIDs and group IDs, which appear all
+// over the API, so they need more than an unguessable path.
+func isSignedPath(path string) bool {
+	// /api/contacts/{uuid}/avatar and /api/groups/{id}/avatar
+	return (strings.HasPrefix(path, "/api/contacts/") || strings.HasPrefix(path, "/api/groups/")) && strings.HasSuffix(path, "/avatar")
+}
+
 func authMiddleware(password string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
@@ -51,6 +59,11 @@ func authMiddleware(password string, next http.Handler) http.Handler {
 			return
 		}
 
+		if r.Method == http.MethodGet && isSignedPath(path) && validPathToken(r.URL.Query().Get("t"), path, password) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		auth := r.Header.Get("Authorization")
 		if !strings.HasPrefix(auth, "Bearer ") {
 			writeError(w, http.StatusUnauthorized, "unauthorized")
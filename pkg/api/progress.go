@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"signal-sideband/pkg/llm"
+)
+
+// operationProgress is a running digest, insight or extraction request.
+type operationProgress struct {
+	ID        int       `json:"id"`
+	Operation string    `json:"operation"`
+	Stage     string    `json:"stage"`
+	Done      int       `json:"done"`
+	Total     int       `json:"total"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// progressTracker holds in-flight operations so long map-reduce runs can be
+// polled via GET /api/progress.
+type progressTracker struct {
+	mu     sync.Mutex
+	nextID int
+	ops    map[int]*operationProgress
+}
+
+func newProgressTracker() *progressTracker {
+	return &progressTracker{ops: make(map[int]*operationProgress)}
+}
+
+// track registers op and returns a context that reports into it, plus a
+// function to call when the operation finishes. onProgress, if set, also
+// receives each update.
+func (t *progressTracker) track(ctx context.Context, op string, onProgress llm.ProgressFunc) (context.Context, func()) {
+	t.mu.Lock()
+	t.nextID++
+	p := &operationProgress{ID: t.nextID, Operation: op, Stage: "starting", StartedAt: time.Now().UTC()}
+	t.ops[p.ID] = p
+	t.mu.Unlock()
+
+	ctx = llm.WithProgress(ctx, func(stage string, done, total int) {
+		t.mu.Lock()
+		p.Stage, p.Done, p.Total = stage, done, total
+		t.mu.Unlock()
+		if onProgress != nil {
+			onProgress(stage, done, total)
+		}
+	})
+	return ctx, func() {
+		t.mu.Lock()
+		delete(t.ops, p.ID)
+		t.mu.Unlock()
+	}
+}
+
+func (t *progressTracker) list() []operationProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]operationProgress, 0, len(t.ops))
+	for _, p := range t.ops {
+		out = append(out, *p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (h *Handlers) GetProgress(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.progress.list())
+}
@@ -3,6 +3,10 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
+
+	"signal-sideband/pkg/store"
+	"signal-sideband/pkg/usage"
 )
 
 type errorResponse struct {
@@ -16,6 +20,21 @@ type paginatedResponse struct {
 	Offset int `json:"offset"`
 }
 
+type searchResponse struct {
+	Query   string            `json:"query"`
+	Results []store.SearchHit `json:"results"`
+	Facets  map[string]int    `json:"facets"`
+	Total   int               `json:"total"`
+}
+
+type usageResponse struct {
+	Period   string               `json:"period"`
+	Since    time.Time            `json:"since"`
+	TotalUSD float64              `json:"total_usd"`
+	Usage    []store.UsageSummary `json:"usage"`
+	Budgets  []usage.BudgetStatus `json:"budgets"`
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -9,10 +9,13 @@ import (
 	"time"
 
 	"signal-sideband/pkg/ai"
+	"signal-sideband/pkg/ask"
 	"signal-sideband/pkg/cerebro"
 	"signal-sideband/pkg/digest"
 	"signal-sideband/pkg/media"
+	"signal-sideband/pkg/redact"
 	"signal-sideband/pkg/store"
+	"signal-sideband/pkg/usage"
 )
 
 type Server struct {
@@ -20,8 +23,8 @@ type Server struct {
 	handlers   *Handlers
 }
 
-func NewServer(s *store.Store, embedder ai.Embedder, generator *digest.Generator, insightsGen *digest.InsightsGenerator, picGen *media.PicOfDayGenerator, cerebroExtractor *cerebro.Extractor, cerebroEnricher *cerebro.Enricher, port string, authPassword string, mediaPath string, version string, buildNumber string, webDir ...string) *Server {
-	h := NewHandlers(s, embedder, generator, insightsGen, picGen, cerebroExtractor, cerebroEnricher, mediaPath, authPassword)
+func NewServer(s *store.Store, embedder ai.Embedder, generator *digest.Generator, insightsGen *digest.InsightsGenerator, picGen *media.PicOfDayGenerator, cerebroExtractor *cerebro.Extractor, cerebroEnricher *cerebro.Enricher, asker *ask.Asker, ledger *usage.Ledger, redactor *redact.Redactor, port string, authPassword string, mediaPath string, version string, buildNumber string, webDir ...string) *Server {
+	h := NewHandlers(s, embedder, generator, insightsGen, picGen, cerebroExtractor, cerebroEnricher, asker, ledger, redactor, mediaPath, authPassword)
 
 	mux := http.NewServeMux()
 
@@ -29,21 +32,37 @@ func NewServer(s *store.Store, embedder ai.Embedder, generator *digest.Generator
 	mux.HandleFunc("GET /api/auth/status", h.AuthStatus)
 	mux.HandleFunc("POST /api/auth/login", h.Login)
 
+	// Search
+	mux.HandleFunc("GET /api/search", h.Search)
+
 	// Messages
 	mux.HandleFunc("GET /api/messages", h.GetMessages)
 	mux.HandleFunc("GET /api/messages/search", h.SearchMessages)
+	mux.HandleFunc("GET /api/messages/{id}/similar", h.SimilarMessages)
 
 	// Contacts
 	mux.HandleFunc("GET /api/contacts", h.GetContacts)
 	mux.HandleFunc("PUT /api/contacts/{uuid}", h.UpdateContact)
+	mux.HandleFunc("GET /api/contacts/{uuid}/avatar", h.ContactAvatar)
 
 	// Groups
 	mux.HandleFunc("GET /api/groups", h.GetGroups)
+	mux.HandleFunc("GET /api/groups/{id}", h.GetGroup)
+	mux.HandleFunc("GET /api/groups/{id}/avatar", h.GroupAvatar)
 
 	// Digests
 	mux.HandleFunc("GET /api/digests", h.GetDigests)
 	mux.HandleFunc("GET /api/digests/{id}", h.GetDigest)
 	mux.HandleFunc("POST /api/digests/generate", h.GenerateDigest)
+	mux.HandleFunc("POST /api/digests/generate/stream", h.GenerateDigestStream)
+
+	// Digest lenses
+	mux.HandleFunc("GET /api/lenses", h.GetLenses)
+	mux.HandleFunc("POST /api/lenses", h.CreateLens)
+	mux.HandleFunc("POST /api/lenses/dry-run", h.DryRunLens)
+	mux.HandleFunc("GET /api/lenses/{name}", h.GetLens)
+	mux.HandleFunc("PUT /api/lenses/{name}", h.UpdateLens)
+	mux.HandleFunc("DELETE /api/lenses/{name}", h.DeleteLens)
 
 	// URLs
 	mux.HandleFunc("GET /api/urls", h.GetURLs)
@@ -56,6 +75,7 @@ func NewServer(s *store.Store, embedder ai.Embedder, generator *digest.Generator
 
 	// Insights
 	mux.HandleFunc("POST /api/insights/generate", h.GenerateInsight)
+	mux.HandleFunc("POST /api/insights/generate/stream", h.GenerateInsightStream)
 	mux.HandleFunc("GET /api/snapshots", h.GetSnapshots)
 
 	// Picture of the Day
@@ -68,8 +88,16 @@ func NewServer(s *store.Store, embedder ai.Embedder, generator *digest.Generator
 	mux.HandleFunc("POST /api/cerebro/concepts/{id}/enrich", h.EnrichCerebroConcept)
 	mux.HandleFunc("POST /api/cerebro/extract", h.ExtractCerebro)
 
+	// Ask the archive
+	mux.HandleFunc("POST /api/ask", h.Ask)
+	mux.HandleFunc("GET /api/ask/sessions", h.GetAskSessions)
+	mux.HandleFunc("GET /api/ask/sessions/{id}", h.GetAskSession)
+	mux.HandleFunc("DELETE /api/ask/sessions/{id}", h.DeleteAskSession)
+
 	// Stats
 	mux.HandleFunc("GET /api/stats", h.GetStats)
+	mux.HandleFunc("GET /api/usage", h.GetUsage)
+	mux.HandleFunc("GET /api/progress", h.GetProgress)
 
 	// Health & version
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
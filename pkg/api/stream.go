@@ -0,0 +1,166 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"signal-sideband/pkg/llm"
+)
+
+type sseEvent struct {
+	name string
+	data any
+}
+
+type deltaEvent struct {
+	Text string `json:"text"`
+}
+
+type progressEvent struct {
+	Stage string `json:"stage"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// streamOperation runs op and reports it as Server-Sent Events:
+//
+//	delta     {"text": ...} model output as it is produced
+//	reset     {} discard the delta text so far; a retry, fallback or next
+//	          call streams its output from the start
+//	progress  {"stage", "done", "total"} for chunked (map-reduce) runs
+//	<final>   the result run returns, e.g. "digest" with the DigestRecord
+//	error     {"error": ...}
+//
+// The server's write timeout is lifted for the stream; a comment line is
+// sent every 15s so idle proxies keep the connection open.
+func (h *Handlers) streamOperation(w http.ResponseWriter, r *http.Request, op string, run func(ctx context.Context) (string, any, error)) {
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ctx := r.Context()
+	events := make(chan sseEvent, 64)
+	emit := func(e sseEvent) {
+		select {
+		case events <- e:
+		case <-ctx.Done():
+		}
+	}
+
+	ctx, done := h.progress.track(ctx, op, func(stage string, d, total int) {
+		emit(sseEvent{"progress", progressEvent{Stage: stage, Done: d, Total: total}})
+	})
+	defer done()
+	ctx = llm.WithStream(ctx, func(delta string) {
+		emit(sseEvent{"delta", deltaEvent{Text: delta}})
+	}, func() {
+		emit(sseEvent{"reset", struct{}{}})
+	})
+
+	type result struct {
+		name string
+		data any
+		err  error
+	}
+	finished := make(chan result, 1)
+	go func() {
+		name, data, err := run(ctx)
+		finished <- result{name, data, err}
+	}()
+
+	send := func(e sseEvent) {
+		b, err := json.Marshal(e.data)
+		if err != nil {
+			b, _ = json.Marshal(map[string]string{"error": err.Error()})
+			e.name = "error"
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, b)
+		_ = rc.Flush()
+	}
+
+	ping := time.NewTicker(15 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case e := <-events:
+			send(e)
+		case <-ping.C:
+			fmt.Fprint(w, ": ping\n\n")
+			_ = rc.Flush()
+		case res := <-finished:
+			for drained := false; !drained; {
+				select {
+				case e := <-events:
+					send(e)
+				default:
+					drained = true
+				}
+			}
+			if res.err != nil {
+				send(sseEvent{"error", map[string]string{"error": res.err.Error()}})
+			} else {
+				send(sseEvent{res.name, res.data})
+			}
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// GenerateDigestStream is GenerateDigest over SSE: the model's output
+// streams as delta events and the saved record arrives as a digest event.
+func (h *Handlers) GenerateDigestStream(w http.ResponseWriter, r *http.Request) {
+	if h.generator == nil {
+		writeError(w, http.StatusServiceUnavailable, "LLM provider not configured")
+		return
+	}
+
+	var req generateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	start, end, err := parsePeriod(req.PeriodStart, req.PeriodEnd)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.streamOperation(w, r, "digest", func(ctx context.Context) (string, any, error) {
+		d, err := h.generator.Generate(ctx, start, end, req.GroupID, req.Lens)
+		return "digest", d, err
+	})
+}
+
+// GenerateInsightStream is GenerateInsight over SSE, ending with an insight
+// event.
+func (h *Handlers) GenerateInsightStream(w http.ResponseWriter, r *http.Request) {
+	if h.insightsGen == nil {
+		writeError(w, http.StatusServiceUnavailable, "LLM provider not configured")
+		return
+	}
+
+	groupID := groupIDParam(r)
+	h.streamOperation(w, r, "insights", func(ctx context.Context) (string, any, error) {
+		if err := h.insightsGen.GenerateDailyInsights(ctx, time.Now(), groupID); err != nil {
+			return "", nil, fmt.Errorf("insight generation failed: %w", err)
+		}
+		if err := h.insightsGen.GeneratePicOfDay(ctx, time.Now(), groupID); err != nil {
+			log.Printf("PicOfDay: generation failed: %v", err)
+		}
+		insight, err := h.store.GetLatestInsight(ctx, groupID)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to retrieve generated insight: %w", err)
+		}
+		return "insight", insight, nil
+	})
+}
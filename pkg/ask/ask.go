@@ -0,0 +1,338 @@
+package ask
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"signal-sideband/pkg/ai"
+	"signal-sideband/pkg/llm"
+	"signal-sideband/pkg/store"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Retrieval limits. Each search contributes up to searchLimit messages; the
+// fused list is cut to maxSources before it goes into the prompt.
+const (
+	searchLimit       = 20
+	maxSources        = 30
+	maxConcepts       = 5
+	maxHistoryTurns   = 6
+	semanticThreshold = 0.3
+	sourceChars       = 600
+)
+
+var (
+	ErrEmptyQuestion   = errors.New("question is required")
+	ErrSessionNotFound = errors.New("ask session not found")
+)
+
+// Asker answers questions about the archive from retrieved messages
+// (retrieval-augmented generation) and keeps the conversation in sessions.
+type Asker struct {
+	store    *store.Store
+	embedder ai.Embedder
+	provider llm.Provider
+}
+
+func NewAsker(s *store.Store, e ai.Embedder, p llm.Provider) *Asker {
+	return &Asker{store: s, embedder: e, provider: p}
+}
+
+// Request is one question. An empty SessionID starts a new session; GroupID
+// narrows retrieval and defaults to the session's group.
+type Request struct {
+	Question  string
+	SessionID string
+	GroupID   *string
+}
+
+// Citation points at a message the answer relies on. Ref is the [n] marker
+// used in the answer text.
+type Citation struct {
+	Ref       int       `json:"ref"`
+	MessageID string    `json:"message_id"`
+	SenderID  string    `json:"sender_id"`
+	Sender    string    `json:"sender"`
+	Snippet   string    `json:"snippet"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Answer struct {
+	SessionID  string                 `json:"session_id"`
+	TurnID     string                 `json:"turn_id"`
+	Question   string                 `json:"question"`
+	Answer     string                 `json:"answer"`
+	Citations  []Citation             `json:"citations"`
+	Concepts   []store.CerebroConcept `json:"concepts"`
+	Provider   string                 `json:"llm_provider"`
+	Model      string                 `json:"llm_model"`
+	TokenCount int                    `json:"token_count"`
+}
+
+type answerJSON struct {
+	Answer    string `json:"answer"`
+	Citations []int  `json:"citations"`
+}
+
+func (a *answerJSON) Validate() error {
+	if strings.TrimSpace(a.Answer) == "" {
+		return errors.New("answer is required")
+	}
+	return nil
+}
+
+const noSourcesAnswer = "I couldn't find anything in the archive about that."
+
+// Ask answers req.Question and records the turn in its session.
+func (a *Asker) Ask(ctx context.Context, req Request) (*Answer, error) {
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		return nil, ErrEmptyQuestion
+	}
+
+	var session *store.AskSession
+	var err error
+	if req.SessionID != "" {
+		session, err = a.store.GetAskSession(ctx, req.SessionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		if err != nil {
+			return nil, fmt.Errorf("load session: %w", err)
+		}
+	} else {
+		session, err = a.store.CreateAskSession(ctx, truncate(question, 80), req.GroupID)
+		if err != nil {
+			return nil, fmt.Errorf("create session: %w", err)
+		}
+	}
+
+	groupID := req.GroupID
+	if groupID == nil {
+		groupID = session.GroupID
+	}
+	history := session.Turns
+	if len(history) > maxHistoryTurns {
+		history = history[len(history)-maxHistoryTurns:]
+	}
+
+	sources, concepts, err := a.retrieve(ctx, question, history, store.SearchFilter{GroupID: groupID})
+	if err != nil {
+		return nil, err
+	}
+
+	answer := &Answer{
+		SessionID: session.ID,
+		Question:  question,
+		Answer:    noSourcesAnswer,
+		Citations: []Citation{},
+		Concepts:  concepts,
+	}
+	if len(sources) > 0 {
+		ids, err := a.store.LoadIdentities(ctx)
+		if err != nil {
+			log.Printf("ask: failed to load contact names, using short sender IDs: %v", err)
+		}
+		parsed, resp, err := llm.CompleteJSON[answerJSON](ctx, a.provider, llm.CompletionRequest{
+			SystemPrompt: systemPrompt,
+			Messages:     buildConversation(history, question, sources, concepts, ids),
+			MaxTokens:    2048,
+			Temperature:  llm.Temperature(0.2),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("llm completion: %w", err)
+		}
+		answer.Answer = parsed.Answer
+		answer.Citations = resolveCitations(parsed.Answer, parsed.Citations, sources, ids)
+		answer.Provider = resp.Provider
+		answer.Model = resp.Model
+		answer.TokenCount = resp.InputTokens + resp.OutputTokens
+	}
+
+	citations, _ := json.Marshal(answer.Citations)
+	answer.TurnID, err = a.store.SaveAskTurn(ctx, store.AskTurn{
+		SessionID:   session.ID,
+		Question:    question,
+		Answer:      answer.Answer,
+		Citations:   citations,
+		LLMProvider: answer.Provider,
+		LLMModel:    answer.Model,
+		TokenCount:  answer.TokenCount,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save turn: %w", err)
+	}
+	return answer, nil
+}
+
+// retrieve gathers candidate messages from semantic and full-text search and
+// related cerebro concepts. Follow-ups are often elliptical ("who booked
+// it?"), so the previous question also feeds the search.
+func (a *Asker) retrieve(ctx context.Context, question string, history []store.AskTurn, filter store.SearchFilter) ([]store.SearchResult, []store.CerebroConcept, error) {
+	var previous string
+	if len(history) > 0 {
+		previous = history[len(history)-1].Question
+	}
+
+	var lists [][]store.SearchResult
+	if _, mock := a.embedder.(*ai.MockEmbedder); !mock && a.embedder != nil {
+		embedding, err := a.embedder.Embed(ctx, strings.TrimSpace(previous+"\n"+question))
+		if err != nil {
+			log.Printf("ask: embedding failed, using full-text search only: %v", err)
+		} else {
+			results, err := a.store.FilteredSemanticSearch(ctx, embedding, semanticThreshold, filter, searchLimit)
+			if err != nil {
+				return nil, nil, fmt.Errorf("semantic search: %w", err)
+			}
+			lists = append(lists, results)
+		}
+	}
+	for _, q := range []string{question, previous} {
+		if q == "" {
+			continue
+		}
+		results, err := a.store.FilteredFullTextSearch(ctx, q, filter, searchLimit)
+		if err != nil {
+			return nil, nil, fmt.Errorf("full-text search: %w", err)
+		}
+		lists = append(lists, results)
+	}
+	sources := fuse(lists, maxSources)
+
+	concepts := []store.CerebroConcept{}
+	hits, err := a.store.UnifiedSearch(ctx, question, []string{"concept"}, filter, maxConcepts)
+	if err != nil {
+		log.Printf("ask: concept search failed: %v", err)
+	} else {
+		for _, h := range hits.Results {
+			if c, ok := h.Data.(store.CerebroConcept); ok {
+				concepts = append(concepts, c)
+			}
+		}
+	}
+	return sources, concepts, nil
+}
+
+// fuse merges ranked result lists with reciprocal rank fusion, keeps the
+// best limit messages and returns them in chronological order.
+func fuse(lists [][]store.SearchResult, limit int) []store.SearchResult {
+	const k = 60
+	scores := make(map[string]float64)
+	byID := make(map[string]store.SearchResult)
+	for _, list := range lists {
+		for rank, r := range list {
+			scores[r.ID] += 1 / float64(k+rank+1)
+			if _, ok := byID[r.ID]; !ok {
+				byID[r.ID] = r
+			}
+		}
+	}
+
+	merged := make([]store.SearchResult, 0, len(byID))
+	for _, r := range byID {
+		merged = append(merged, r)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		si, sj := scores[merged[i].ID], scores[merged[j].ID]
+		if si != sj {
+			return si > sj
+		}
+		return merged[i].CreatedAt.After(merged[j].CreatedAt)
+	})
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].CreatedAt.Before(merged[j].CreatedAt)
+	})
+	return merged
+}
+
+const systemPrompt = `You answer questions about a group chat archive using only the numbered messages provided.
+
+Rules:
+- Base every statement on the messages. If they don't contain the answer, say so plainly.
+- Cite the messages you rely on inline with their numbers, e.g. "They booked the cabin for June [3][7]."
+- Prefer the most recent message when the chat changed its mind, and mention the change.
+- Related concepts are background from the chat's knowledge graph; don't cite them.
+- Keep the answer short and conversational.
+
+Respond ONLY with JSON:
+{"answer": "answer text with [n] citations", "citations": [3, 7]}`
+
+// buildConversation replays the session's earlier turns, then asks the new
+// question with its sources attached.
+func buildConversation(history []store.AskTurn, question string, sources []store.SearchResult, concepts []store.CerebroConcept, ids *store.Identities) []llm.Message {
+	var msgs []llm.Message
+	for _, t := range history {
+		msgs = append(msgs,
+			llm.Message{Role: llm.RoleUser, Content: t.Question},
+			llm.Message{Role: llm.RoleAssistant, Content: t.Answer},
+		)
+	}
+
+	var b strings.Builder
+	b.WriteString("Messages:\n")
+	for i, m := range sources {
+		sender := ids.Sender(m.SenderID, m.SourceUUID, m.IsOutgoing)
+		fmt.Fprintf(&b, "[%d] %s %s: %s\n", i+1, m.CreatedAt.Format("2006-01-02 15:04"), sender, truncate(m.Content, sourceChars))
+	}
+	if len(concepts) > 0 {
+		b.WriteString("\nRelated concepts:\n")
+		for _, c := range concepts {
+			fmt.Fprintf(&b, "- %s (%s): %s\n", c.Name, c.Category, c.Description)
+		}
+	}
+	fmt.Fprintf(&b, "\nQuestion: %s", question)
+
+	return append(msgs, llm.Message{Role: llm.RoleUser, Content: b.String()})
+}
+
+var citationMarker = regexp.MustCompile(`\[(\d+)\]`)
+
+// resolveCitations maps the model's [n] references, from the answer text and
+// the citations list, back to messages. Out-of-range numbers are dropped.
+func resolveCitations(answer string, declared []int, sources []store.SearchResult, ids *store.Identities) []Citation {
+	refs := append([]int(nil), declared...)
+	for _, m := range citationMarker.FindAllStringSubmatch(answer, -1) {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			refs = append(refs, n)
+		}
+	}
+	sort.Ints(refs)
+
+	citations := []Citation{}
+	seen := make(map[int]bool)
+	for _, n := range refs {
+		if n < 1 || n > len(sources) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		m := sources[n-1]
+		citations = append(citations, Citation{
+			Ref:       n,
+			MessageID: m.ID,
+			SenderID:  m.SenderID,
+			Sender:    ids.Sender(m.SenderID, m.SourceUUID, m.IsOutgoing),
+			Snippet:   truncate(m.Content, 200),
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return citations
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "..."
+	}
+	return s
+}
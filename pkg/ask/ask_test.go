@@ -0,0 +1,44 @@
+package ask
+
+import (
+	"testing"
+	"time"
+
+	"signal-sideband/pkg/store"
+)
+
+func msg(id string, minute int) store.SearchResult {
+	return store.SearchResult{ID: id, Content: "msg " + id, CreatedAt: time.Date(2026, 6, 1, 12, minute, 0, 0, time.UTC)}
+}
+
+func TestFuse(t *testing.T) {
+	semantic := []store.SearchResult{msg("a", 5), msg("b", 1), msg("c", 3)}
+	fulltext := []store.SearchResult{msg("c", 3), msg("d", 0)}
+
+	got := fuse([][]store.SearchResult{semantic, fulltext}, 3)
+	if len(got) != 3 {
+		t.Fatalf("len = %d, want 3", len(got))
+	}
+	// c is in both lists so it ranks first; b and d tie and the newer b wins.
+	// The kept messages come back oldest first.
+	want := []string{"b", "c", "a"}
+	for i, id := range want {
+		if got[i].ID != id {
+			t.Fatalf("got[%d] = %s, want %s (%v)", i, got[i].ID, id, got)
+		}
+	}
+}
+
+func TestResolveCitations(t *testing.T) {
+	sources := []store.SearchResult{msg("a", 1), msg("b", 2), msg("c", 3)}
+
+	got := resolveCitations("They booked it [3], then changed dates [1][3]. See [9].", []int{2}, sources, nil)
+	if len(got) != 3 {
+		t.Fatalf("citations = %+v, want refs 1, 2, 3", got)
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		if got[i].Ref != i+1 || got[i].MessageID != id {
+			t.Fatalf("citation %d = %+v, want ref %d -> %s", i, got[i], i+1, id)
+		}
+	}
+}
@@ -3,9 +3,9 @@ package cerebro
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
 	"signal-sideband/pkg/llm"
@@ -69,15 +69,24 @@ Respond ONLY with JSON:
 func (e *Enricher) EnrichConcept(ctx context.Context, concept store.CerebroConcept) error {
 	if e.perplexityProvider != nil {
 		if err := e.enrichWithPerplexity(ctx, concept); err != nil {
+			if errors.Is(err, llm.ErrBudgetExceeded) {
+				return err
+			}
 			log.Printf("cerebro: perplexity enrichment failed for %q: %v", concept.Name, err)
 		}
 	}
 
 	if e.grokProvider != nil {
 		if err := e.enrichWithGrokX(ctx, concept); err != nil {
+			if errors.Is(err, llm.ErrBudgetExceeded) {
+				return err
+			}
 			log.Printf("cerebro: grok X enrichment failed for %q: %v", concept.Name, err)
 		}
 		if err := e.enrichWithGrokBooks(ctx, concept); err != nil {
+			if errors.Is(err, llm.ErrBudgetExceeded) {
+				return err
+			}
 			log.Printf("cerebro: grok books enrichment failed for %q: %v", concept.Name, err)
 		}
 	}
@@ -86,22 +95,16 @@ func (e *Enricher) EnrichConcept(ctx context.Context, concept store.CerebroConce
 }
 
 func (e *Enricher) enrichWithPerplexity(ctx context.Context, concept store.CerebroConcept) error {
-	resp, err := e.perplexityProvider.Complete(ctx, llm.CompletionRequest{
+	contentJSON, err := completeEnrichment(ctx, e.perplexityProvider, llm.CompletionRequest{
 		SystemPrompt: "You are a knowledge enrichment engine. Provide factual, concise information.",
 		UserPrompt:   fmt.Sprintf(perplexityPrompt, concept.Name, concept.Category, concept.Description),
 		MaxTokens:    2048,
-		Temperature:  0.3,
+		Temperature:  llm.Temperature(0.3),
 	})
 	if err != nil {
 		return err
 	}
 
-	content := parseJSONResponse(resp.Content)
-	contentJSON, err := json.Marshal(json.RawMessage(content))
-	if err != nil {
-		contentJSON = []byte(`{"raw": ` + fmt.Sprintf("%q", resp.Content) + `}`)
-	}
-
 	_, err = e.store.SaveEnrichment(ctx, store.CerebroEnrichment{
 		ConceptID: concept.ID,
 		Source:    "perplexity",
@@ -111,22 +114,16 @@ func (e *Enricher) enrichWithPerplexity(ctx context.Context, concept store.Cereb
 }
 
 func (e *Enricher) enrichWithGrokX(ctx context.Context, concept store.CerebroConcept) error {
-	resp, err := e.grokProvider.Complete(ctx, llm.CompletionRequest{
+	contentJSON, err := completeEnrichment(ctx, e.grokProvider, llm.CompletionRequest{
 		SystemPrompt: "You are a social media trends analyst. Provide relevant X/Twitter trending info.",
 		UserPrompt:   fmt.Sprintf(grokXPrompt, concept.Name, concept.Category),
 		MaxTokens:    2048,
-		Temperature:  0.5,
+		Temperature:  llm.Temperature(0.5),
 	})
 	if err != nil {
 		return err
 	}
 
-	content := parseJSONResponse(resp.Content)
-	contentJSON, err := json.Marshal(json.RawMessage(content))
-	if err != nil {
-		contentJSON = []byte(`{"raw": ` + fmt.Sprintf("%q", resp.Content) + `}`)
-	}
-
 	ttl := 24 * time.Hour
 	expiresAt := time.Now().Add(ttl)
 
@@ -140,22 +137,16 @@ func (e *Enricher) enrichWithGrokX(ctx context.Context, concept store.CerebroCon
 }
 
 func (e *Enricher) enrichWithGrokBooks(ctx context.Context, concept store.CerebroConcept) error {
-	resp, err := e.grokProvider.Complete(ctx, llm.CompletionRequest{
+	contentJSON, err := completeEnrichment(ctx, e.grokProvider, llm.CompletionRequest{
 		SystemPrompt: "You are a research librarian. Suggest relevant books and articles.",
 		UserPrompt:   fmt.Sprintf(grokBooksPrompt, concept.Name, concept.Category, concept.Description),
 		MaxTokens:    2048,
-		Temperature:  0.3,
+		Temperature:  llm.Temperature(0.3),
 	})
 	if err != nil {
 		return err
 	}
 
-	content := parseJSONResponse(resp.Content)
-	contentJSON, err := json.Marshal(json.RawMessage(content))
-	if err != nil {
-		contentJSON = []byte(`{"raw": ` + fmt.Sprintf("%q", resp.Content) + `}`)
-	}
-
 	ttl := 24 * time.Hour
 	expiresAt := time.Now().Add(ttl)
 
@@ -175,6 +166,9 @@ func (e *Enricher) EnrichBatch(ctx context.Context, limit int) error {
 	}
 	for _, c := range concepts {
 		if err := e.EnrichConcept(ctx, c); err != nil {
+			if errors.Is(err, llm.ErrBudgetExceeded) {
+				return err
+			}
 			log.Printf("cerebro: batch enrichment failed for %q: %v", c.Name, err)
 		}
 	}
@@ -184,23 +178,14 @@ func (e *Enricher) EnrichBatch(ctx context.Context, limit int) error {
 	return nil
 }
 
-func parseJSONResponse(content string) string {
-	trimmed := content
-	if idx := strings.Index(trimmed, "```json"); idx != -1 {
-		trimmed = trimmed[idx+7:]
-		if end := strings.Index(trimmed, "```"); end != -1 {
-			trimmed = trimmed[:end]
-		}
-	} else if idx := strings.Index(trimmed, "```"); idx != -1 {
-		trimmed = trimmed[idx+3:]
-		if end := strings.Index(trimmed, "```"); end != -1 {
-			trimmed = trimmed[:end]
-		}
-	}
-	trimmed = strings.TrimSpace(trimmed)
-	var js json.RawMessage
-	if json.Unmarshal([]byte(trimmed), &js) == nil {
-		return trimmed
+// completeEnrichment returns the provider's JSON reply. Replies that never
+// parse are kept verbatim as {"raw": "..."} rather than discarded.
+func completeEnrichment(ctx context.Context, p llm.Provider, req llm.CompletionRequest) (json.RawMessage, error) {
+	content, resp, err := llm.CompleteJSON[json.RawMessage](ctx, p, req)
+	var parseErr *llm.ParseError
+	if errors.As(err, &parseErr) {
+		raw, _ := json.Marshal(map[string]string{"raw": resp.Content})
+		return raw, nil
 	}
-	return content
+	return content, err
 }
@@ -2,7 +2,6 @@ package cerebro
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
@@ -26,6 +25,15 @@ type extractionResult struct {
 	Edges    []extractedEdge    `json:"edges"`
 }
 
+func (r *extractionResult) Validate() error {
+	for i, c := range r.Concepts {
+		if c.Name == "" {
+			return fmt.Errorf("concept %d has no name", i)
+		}
+	}
+	return nil
+}
+
 type extractedConcept struct {
 	Name        string `json:"name"`
 	Category    string `json:"category"`
@@ -38,6 +46,41 @@ type extractedEdge struct {
 	Relation string `json:"relation"`
 }
 
+// merge folds another chunk's graph into r. Concepts are matched by
+// case-insensitive name, keeping the first spelling and description seen;
+// edges are renamed to match and deduplicated.
+func (r *extractionResult) merge(other extractionResult) {
+	canonical := make(map[string]string, len(r.Concepts))
+	for _, c := range r.Concepts {
+		canonical[strings.ToLower(c.Name)] = c.Name
+	}
+	for _, c := range other.Concepts {
+		key := strings.ToLower(c.Name)
+		if _, ok := canonical[key]; ok {
+			continue
+		}
+		canonical[key] = c.Name
+		r.Concepts = append(r.Concepts, c)
+	}
+
+	seen := make(map[extractedEdge]bool, len(r.Edges))
+	for _, e := range r.Edges {
+		seen[e] = true
+	}
+	for _, e := range other.Edges {
+		if name, ok := canonical[strings.ToLower(e.Source)]; ok {
+			e.Source = name
+		}
+		if name, ok := canonical[strings.ToLower(e.Target)]; ok {
+			e.Target = name
+		}
+		if !seen[e] {
+			seen[e] = true
+			r.Edges = append(r.Edges, e)
+		}
+	}
+}
+
 const extractionSystemPrompt = `You are a knowledge graph extraction engine. Given a chat transcript, extract concepts and their relationships.
 
 For each concept, provide:
@@ -58,6 +101,41 @@ Respond ONLY with JSON in this exact format:
   "edges": [{"source": "...", "target": "...", "relation": "..."}]
 }`
 
+// extractGraph asks the model for the concept graph of a transcript. Long
+// ranges (e.g. a first run over history) are extracted chunk by chunk and the
+// graphs merged by concept name. It returns the last response, for its
+// provider and model, and the tokens used across all calls.
+func (e *Extractor) extractGraph(ctx context.Context, lines []string) (extractionResult, *llm.CompletionResponse, int, error) {
+	chunks := llm.Chunk(lines, llm.ChunkTokens(e.provider)-llm.EstimateTokens(extractionSystemPrompt))
+	var result extractionResult
+	var resp *llm.CompletionResponse
+	tokens := 0
+	for i, chunk := range chunks {
+		if len(chunks) > 1 {
+			llm.ReportProgress(ctx, "cerebro", "extract", i, len(chunks))
+		}
+		var part extractionResult
+		var err error
+		part, resp, err = llm.CompleteJSON[extractionResult](ctx, e.provider, llm.CompletionRequest{
+			SystemPrompt: extractionSystemPrompt,
+			UserPrompt:   fmt.Sprintf("Extract concepts and relationships from this chat transcript (%d messages):\n\n%s", strings.Count(chunk, "\n"), chunk),
+			MaxTokens:    4096,
+			Temperature:  llm.Temperature(0.2),
+		})
+		if resp != nil {
+			tokens += resp.InputTokens + resp.OutputTokens
+		}
+		if err != nil {
+			return result, resp, tokens, fmt.Errorf("extract concepts (part %d of %d): %w", i+1, len(chunks), err)
+		}
+		result.merge(part)
+	}
+	if len(chunks) > 1 {
+		llm.ReportProgress(ctx, "cerebro", "extract", len(chunks), len(chunks))
+	}
+	return result, resp, tokens, nil
+}
+
 func (e *Extractor) Extract(ctx context.Context, start, end time.Time) (*store.CerebroExtraction, error) {
 	messages, err := e.store.GetMessagesByTimeRange(ctx, start, end, nil)
 	if err != nil {
@@ -69,43 +147,19 @@ func (e *Extractor) Extract(ctx context.Context, start, end time.Time) (*store.C
 	}
 
 	// Format messages for LLM
-	var sb strings.Builder
-	for _, m := range messages {
-		sender := m.SenderID
-		if m.IsOutgoing {
-			sender = "me"
-		}
-		ts := m.CreatedAt.Format("15:04")
-		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", ts, sender, m.Content))
-	}
-
-	resp, err := e.provider.Complete(ctx, llm.CompletionRequest{
-		SystemPrompt: extractionSystemPrompt,
-		UserPrompt:   fmt.Sprintf("Extract concepts and relationships from this chat transcript (%d messages):\n\n%s", len(messages), sb.String()),
-		MaxTokens:    4096,
-		Temperature:  0.2,
-	})
+	ids, err := e.store.LoadIdentities(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("llm completion: %w", err)
+		log.Printf("cerebro: failed to load contact names, using short sender IDs: %v", err)
 	}
-
-	// Parse response - strip markdown code blocks if present
-	content := resp.Content
-	if idx := strings.Index(content, "```json"); idx != -1 {
-		content = content[idx+7:]
-		if end := strings.Index(content, "```"); end != -1 {
-			content = content[:end]
-		}
-	} else if idx := strings.Index(content, "```"); idx != -1 {
-		content = content[idx+3:]
-		if end := strings.Index(content, "```"); end != -1 {
-			content = content[:end]
-		}
+	lines := make([]string, len(messages))
+	for i, m := range messages {
+		ts := m.CreatedAt.Format("15:04")
+		lines[i] = fmt.Sprintf("[%s] %s: %s", ts, ids.Sender(m.SenderID, m.SourceUUID, m.IsOutgoing), m.Content)
 	}
 
-	var result extractionResult
-	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &result); err != nil {
-		return nil, fmt.Errorf("parse extraction result: %w (response: %s)", err, truncate(resp.Content, 200))
+	result, resp, tokens, err := e.extractGraph(ctx, lines)
+	if err != nil {
+		return nil, err
 	}
 
 	// Upsert concepts and build name->id map
@@ -156,9 +210,9 @@ func (e *Extractor) Extract(ctx context.Context, start, end time.Time) (*store.C
 		MessageCount: len(messages),
 		ConceptCount: conceptCount,
 		EdgeCount:    edgeCount,
-		LLMProvider:  e.provider.Name(),
+		LLMProvider:  resp.Provider,
 		LLMModel:     resp.Model,
-		TokenCount:   resp.InputTokens + resp.OutputTokens,
+		TokenCount:   tokens,
 	}
 	id, err := e.store.SaveExtraction(ctx, extraction)
 	if err != nil {
@@ -169,10 +223,3 @@ func (e *Extractor) Extract(ctx context.Context, start, end time.Time) (*store.C
 	log.Printf("cerebro: extracted %d concepts, %d edges from %d messages", conceptCount, edgeCount, len(messages))
 	return &extraction, nil
 }
-
-func truncate(s string, n int) string {
-	if len(s) <= n {
-		return s
-	}
-	return s[:n] + "..."
-}
@@ -0,0 +1,50 @@
+package cerebro
+
+import (
+	"context"
+	"testing"
+
+	"signal-sideband/pkg/llm"
+)
+
+// Cassettes in testdata/cassettes were recorded with LLM_REPLAY_MODE=record.
+// Changing the prompt changes the request keys; re-record them when that
+// happens.
+func TestExtractGraphReplay(t *testing.T) {
+	// Small enough that the transcript splits in two and the graphs merge
+	p, err := llm.NewReplayProvider("testdata/cassettes", llm.ReplayModeReplay, nil, llm.EstimateTokens(extractionSystemPrompt)+30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Extractor{provider: p}
+	lines := []string{
+		"[09:00] alice: has anyone read Dune yet?",
+		"[09:02] bob: Dune is next for the book club",
+		"[18:00] carol: book club moves to the library on thursday",
+	}
+
+	result, resp, tokens, err := e.extractGraph(context.Background(), lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	for _, c := range result.Concepts {
+		if names[c.Name] {
+			t.Errorf("concept %q repeated after merge", c.Name)
+		}
+		names[c.Name] = true
+	}
+	if len(result.Concepts) != 3 || !names["book club"] || !names["library"] {
+		t.Errorf("concepts = %+v", result.Concepts)
+	}
+	// The second part spells it "Book Club"; edges follow the first spelling
+	if len(result.Edges) != 2 || result.Edges[1].Source != "book club" {
+		t.Errorf("edges = %+v", result.Edges)
+	}
+	if resp == nil || resp.Model != "gpt-4o-mini" {
+		t.Errorf("last response = %+v", resp)
+	}
+	if tokens != 2*300 {
+		t.Errorf("tokens = %d, want the sum over both parts", tokens)
+	}
+}
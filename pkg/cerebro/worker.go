@@ -2,45 +2,27 @@ package cerebro
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 )
 
+// Worker holds the scheduled cerebro jobs: extraction of new messages and
+// enrichment of concepts.
 type Worker struct {
 	extractor *Extractor
 	enricher  *Enricher
-	interval  time.Duration
 }
 
-func NewWorker(extractor *Extractor, enricher *Enricher, interval time.Duration) *Worker {
-	return &Worker{extractor: extractor, enricher: enricher, interval: interval}
+func NewWorker(extractor *Extractor, enricher *Enricher) *Worker {
+	return &Worker{extractor: extractor, enricher: enricher}
 }
 
-func (w *Worker) Start(ctx context.Context) {
-	// Run once at startup after a brief delay
-	select {
-	case <-ctx.Done():
-		return
-	case <-time.After(30 * time.Second):
-	}
-	w.run(ctx)
-
-	ticker := time.NewTicker(w.interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			w.run(ctx)
-		}
-	}
-}
-
-func (w *Worker) run(ctx context.Context) {
+// Extract pulls concepts from messages since the last extraction (at most
+// the last 24h) and drops expired enrichments.
+func (w *Worker) Extract(ctx context.Context) error {
 	if w.extractor == nil {
-		return
+		return nil
 	}
 
 	// Determine extraction window
@@ -56,18 +38,11 @@ func (w *Worker) run(ctx context.Context) {
 
 	// Skip if less than 1 hour since last extraction
 	if now.Sub(start) < time.Hour {
-		return
+		return nil
 	}
 
 	if _, err := w.extractor.Extract(ctx, start, now); err != nil {
-		log.Printf("cerebro worker: extraction failed: %v", err)
-	}
-
-	// Enrich top concepts needing it
-	if w.enricher != nil {
-		if err := w.enricher.EnrichBatch(ctx, 5); err != nil {
-			log.Printf("cerebro worker: enrichment failed: %v", err)
-		}
+		return fmt.Errorf("extraction: %w", err)
 	}
 
 	// Cleanup expired enrichments
@@ -76,4 +51,16 @@ func (w *Worker) run(ctx context.Context) {
 	} else if deleted > 0 {
 		log.Printf("cerebro worker: cleaned up %d expired enrichments", deleted)
 	}
+	return nil
+}
+
+// Enrich looks up the top concepts that need it.
+func (w *Worker) Enrich(ctx context.Context) error {
+	if w.enricher == nil {
+		return nil
+	}
+	if err := w.enricher.EnrichBatch(ctx, 5); err != nil {
+		return fmt.Errorf("enrichment: %w", err)
+	}
+	return nil
 }
@@ -3,6 +3,7 @@ package digest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -29,7 +30,83 @@ type digestJSON struct {
 	ActionItems []string `json:"action_items"`
 }
 
+func (d *digestJSON) Validate() error {
+	if d.Title == "" || d.Summary == "" {
+		return errors.New("title and summary are required")
+	}
+	return nil
+}
+
+// Generate digests the period through the named lens (DefaultLens when
+// omitted) and saves the result as a custom-period digest.
 func (g *Generator) Generate(ctx context.Context, start, end time.Time, groupID *string, lens ...string) (*store.DigestRecord, error) {
+	name := DefaultLens
+	if len(lens) > 0 && lens[0] != "" {
+		name = lens[0]
+	}
+	return g.generate(ctx, store.PeriodCustom, start, end, groupID, name)
+}
+
+func (g *Generator) generate(ctx context.Context, kind string, start, end time.Time, groupID *string, lensName string) (*store.DigestRecord, error) {
+	l, err := g.lens(ctx, lensName)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := g.digest(ctx, *l, start, end, groupID, false)
+	if err != nil {
+		return nil, err
+	}
+	record.PeriodKind = kind
+	return g.save(ctx, record)
+}
+
+func (g *Generator) save(ctx context.Context, record *store.DigestRecord) (*store.DigestRecord, error) {
+	id, err := g.store.SaveDigest(ctx, *record)
+	if err != nil {
+		return nil, fmt.Errorf("save digest: %w", err)
+	}
+	record.ID = id
+	return record, nil
+}
+
+// GenerateDaily digests the group's messages on the calendar day before at,
+// in at's time zone, with the default lens.
+func (g *Generator) GenerateDaily(ctx context.Context, at time.Time, groupID *string) error {
+	start := time.Date(at.Year(), at.Month(), at.Day()-1, 0, 0, 0, 0, at.Location())
+	end := time.Date(at.Year(), at.Month(), at.Day()-1, 23, 59, 59, 0, at.Location())
+
+	log.Printf("Generating daily digest for %s (group %s)", start.Format("2006-01-02"), groupLabel(groupID))
+	digest, err := g.generate(ctx, store.PeriodDaily, start, end, groupID, DefaultLens)
+	if err != nil {
+		return err
+	}
+	log.Printf("Daily digest generated: %s (id: %s)", digest.Title, digest.ID)
+	return nil
+}
+
+// DryRun runs lens over the most recent chunk of the period without saving,
+// failing if the reply isn't a valid digest (no raw-text fallback).
+func (g *Generator) DryRun(ctx context.Context, lens store.DigestLens, start, end time.Time, groupID *string) (*store.DigestRecord, error) {
+	return g.digest(ctx, lens, start, end, groupID, true)
+}
+
+// lens looks up a stored lens, falling back to the built-ins if the lens
+// table can't be read.
+func (g *Generator) lens(ctx context.Context, name string) (*store.DigestLens, error) {
+	l, err := g.store.GetLens(ctx, name)
+	if err == nil {
+		return l, nil
+	}
+	for _, b := range BuiltinLenses() {
+		if b.Name == name {
+			return &b, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown lens %q: %w", name, err)
+}
+
+func (g *Generator) digest(ctx context.Context, lens store.DigestLens, start, end time.Time, groupID *string, dryRun bool) (*store.DigestRecord, error) {
 	messages, err := g.store.GetMessagesByTimeRange(ctx, start, end, groupID)
 	if err != nil {
 		return nil, fmt.Errorf("fetch messages: %w", err)
@@ -39,61 +116,50 @@ func (g *Generator) Generate(ctx context.Context, start, end time.Time, groupID
 		return nil, fmt.Errorf("no messages found in the specified time range")
 	}
 
-	// Format messages for the LLM
-	var sb strings.Builder
-	for _, m := range messages {
-		sender := m.SenderID
-		if m.IsOutgoing {
-			sender = "me"
-		}
+	ids := loadIdentities(ctx, g.store)
+	lines := make([]string, len(messages))
+	for i, m := range messages {
 		ts := m.CreatedAt.Format("15:04")
-		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", ts, sender, m.Content))
+		lines[i] = fmt.Sprintf("[%s] %s: %s", ts, ids.Sender(m.SenderID, m.SourceUUID, m.IsOutgoing), m.Content)
 	}
 
 	periodLabel := fmt.Sprintf("%s to %s", start.Format("Jan 2, 2006"), end.Format("Jan 2, 2006"))
 
-	selectedLens := ""
-	if len(lens) > 0 {
-		selectedLens = lens[0]
-	}
-	temperature := 0.3
-	if selectedLens != "" && selectedLens != "default" {
-		temperature = 0.7 // more creative for character lenses
-	}
+	systemPrompt := lensSystemPrompt(lens)
+	temperature := lens.Temperature
 
-	// Call LLM
-	resp, err := g.provider.Complete(ctx, llm.CompletionRequest{
-		SystemPrompt: systemPromptForLens(selectedLens),
-		UserPrompt:   buildUserPrompt(sb.String(), periodLabel),
-		MaxTokens:    4096,
-		Temperature:  temperature,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("llm completion: %w", err)
+	// Split the transcript to fit the provider's context; each chunk is
+	// digested on its own and the partial digests are merged.
+	chunks := llm.Chunk(lines, llm.ChunkTokens(g.provider)-llm.EstimateTokens(systemPrompt))
+	if dryRun {
+		chunks = chunks[len(chunks)-1:]
 	}
-
-	// Parse response
+	var stats callStats
 	var parsed digestJSON
-	content := resp.Content
 
-	// Try to extract JSON from markdown code blocks if present
-	if idx := strings.Index(content, "```json"); idx != -1 {
-		content = content[idx+7:]
-		if end := strings.Index(content, "```"); end != -1 {
-			content = content[:end]
+	if len(chunks) == 1 {
+		var resp *llm.CompletionResponse
+		parsed, resp, err = llm.CompleteJSON[digestJSON](ctx, g.provider, llm.CompletionRequest{
+			SystemPrompt: systemPrompt,
+			UserPrompt:   buildUserPrompt(chunks[0], periodLabel),
+			MaxTokens:    4096,
+			Temperature:  llm.Temperature(temperature),
+		})
+		var parseErr *llm.ParseError
+		if errors.As(err, &parseErr) && !dryRun {
+			log.Printf("Failed to parse LLM response as JSON, using raw content: %v", err)
+			parsed = digestJSON{
+				Title:   periodLabel + " Digest",
+				Summary: resp.Content,
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("llm completion: %w", err)
 		}
-	} else if idx := strings.Index(content, "```"); idx != -1 {
-		content = content[idx+3:]
-		if end := strings.Index(content, "```"); end != -1 {
-			content = content[:end]
-		}
-	}
-
-	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &parsed); err != nil {
-		log.Printf("Failed to parse LLM response as JSON, using raw content: %v", err)
-		parsed = digestJSON{
-			Title:   periodLabel + " Digest",
-			Summary: resp.Content,
+		stats.add(resp)
+	} else {
+		parsed, err = g.mapReduce(ctx, chunks, systemPrompt, periodLabel, temperature, &stats)
+		if err != nil {
+			return nil, err
 		}
 	}
 
@@ -110,16 +176,143 @@ func (g *Generator) Generate(ctx context.Context, start, end time.Time, groupID
 		PeriodStart: start,
 		PeriodEnd:   end,
 		GroupID:     groupID,
-		LLMProvider: g.provider.Name(),
-		LLMModel:    resp.Model,
-		TokenCount:  resp.InputTokens + resp.OutputTokens,
+		LLMProvider: stats.provider,
+		LLMModel:    stats.model,
+		TokenCount:  stats.tokens,
 	}
 
-	id, err := g.store.SaveDigest(ctx, record)
+	return &record, nil
+}
+
+// mapReduce digests each chunk separately, then merges the partial digests,
+// in rounds if they don't fit in one request.
+func (g *Generator) mapReduce(ctx context.Context, chunks []string, systemPrompt, periodLabel string, temperature float64, stats *callStats) (digestJSON, error) {
+	partials := make([]digestJSON, 0, len(chunks))
+	for i, chunk := range chunks {
+		llm.ReportProgress(ctx, "digest", "map", i, len(chunks))
+		part, resp, err := llm.CompleteJSON[digestJSON](ctx, g.provider, llm.CompletionRequest{
+			SystemPrompt: systemPrompt,
+			UserPrompt:   buildUserPrompt(chunk, fmt.Sprintf("%s (part %d of %d)", periodLabel, i+1, len(chunks))),
+			MaxTokens:    4096,
+			Temperature:  llm.Temperature(temperature),
+		})
+		stats.add(resp)
+		if err != nil {
+			return digestJSON{}, fmt.Errorf("digest part %d of %d: %w", i+1, len(chunks), err)
+		}
+		partials = append(partials, part)
+	}
+	llm.ReportProgress(ctx, "digest", "map", len(chunks), len(chunks))
+
+	return g.reduce(ctx, digestLines(partials), systemPrompt, temperature, stats, func(group string, _ int) string {
+		return buildReducePrompt(group, periodLabel)
+	})
+}
+
+// reduce merges digests, one JSON object per line, into a single digest. It
+// takes at least one call, and more rounds when the lines don't fit in one
+// request. prompt builds the user prompt for a group of lines in a round.
+func (g *Generator) reduce(ctx context.Context, lines []string, systemPrompt string, temperature float64, stats *callStats, prompt func(group string, round int) string) (digestJSON, error) {
+	budget := llm.ChunkTokens(g.provider) - llm.EstimateTokens(systemPrompt)
+	for round := 1; ; round++ {
+		groups := llm.Chunk(lines, budget)
+		if len(groups) >= len(lines) {
+			groups = pairLines(lines) // each line alone fills the budget; merge two at a time
+		}
+
+		merged := make([]digestJSON, 0, len(groups))
+		for i, group := range groups {
+			llm.ReportProgress(ctx, "digest", fmt.Sprintf("reduce round %d", round), i, len(groups))
+			d, resp, err := llm.CompleteJSON[digestJSON](ctx, g.provider, llm.CompletionRequest{
+				SystemPrompt: systemPrompt,
+				UserPrompt:   prompt(group, round),
+				MaxTokens:    4096,
+				Temperature:  llm.Temperature(temperature),
+			})
+			stats.add(resp)
+			if err != nil {
+				return digestJSON{}, fmt.Errorf("merge partial digests: %w", err)
+			}
+			merged = append(merged, d)
+		}
+		if len(merged) == 1 {
+			return merged[0], nil
+		}
+		lines = digestLines(merged)
+	}
+}
+
+func digestLines(digests []digestJSON) []string {
+	lines := make([]string, len(digests))
+	for i, d := range digests {
+		b, _ := json.Marshal(d)
+		lines[i] = string(b)
+	}
+	return lines
+}
+
+func pairLines(lines []string) []string {
+	var groups []string
+	for i := 0; i < len(lines); i += 2 {
+		end := min(i+2, len(lines))
+		groups = append(groups, strings.Join(lines[i:end], "\n"))
+	}
+	return groups
+}
+
+// forEachGroup runs fn for every group with messages in [start, end),
+// carrying on past failures and returning them joined. 1:1 messages get one
+// run of their own under store.DirectGroupID.
+func forEachGroup(ctx context.Context, s *store.Store, start, end time.Time, fn func(groupID *string) error) error {
+	ids, err := s.ActiveGroupIDs(ctx, start, end)
 	if err != nil {
-		return nil, fmt.Errorf("save digest: %w", err)
+		return fmt.Errorf("list active groups: %w", err)
+	}
+	if len(ids) == 0 {
+		log.Printf("No messages between %s and %s, nothing to do", start.Format(time.DateTime), end.Format(time.DateTime))
 	}
-	record.ID = id
 
-	return &record, nil
+	var errs []error
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := fn(&id); err != nil {
+			errs = append(errs, fmt.Errorf("group %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func groupLabel(groupID *string) string {
+	if groupID == nil {
+		return "all"
+	}
+	return *groupID
+}
+
+// loadIdentities returns the contact names for transcripts. Without them
+// senders still get short IDs, so a lookup failure is only logged.
+func loadIdentities(ctx context.Context, s *store.Store) *store.Identities {
+	ids, err := s.LoadIdentities(ctx)
+	if err != nil {
+		log.Printf("Failed to load contact names, using short sender IDs: %v", err)
+	}
+	return ids
+}
+
+// callStats totals token usage across the calls behind one result.
+type callStats struct {
+	provider string
+	model    string
+	tokens   int
+}
+
+func (s *callStats) add(resp *llm.CompletionResponse) {
+	if resp == nil {
+		return
+	}
+	s.provider = resp.Provider
+	s.model = resp.Model
+	s.tokens += resp.InputTokens + resp.OutputTokens
 }
@@ -0,0 +1,43 @@
+package digest
+
+import (
+	"context"
+	"testing"
+
+	"signal-sideband/pkg/llm"
+)
+
+// Cassettes in testdata/cassettes were recorded with LLM_REPLAY_MODE=record.
+// Changing the prompts changes the request keys; re-record them when that
+// happens.
+func replayProvider(t *testing.T) llm.Provider {
+	t.Helper()
+	p, err := llm.NewReplayProvider("testdata/cassettes", llm.ReplayModeReplay, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestMapReduceReplay(t *testing.T) {
+	g := &Generator{provider: replayProvider(t)}
+	chunks := []string{
+		"[09:00] alice: shall we ship the release on friday?\n[09:05] bob: yes, friday works\n",
+		"[17:00] bob: I'll write the release notes tonight\n",
+	}
+
+	var stats callStats
+	d, err := g.mapReduce(context.Background(), chunks, lensSystemPrompt(BuiltinLenses()[0]), "Jan 1, 2026 to Jan 2, 2026", 0.3, &stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Title != "Friday release" {
+		t.Errorf("title = %q", d.Title)
+	}
+	if len(d.Decisions) != 1 || len(d.ActionItems) != 1 {
+		t.Errorf("decisions = %v, action items = %v", d.Decisions, d.ActionItems)
+	}
+	if stats.tokens != 3*150 {
+		t.Errorf("tokens = %d, want the sum over map and reduce calls", stats.tokens)
+	}
+}
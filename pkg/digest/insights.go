@@ -3,6 +3,7 @@ package digest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -29,69 +30,83 @@ type insightsJSON struct {
 	QuoteIndex int      `json:"quote_index"`
 }
 
-func (g *InsightsGenerator) GenerateDailyInsights(ctx context.Context) error {
-	now := time.Now()
-	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-	end := now
+func (i *insightsJSON) Validate() error {
+	if i.Overview == "" {
+		return errors.New("overview is required")
+	}
+	return nil
+}
+
+const insightsSystemPrompt = `You are analyzing a day's chat messages. Respond with ONLY a JSON object (no markdown, no code blocks):
+{
+  "overview": "A 2-3 sentence conversational gist of the day. Start with 'Today the group...' or similar casual phrasing.",
+  "themes": ["theme1", "theme2", "theme3"],
+  "quote_index": 0
+}
+- themes: 3-5 topic/theme tags (short, lowercase)
+- quote_index: the [index] of the most interesting, funny, or notable message`
+
+// buildInsightsReducePrompt lists the per-chunk results with each chunk's
+// chosen quote so the merge can pick one by its global index.
+func buildInsightsReducePrompt(partials []insightsJSON, messages []store.MessageRecord) string {
+	var sb strings.Builder
+	sb.WriteString("The day was too long to read at once. These are the results for consecutive parts of it, in order:\n\n")
+	for i, p := range partials {
+		sb.WriteString(fmt.Sprintf("Part %d: %s\nThemes: %s\n", i+1, p.Overview, strings.Join(p.Themes, ", ")))
+		if p.QuoteIndex >= 0 && p.QuoteIndex < len(messages) {
+			sb.WriteString(fmt.Sprintf("Candidate quote [%d]: %s\n", p.QuoteIndex, messages[p.QuoteIndex].Content))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("Combine them into one result for the whole day. quote_index must be one of the candidate quote indices.")
+	return sb.String()
+}
 
-	// If early in the day, also include yesterday
-	if now.Hour() < 6 {
-		start = start.Add(-24 * time.Hour)
+// insightsWindow is the stretch of chat an insight generated at covers, in
+// at's time zone: that day so far, plus the day before early in the morning.
+func insightsWindow(at time.Time) (time.Time, time.Time) {
+	start := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	if at.Hour() < 6 {
+		start = start.AddDate(0, 0, -1)
 	}
+	return start, at
+}
 
-	messages, err := g.store.GetMessagesByTimeRange(ctx, start, end, nil)
+// GenerateScheduledInsights generates insights for every group with
+// messages in the window ending at.
+func (g *InsightsGenerator) GenerateScheduledInsights(ctx context.Context, at time.Time) error {
+	start, end := insightsWindow(at)
+	return forEachGroup(ctx, g.store, start, end, func(groupID *string) error {
+		return g.GenerateDailyInsights(ctx, at, groupID)
+	})
+}
+
+// GenerateDailyInsights summarizes the group's day up to at (all groups when
+// groupID is nil) with its snapshot and superlatives.
+func (g *InsightsGenerator) GenerateDailyInsights(ctx context.Context, at time.Time, groupID *string) error {
+	start, end := insightsWindow(at)
+
+	messages, err := g.store.GetMessagesByTimeRange(ctx, start, end, groupID)
 	if err != nil {
 		return fmt.Errorf("fetch messages: %w", err)
 	}
 
 	if len(messages) < 5 {
-		log.Println("Insights: fewer than 5 messages, skipping")
+		log.Printf("Insights: fewer than 5 messages in group %s, skipping", groupLabel(groupID))
 		return nil
 	}
 
-	// Format messages for LLM
-	var sb strings.Builder
+	// Format messages for LLM; indices are global so quote_index survives chunking
+	ids := loadIdentities(ctx, g.store)
+	lines := make([]string, len(messages))
 	for i, m := range messages {
-		sender := m.SenderID
-		if m.IsOutgoing {
-			sender = "me"
-		}
 		ts := m.CreatedAt.Format("15:04")
-		sb.WriteString(fmt.Sprintf("[%d] [%s] %s: %s\n", i, ts, sender, m.Content))
+		lines[i] = fmt.Sprintf("[%d] [%s] %s: %s", i, ts, ids.Sender(m.SenderID, m.SourceUUID, m.IsOutgoing), m.Content)
 	}
 
-	resp, err := g.provider.Complete(ctx, llm.CompletionRequest{
-		SystemPrompt: `You are analyzing a day's chat messages. Respond with ONLY a JSON object (no markdown, no code blocks):
-{
-  "overview": "A 2-3 sentence conversational gist of the day. Start with 'Today the group...' or similar casual phrasing.",
-  "themes": ["theme1", "theme2", "theme3"],
-  "quote_index": 0
-}
-- themes: 3-5 topic/theme tags (short, lowercase)
-- quote_index: the [index] of the most interesting, funny, or notable message`,
-		UserPrompt:  sb.String(),
-		MaxTokens:   512,
-		Temperature: 0.4,
-	})
+	parsed, err := g.summarize(ctx, lines, messages)
 	if err != nil {
-		return fmt.Errorf("llm completion: %w", err)
-	}
-
-	content := resp.Content
-	content = strings.TrimSpace(content)
-	if strings.HasPrefix(content, "```") {
-		if idx := strings.Index(content[3:], "\n"); idx != -1 {
-			content = content[3+idx+1:]
-		}
-		if idx := strings.LastIndex(content, "```"); idx != -1 {
-			content = content[:idx]
-		}
-		content = strings.TrimSpace(content)
-	}
-
-	var parsed insightsJSON
-	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
-		return fmt.Errorf("parse insights json: %w (content: %s)", err, content)
+		return err
 	}
 
 	// Pick the quote
@@ -99,32 +114,29 @@ func (g *InsightsGenerator) GenerateDailyInsights(ctx context.Context) error {
 	if parsed.QuoteIndex >= 0 && parsed.QuoteIndex < len(messages) {
 		msg := messages[parsed.QuoteIndex]
 		quoteContent = msg.Content
-		quoteSender = msg.SenderID
-		if msg.IsOutgoing {
-			quoteSender = "me"
-		}
+		quoteSender = ids.Sender(msg.SenderID, msg.SourceUUID, msg.IsOutgoing)
 	} else if len(messages) > 0 {
 		// Fallback to random quote
-		qc, qs, err := g.store.GetRandomQuote(ctx)
+		qc, qs, err := g.store.GetRandomQuote(ctx, groupID)
 		if err == nil {
 			quoteContent = qc
-			quoteSender = qs
+			quoteSender = ids.Name(qs)
 		}
 	}
 
 	themes, _ := json.Marshal(parsed.Themes)
 
 	// Compute and cache superlatives
-	superlatives := g.store.GetSuperlatives(ctx)
+	superlatives := g.store.GetSuperlatives(ctx, groupID)
 	superlativesJSON, _ := json.Marshal(superlatives)
 
 	// Compute snapshot data
 	snapshotDate := start
-	snapshot, snapErr := g.store.ComputeDaySnapshot(ctx, start)
+	snapshot, snapErr := g.store.ComputeDaySnapshot(ctx, start, groupID)
 	var snapshotJSON json.RawMessage
 	if snapErr == nil && snapshot != nil {
-		if now.Weekday() == time.Sunday {
-			weeklyTotal, busiestDay, busiestDayCount := g.store.ComputeWeeklyExtras(ctx, start)
+		if at.Weekday() == time.Sunday {
+			weeklyTotal, busiestDay, busiestDayCount := g.store.ComputeWeeklyExtras(ctx, start, groupID)
 			snapshot.IsWeekly = true
 			snapshot.WeeklyTotal = weeklyTotal
 			snapshot.BusiestDay = busiestDay
@@ -135,24 +147,90 @@ func (g *InsightsGenerator) GenerateDailyInsights(ctx context.Context) error {
 		snapshotJSON = json.RawMessage(`{}`)
 	}
 
-	id, err := g.store.SaveDailyInsight(ctx, parsed.Overview, themes, quoteContent, quoteSender, superlativesJSON, snapshotJSON, &snapshotDate)
+	id, err := g.store.SaveDailyInsight(ctx, parsed.Overview, themes, quoteContent, quoteSender, superlativesJSON, snapshotJSON, &snapshotDate, groupID)
 	if err != nil {
 		return fmt.Errorf("save insight: %w", err)
 	}
 
-	log.Printf("Insights: generated daily insight %s", id)
+	log.Printf("Insights: generated daily insight %s (group %s)", id, groupLabel(groupID))
+	return nil
+}
 
-	// Generate nano banana picture of the day
-	if g.picGen != nil {
-		imagePath, err := g.picGen.Generate(ctx, parsed.Themes, parsed.Overview)
+// summarize asks the model for the day's overview, themes and quote over
+// lines (one per message, indexed like messages), chunk by chunk when the day
+// is too long, merging the parts in one more call.
+func (g *InsightsGenerator) summarize(ctx context.Context, lines []string, messages []store.MessageRecord) (insightsJSON, error) {
+	chunks := llm.Chunk(lines, llm.ChunkTokens(g.provider)-llm.EstimateTokens(insightsSystemPrompt))
+	partials := make([]insightsJSON, 0, len(chunks))
+	for i, chunk := range chunks {
+		if len(chunks) > 1 {
+			llm.ReportProgress(ctx, "insights", "map", i, len(chunks))
+		}
+		part, _, err := llm.CompleteJSON[insightsJSON](ctx, g.provider, llm.CompletionRequest{
+			SystemPrompt: insightsSystemPrompt,
+			UserPrompt:   chunk,
+			MaxTokens:    512,
+			Temperature:  llm.Temperature(0.4),
+		})
 		if err != nil {
-			log.Printf("PicOfDay: generation failed: %v", err)
-		} else if imagePath != "" {
-			if err := g.store.SetInsightImagePath(ctx, id, imagePath); err != nil {
-				log.Printf("PicOfDay: failed to save image path: %v", err)
-			}
+			return insightsJSON{}, fmt.Errorf("llm completion: %w", err)
 		}
+		partials = append(partials, part)
 	}
 
+	if len(partials) == 1 {
+		return partials[0], nil
+	}
+	llm.ReportProgress(ctx, "insights", "reduce", 0, 1)
+	parsed, _, err := llm.CompleteJSON[insightsJSON](ctx, g.provider, llm.CompletionRequest{
+		SystemPrompt: insightsSystemPrompt,
+		UserPrompt:   buildInsightsReducePrompt(partials, messages),
+		MaxTokens:    512,
+		Temperature:  llm.Temperature(0.4),
+	})
+	if err != nil {
+		return insightsJSON{}, fmt.Errorf("merge partial insights: %w", err)
+	}
+	return parsed, nil
+}
+
+// GenerateScheduledPics illustrates the latest insight of every group with
+// messages in the day before at.
+func (g *InsightsGenerator) GenerateScheduledPics(ctx context.Context, at time.Time) error {
+	if g.picGen == nil {
+		return nil
+	}
+	return forEachGroup(ctx, g.store, at.AddDate(0, 0, -1), at, func(groupID *string) error {
+		return g.GeneratePicOfDay(ctx, at, groupID)
+	})
+}
+
+// GeneratePicOfDay illustrates the group's latest insight if it is from the
+// day before at and has no picture yet. It does nothing without an image
+// generator.
+func (g *InsightsGenerator) GeneratePicOfDay(ctx context.Context, at time.Time, groupID *string) error {
+	if g.picGen == nil {
+		return nil
+	}
+	insight, err := g.store.GetLatestInsight(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("latest insight: %w", err)
+	}
+	if insight.ImagePath != "" || at.Sub(insight.CreatedAt) > 24*time.Hour {
+		return nil
+	}
+
+	var themes []string
+	_ = json.Unmarshal(insight.Themes, &themes)
+	imagePath, err := g.picGen.Generate(ctx, themes, insight.Overview)
+	if err != nil {
+		return fmt.Errorf("generate picture: %w", err)
+	}
+	if imagePath == "" {
+		return nil
+	}
+	if err := g.store.SetInsightImagePath(ctx, insight.ID, imagePath); err != nil {
+		return fmt.Errorf("save image path: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,68 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"signal-sideband/pkg/llm"
+	"signal-sideband/pkg/store"
+)
+
+func TestInsightsWindow(t *testing.T) {
+	tz, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// Early in the morning the window reaches back to the previous
+	// midnight in at's zone, not the process's
+	at := time.Date(2026, 3, 17, 0, 15, 0, 0, tz)
+	start, end := insightsWindow(at)
+	if want := time.Date(2026, 3, 16, 0, 0, 0, 0, tz); !start.Equal(want) {
+		t.Errorf("early start = %s, want %s", start, want)
+	}
+	if !end.Equal(at) {
+		t.Errorf("end = %s, want %s", end, at)
+	}
+
+	at = time.Date(2026, 3, 17, 18, 0, 0, 0, tz)
+	if start, _ := insightsWindow(at); !start.Equal(time.Date(2026, 3, 17, 0, 0, 0, 0, tz)) {
+		t.Errorf("evening start = %s", start)
+	}
+}
+
+func TestInsightsReplay(t *testing.T) {
+	// Small enough that the day splits in two and the parts are merged
+	p, err := llm.NewReplayProvider("testdata/cassettes", llm.ReplayModeReplay, nil, llm.EstimateTokens(insightsSystemPrompt)+40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := &InsightsGenerator{provider: p}
+
+	texts := []string{
+		"alice: the cat knocked my coffee onto the laptop",
+		"bob: rice, put it in rice",
+		"alice: it's fine, it still boots",
+		"bob: who's in for tacos tonight?",
+		"carol: me! 7pm at the usual place",
+	}
+	messages := make([]store.MessageRecord, len(texts))
+	lines := make([]string, len(texts))
+	for i, text := range texts {
+		messages[i].Content = text
+		lines[i] = fmt.Sprintf("[%d] [12:0%d] %s", i, i, text)
+	}
+
+	got, err := g.summarize(context.Background(), lines, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Overview == "" || len(got.Themes) != 3 {
+		t.Errorf("insight = %+v", got)
+	}
+	if got.QuoteIndex != 1 {
+		t.Errorf("quote_index = %d, want the merged pick 1", got.QuoteIndex)
+	}
+}
@@ -1,5 +1,14 @@
 package digest
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"signal-sideband/pkg/store"
+)
+
 const defaultSystemPrompt = `You are a newsletter editor analyzing Signal group messages. Your task is to create a well-structured digest of the conversation.
 
 You must respond with valid JSON in exactly this format:
@@ -19,8 +28,7 @@ Guidelines:
 - If no clear decisions or action items, use empty arrays
 - Focus on substance, not pleasantries or greetings`
 
-var lensPrompts = map[string]string{
-	"gondor": `You are a chronicler of the Citadel of Minas Tirith, tasked with recording the dispatches and parleys of the Free Peoples. Write as though you are composing an entry in the Great Archives — the tone of Tolkien's prose, epic and grave, with the weight of ages behind every word. Refer to conversations as "councils," participants as "riders," "captains," or "wardens," and topics as "tidings from the realm." Decisions are "decrees of the council." Action items are "quests set before the fellowship."
+const gondorPrompt = `You are a chronicler of the Citadel of Minas Tirith, tasked with recording the dispatches and parleys of the Free Peoples. Write as though you are composing an entry in the Great Archives — the tone of Tolkien's prose, epic and grave, with the weight of ages behind every word. Refer to conversations as "councils," participants as "riders," "captains," or "wardens," and topics as "tidings from the realm." Decisions are "decrees of the council." Action items are "quests set before the fellowship."
 
 You must respond with valid JSON in exactly this format:
 {
@@ -35,9 +43,9 @@ Guidelines:
 - Write as Tolkien would — "And so it was spoken in the halls..." style
 - Topics should be named like chapter headings from The Lord of the Rings
 - Lean into the epic, but keep the actual substance of the conversations intact
-- If no clear decisions or action items, use empty arrays`,
+- If no clear decisions or action items, use empty arrays`
 
-	"confucius": `You are a sage in the tradition of Confucius, reflecting upon the exchanges of your students and fellow scholars. Compose this digest as a collection of observations and teachings drawn from the conversation. Frame discussions as philosophical dialogues, participants as "the student" or "the elder," and weave in aphoristic wisdom. Every topic is a lesson; every decision is a principle discovered.
+const confuciusPrompt = `You are a sage in the tradition of Confucius, reflecting upon the exchanges of your students and fellow scholars. Compose this digest as a collection of observations and teachings drawn from the conversation. Frame discussions as philosophical dialogues, participants as "the student" or "the elder," and weave in aphoristic wisdom. Every topic is a lesson; every decision is a principle discovered.
 
 You must respond with valid JSON in exactly this format:
 {
@@ -53,9 +61,9 @@ Guidelines:
 - Use phrases like "The Master said..." or "It is written..."
 - Topics should read like chapter titles from the Analects
 - Preserve the actual content, but dress it in wisdom
-- If no clear decisions or action items, use empty arrays`,
+- If no clear decisions or action items, use empty arrays`
 
-	"city-wok": `You are the owner of City Wok, the best Chinese restaurant in South Park, Colorado. You are very passionate and animated. You talk about everything with great intensity, frequently getting distracted by Mongorians and your ongoing battle to protect your City Wall. Write the digest in your distinctive voice — dramatic, excitable, with your signature accent and expressions. Conversations are "order" discussions, people are "customer" or sometimes "goddamn Mongorian" if they cause trouble, and action items are things that need doing "right now, before Mongorian come back."
+const cityWokPrompt = `You are the owner of City Wok, the best Chinese restaurant in South Park, Colorado. You are very passionate and animated. You talk about everything with great intensity, frequently getting distracted by Mongorians and your ongoing battle to protect your City Wall. Write the digest in your distinctive voice — dramatic, excitable, with your signature accent and expressions. Conversations are "order" discussions, people are "customer" or sometimes "goddamn Mongorian" if they cause trouble, and action items are things that need doing "right now, before Mongorian come back."
 
 You must respond with valid JSON in exactly this format:
 {
@@ -71,21 +79,114 @@ Guidelines:
 - Use his speech patterns and expressions throughout
 - Topics should sound like they're being yelled across the restaurant
 - Keep the actual substance but make it entertaining
-- If no clear decisions or action items, use empty arrays`,
+- If no clear decisions or action items, use empty arrays`
+
+// Schema variants control the JSON format instructions appended to a lens's
+// system prompt.
+const (
+	SchemaStandard = "standard" // title, summary, topics, decisions, action items
+	SchemaBrief    = "brief"    // title, summary, topics
+	SchemaCustom   = "custom"   // the prompt specifies the format itself
+)
+
+var schemaInstructions = map[string]string{
+	SchemaStandard: `You must respond with valid JSON in exactly this format:
+{
+  "title": "A concise title for this digest",
+  "summary": "A markdown-formatted summary of the key discussions (2-4 paragraphs)",
+  "topics": ["topic1", "topic2", "topic3"],
+  "decisions": ["Decision or conclusion that was reached", ...],
+  "action_items": ["Action item someone committed to", ...]
 }
+If no clear decisions or action items, use empty arrays.`,
+	SchemaBrief: `You must respond with valid JSON in exactly this format:
+{
+  "title": "A concise title for this digest",
+  "summary": "A short markdown-formatted summary (1 paragraph)",
+  "topics": ["topic1", "topic2", "topic3"]
+}`,
+	SchemaCustom: "",
+}
+
+// DefaultLens is used when a digest request names no lens.
+const DefaultLens = "default"
 
-// LensNames returns all available lens keys.
-func LensNames() []string {
-	return []string{"default", "gondor", "confucius", "city-wok"}
+// BuiltinLenses are seeded into digest_lenses at startup and used directly
+// if the table is unavailable.
+func BuiltinLenses() []store.DigestLens {
+	return []store.DigestLens{
+		{Name: DefaultLens, Label: "Standard", SystemPrompt: defaultSystemPrompt, Temperature: 0.3, SchemaVariant: SchemaCustom},
+		{Name: "gondor", Label: "Gondor", SystemPrompt: gondorPrompt, Temperature: 0.7, SchemaVariant: SchemaCustom},
+		{Name: "confucius", Label: "Confucius", SystemPrompt: confuciusPrompt, Temperature: 0.7, SchemaVariant: SchemaCustom},
+		{Name: "city-wok", Label: "City Wok", SystemPrompt: cityWokPrompt, Temperature: 0.7, SchemaVariant: SchemaCustom},
+	}
 }
 
-func systemPromptForLens(lens string) string {
-	if p, ok := lensPrompts[lens]; ok {
-		return p
+var lensNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,47}$`)
+
+// ValidateLens checks a lens's fields before it is stored.
+func ValidateLens(l store.DigestLens) error {
+	if !lensNamePattern.MatchString(l.Name) {
+		return errors.New("name must be 1-48 lowercase letters, digits or dashes")
 	}
-	return defaultSystemPrompt
+	if strings.TrimSpace(l.SystemPrompt) == "" {
+		return errors.New("system_prompt is required")
+	}
+	if l.Temperature < 0 || l.Temperature > 2 {
+		return errors.New("temperature must be between 0 and 2")
+	}
+	if _, ok := schemaInstructions[l.SchemaVariant]; !ok {
+		return fmt.Errorf("schema_variant must be %s, %s or %s", SchemaStandard, SchemaBrief, SchemaCustom)
+	}
+	return nil
+}
+
+// lensSystemPrompt is the lens prompt plus its variant's format instructions.
+func lensSystemPrompt(l store.DigestLens) string {
+	if instr := schemaInstructions[l.SchemaVariant]; instr != "" {
+		return l.SystemPrompt + "\n\n" + instr
+	}
+	return l.SystemPrompt
 }
 
 func buildUserPrompt(messages string, periodLabel string) string {
 	return "Here are the messages from " + periodLabel + ":\n\n" + messages + "\n\nPlease create a digest of these conversations."
 }
+
+func buildReducePrompt(partials string, periodLabel string) string {
+	return "These are digests of consecutive parts of the conversations from " + periodLabel + ", one JSON object per line, in order:\n\n" + partials +
+		"\nMerge them into a single digest of the whole period in the same JSON format. Deduplicate topics, decisions and action items."
+}
+
+const rollupSystemPrompt = `You are a newsletter editor writing the %s roundup of a Signal group. You are given the daily digests of the period, not the raw messages. Most members read only this roundup, so it must stand on its own.
+
+You must respond with valid JSON in exactly this format:
+{
+  "title": "A concise, descriptive title for this roundup",
+  "summary": "A markdown-formatted summary of the period (3-5 paragraphs)",
+  "topics": ["topic1", "topic2", "topic3"],
+  "decisions": ["Decision or conclusion that was reached", ...],
+  "action_items": ["Action item someone committed to that is still open", ...]
+}
+
+Guidelines:
+- Lead with the recurring topics: what kept coming up across days and how the discussion moved
+- Mention one-off topics only if they mattered
+- Consolidate decisions: merge repeats, and when a decision was revisited or reversed keep the final outcome and say that it changed
+- Drop action items that a later day shows were done
+- Order topics by how often they came up
+- Use markdown formatting in the summary (bold, lists, etc.)
+- If no clear decisions or action items, use empty arrays`
+
+func buildRollupPrompt(digests, periodLabel string, recurring []topicCount) string {
+	var sb strings.Builder
+	sb.WriteString("Here are the daily digests from " + periodLabel + ", one JSON object per line, oldest first:\n\n" + digests)
+	if len(recurring) > 0 {
+		sb.WriteString("\nTopics that came up on more than one day:\n")
+		for _, t := range recurring {
+			fmt.Fprintf(&sb, "- %s (%d days)\n", t.Topic, t.Days)
+		}
+	}
+	sb.WriteString("\nPlease write the roundup of this period.")
+	return sb.String()
+}
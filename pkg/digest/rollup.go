@@ -0,0 +1,226 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"signal-sideband/pkg/store"
+)
+
+// maxRecurringTopics caps the recurring topics listed in a rollup prompt.
+const maxRecurringTopics = 10
+
+// GenerateScheduled is the scheduled digest job. For each group with
+// messages on the day before at it writes the daily digest, then the weekly
+// rollup on Mondays and the monthly rollup on the 1st for each group active
+// in that period. Rolling up in the same job, after the daily digests, means
+// a catch-up after downtime has every day of a period before summarizing it.
+// Digests that already exist are skipped, so when the scheduler retries a
+// failed run only the failed groups are redone; a group whose daily digest
+// failed gets no rollup until the retry has it.
+func (g *Generator) GenerateScheduled(ctx context.Context, at time.Time) error {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	dayStart := day.AddDate(0, 0, -1)
+	failed := make(map[string]bool)
+	err := forEachGroup(ctx, g.store, dayStart, day, func(groupID *string) error {
+		err := g.unlessDigested(ctx, store.PeriodDaily, dayStart, groupID, func() error {
+			return g.GenerateDaily(ctx, at, groupID)
+		})
+		if err != nil {
+			failed[*groupID] = true
+		}
+		return err
+	})
+	rollup := func(kind string, start, end time.Time, fn func(groupID *string) error) error {
+		return forEachGroup(ctx, g.store, start, end, func(groupID *string) error {
+			if failed[*groupID] {
+				return fmt.Errorf("%s rollup deferred until the daily digest succeeds", kind)
+			}
+			return g.unlessDigested(ctx, kind, start, groupID, func() error { return fn(groupID) })
+		})
+	}
+	if at.Weekday() == time.Monday {
+		start, end := weekBefore(at)
+		err = errors.Join(err, rollup(store.PeriodWeekly, start, end, func(groupID *string) error {
+			return g.GenerateWeekly(ctx, at, groupID)
+		}))
+	}
+	if at.Day() == 1 {
+		start, end := monthBefore(at)
+		err = errors.Join(err, rollup(store.PeriodMonthly, start, end, func(groupID *string) error {
+			return g.GenerateMonthly(ctx, at, groupID)
+		}))
+	}
+	return err
+}
+
+// unlessDigested runs fn unless the group already has a digest of kind for
+// the period starting at start.
+func (g *Generator) unlessDigested(ctx context.Context, kind string, start time.Time, groupID *string, fn func() error) error {
+	done, err := g.store.HasDigest(ctx, kind, start, groupID)
+	if err != nil {
+		return fmt.Errorf("check existing %s digest: %w", kind, err)
+	}
+	if done {
+		log.Printf("%s digest for %s (group %s) already exists, skipping", kind, start.Format("2006-01-02"), groupLabel(groupID))
+		return nil
+	}
+	return fn()
+}
+
+// GenerateWeekly rolls up the group's last full week (Monday to Sunday)
+// before at's day from its daily digests.
+func (g *Generator) GenerateWeekly(ctx context.Context, at time.Time, groupID *string) error {
+	start, end := weekBefore(at)
+	_, err := g.Rollup(ctx, store.PeriodWeekly, start, end, groupID)
+	return err
+}
+
+// GenerateMonthly rolls up the group's calendar month before at's from its
+// daily digests.
+func (g *Generator) GenerateMonthly(ctx context.Context, at time.Time, groupID *string) error {
+	start, end := monthBefore(at)
+	_, err := g.Rollup(ctx, store.PeriodMonthly, start, end, groupID)
+	return err
+}
+
+// Rollup summarizes the group's daily digests that start in [start, end)
+// into one digest of kind and saves it. This reads a few hundred tokens per
+// day instead of the whole period's messages.
+func (g *Generator) Rollup(ctx context.Context, kind string, start, end time.Time, groupID *string) (*store.DigestRecord, error) {
+	days, err := g.store.DigestsInPeriod(ctx, store.PeriodDaily, start, end, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("fetch daily digests: %w", err)
+	}
+	if len(days) == 0 {
+		return nil, fmt.Errorf("no daily digests between %s and %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+
+	last := end.Add(-time.Second)
+	periodLabel := fmt.Sprintf("%s to %s", start.Format("Jan 2, 2006"), last.Format("Jan 2, 2006"))
+	log.Printf("Generating %s digest for %s (group %s) from %d daily digests", kind, periodLabel, groupLabel(groupID), len(days))
+
+	lines := make([]string, len(days))
+	for i, d := range days {
+		lines[i] = rollupLine(d)
+	}
+	recurring := recurringTopics(days, maxRecurringTopics)
+	systemPrompt := fmt.Sprintf(rollupSystemPrompt, kind)
+
+	var stats callStats
+	parsed, err := g.reduce(ctx, lines, systemPrompt, 0.3, &stats, func(group string, round int) string {
+		if round > 1 {
+			return buildReducePrompt(group, periodLabel)
+		}
+		return buildRollupPrompt(group, periodLabel, recurring)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	topics, _ := json.Marshal(parsed.Topics)
+	decisions, _ := json.Marshal(parsed.Decisions)
+	actionItems, _ := json.Marshal(parsed.ActionItems)
+
+	record, err := g.save(ctx, &store.DigestRecord{
+		Title:       parsed.Title,
+		Summary:     parsed.Summary,
+		Topics:      topics,
+		Decisions:   decisions,
+		ActionItems: actionItems,
+		PeriodStart: start,
+		PeriodEnd:   last,
+		PeriodKind:  kind,
+		GroupID:     groupID,
+		LLMProvider: stats.provider,
+		LLMModel:    stats.model,
+		TokenCount:  stats.tokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("%s digest generated: %s (id: %s)", kind, record.Title, record.ID)
+	return record, nil
+}
+
+// weekBefore is the Monday-to-Monday week that ends on or before at's day.
+func weekBefore(at time.Time) (time.Time, time.Time) {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	end := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	return end.AddDate(0, 0, -7), end
+}
+
+// monthBefore is the calendar month before at's.
+func monthBefore(at time.Time) (time.Time, time.Time) {
+	end := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+	return end.AddDate(0, -1, 0), end
+}
+
+// rollupDay is a daily digest as the rollup prompt sees it.
+type rollupDay struct {
+	Date        string          `json:"date"`
+	Title       string          `json:"title"`
+	Summary     string          `json:"summary"`
+	Topics      json.RawMessage `json:"topics,omitempty"`
+	Decisions   json.RawMessage `json:"decisions,omitempty"`
+	ActionItems json.RawMessage `json:"action_items,omitempty"`
+}
+
+func rollupLine(d store.DigestRecord) string {
+	b, _ := json.Marshal(rollupDay{
+		Date:        d.PeriodStart.Format("Mon Jan 2"),
+		Title:       d.Title,
+		Summary:     d.Summary,
+		Topics:      d.Topics,
+		Decisions:   d.Decisions,
+		ActionItems: d.ActionItems,
+	})
+	return string(b)
+}
+
+type topicCount struct {
+	Topic string
+	Days  int
+}
+
+// recurringTopics counts the days each topic came up on, ignoring case, and
+// returns the top limit that came up on more than one day, most frequent
+// first (ties in order of first appearance).
+func recurringTopics(days []store.DigestRecord, limit int) []topicCount {
+	var counts []topicCount
+	index := map[string]int{}
+	for _, d := range days {
+		var topics []string
+		if json.Unmarshal(d.Topics, &topics) != nil {
+			continue
+		}
+		seen := map[string]bool{}
+		for _, t := range topics {
+			t = strings.TrimSpace(t)
+			key := strings.ToLower(t)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			if i, ok := index[key]; ok {
+				counts[i].Days++
+			} else {
+				index[key] = len(counts)
+				counts = append(counts, topicCount{Topic: t, Days: 1})
+			}
+		}
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool { return counts[i].Days > counts[j].Days })
+	n := 0
+	for n < len(counts) && n < limit && counts[n].Days > 1 {
+		n++
+	}
+	return counts[:n]
+}
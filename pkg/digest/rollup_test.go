@@ -0,0 +1,66 @@
+package digest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"signal-sideband/pkg/store"
+)
+
+func TestRollupPeriods(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	for _, c := range []struct {
+		at         time.Time
+		start, end time.Time
+	}{
+		{day(2026, 3, 16), day(2026, 3, 9), day(2026, 3, 16)}, // Monday
+		{day(2026, 3, 18).Add(15 * time.Minute), day(2026, 3, 9), day(2026, 3, 16)},
+		{day(2026, 3, 22), day(2026, 3, 9), day(2026, 3, 16)}, // Sunday
+		{day(2026, 1, 1), day(2025, 12, 22), day(2025, 12, 29)},
+	} {
+		start, end := weekBefore(c.at)
+		if !start.Equal(c.start) || !end.Equal(c.end) {
+			t.Errorf("weekBefore(%s) = %s..%s, want %s..%s", c.at, start, end, c.start, c.end)
+		}
+	}
+
+	start, end := monthBefore(day(2026, 3, 1))
+	if !start.Equal(day(2026, 2, 1)) || !end.Equal(day(2026, 3, 1)) {
+		t.Errorf("monthBefore(Mar 1) = %s..%s", start, end)
+	}
+	start, _ = monthBefore(day(2026, 1, 15))
+	if !start.Equal(day(2025, 12, 1)) {
+		t.Errorf("monthBefore(Jan 15) starts %s", start)
+	}
+}
+
+func TestRecurringTopics(t *testing.T) {
+	digest := func(topics ...string) store.DigestRecord {
+		b, _ := json.Marshal(topics)
+		return store.DigestRecord{Topics: b}
+	}
+	days := []store.DigestRecord{
+		digest("Release", "lunch", "release"),
+		digest("Hiring", "release"),
+		digest("hiring", "RELEASE", "travel"),
+		digest("travel"),
+		{Topics: json.RawMessage(`null`)},
+	}
+
+	got := recurringTopics(days, 10)
+	want := []topicCount{{"Release", 3}, {"Hiring", 2}, {"travel", 2}}
+	if len(got) != len(want) {
+		t.Fatalf("recurringTopics = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("recurringTopics[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if got := recurringTopics(days, 1); len(got) != 1 || got[0].Topic != "Release" {
+		t.Errorf("limit 1: %v", got)
+	}
+}
@@ -0,0 +1,222 @@
+// Package directory keeps the contacts and groups tables in step with
+// signal-cli: names, numbers and avatars.
+package directory
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"signal-sideband/pkg/media"
+	"signal-sideband/pkg/redact"
+	"signal-sideband/pkg/signal"
+	"signal-sideband/pkg/store"
+)
+
+type Worker struct {
+	store      *store.Store
+	api        *signal.APIClient
+	downloader *media.Downloader
+	redactor   *redact.Redactor
+	interval   time.Duration
+}
+
+// NewWorker creates a directory worker. redactor, if non-nil, gets the
+// contact aliases again after every contact sync.
+func NewWorker(s *store.Store, api *signal.APIClient, d *media.Downloader, redactor *redact.Redactor, interval time.Duration) *Worker {
+	return &Worker{store: s, api: api, downloader: d, redactor: redactor, interval: interval}
+}
+
+func (w *Worker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	log.Println("Directory worker started")
+	w.process(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Directory worker stopped")
+			return
+		case <-ticker.C:
+			w.process(ctx)
+		}
+	}
+}
+
+func (w *Worker) process(ctx context.Context) {
+	w.syncContacts(ctx)
+	w.syncGroups(ctx)
+}
+
+// syncContacts upserts every contact signal-cli knows. Aliases are ours and
+// left alone.
+func (w *Worker) syncContacts(ctx context.Context) {
+	details, err := w.api.ListContacts()
+	if err != nil {
+		log.Printf("Directory worker: contact sync failed: %v", err)
+		return
+	}
+	existing, err := w.store.ListContacts(ctx)
+	if err != nil {
+		log.Printf("Directory worker: load contacts failed: %v", err)
+		return
+	}
+	previous := make(map[string]store.ContactRecord, len(existing))
+	for _, c := range existing {
+		previous[c.SourceUUID] = c
+	}
+
+	synced := 0
+	for _, d := range details {
+		if d.UUID == "" {
+			continue
+		}
+		avatar, avatarUpdated := w.contactAvatar(d, previous[d.UUID])
+
+		if err := w.store.UpsertContact(ctx, store.ContactRecord{
+			SourceUUID:      d.UUID,
+			PhoneNumber:     d.Number,
+			ProfileName:     d.DisplayName(),
+			AvatarPath:      avatar,
+			AvatarUpdatedMs: avatarUpdated,
+		}); err != nil {
+			log.Printf("Directory worker: upsert contact %s failed: %v", store.ShortID(d.UUID), err)
+			continue
+		}
+		// Tie number and UUID to one person, even for contacts who never post
+		if d.Number != "" {
+			uuid := d.UUID
+			if _, err := w.store.ResolvePerson(ctx, d.Number, &uuid, false); err != nil {
+				log.Printf("Directory worker: link person %s failed: %v", store.ShortID(d.UUID), err)
+			}
+		}
+		synced++
+	}
+	log.Printf("Directory worker: synced %d contacts", synced)
+
+	if w.redactor != nil {
+		if aliases, err := w.store.ContactAliases(ctx); err != nil {
+			log.Printf("Directory worker: reload redaction aliases failed: %v", err)
+		} else {
+			w.redactor.SetAliases(aliases)
+		}
+	}
+}
+
+// contactAvatar returns the contact's avatar path and the profile version it
+// was downloaded at. It only downloads when the profile has an avatar and
+// changed since the last download, and deletes pictures that were replaced
+// or removed. A failed download keeps the previous picture.
+func (w *Worker) contactAvatar(d signal.ContactDetail, prev store.ContactRecord) (string, int64) {
+	profile := d.Profile
+	switch {
+	case profile.LastUpdatedTimestamp == 0:
+		// signal-cli hasn't fetched the profile; has_avatar means nothing yet
+		return prev.AvatarPath, prev.AvatarUpdatedMs
+	case !profile.HasAvatar:
+		w.removeAvatar(prev.AvatarPath)
+		return "", profile.LastUpdatedTimestamp
+	case prev.AvatarPath != "" && prev.AvatarUpdatedMs == profile.LastUpdatedTimestamp:
+		return prev.AvatarPath, prev.AvatarUpdatedMs
+	}
+
+	path, err := w.downloader.DownloadContactAvatar(d.UUID)
+	if err != nil {
+		log.Printf("Directory worker: avatar for %s failed: %v", store.ShortID(d.UUID), err)
+		return prev.AvatarPath, prev.AvatarUpdatedMs
+	}
+	if path != prev.AvatarPath {
+		w.removeAvatar(prev.AvatarPath)
+	}
+	return path, profile.LastUpdatedTimestamp
+}
+
+func (w *Worker) removeAvatar(path string) {
+	if err := w.downloader.RemoveAvatar(path); err != nil {
+		log.Printf("Directory worker: remove old avatar failed: %v", err)
+	}
+}
+
+// syncGroups upserts every group the account is in, keyed by the internal
+// group ID that messages carry, with its avatar and current members.
+func (w *Worker) syncGroups(ctx context.Context) {
+	groups, err := w.api.ListGroups()
+	if err != nil {
+		log.Printf("Directory worker: group sync failed: %v", err)
+		return
+	}
+	existing, err := w.store.ListGroups(ctx)
+	if err != nil {
+		log.Printf("Directory worker: load groups failed: %v", err)
+		return
+	}
+	avatars := make(map[string]string, len(existing))
+	for _, g := range existing {
+		avatars[g.GroupID] = g.AvatarPath
+	}
+
+	for _, g := range groups {
+		// Messages carry the internal ID; a row keyed by the REST "group.…"
+		// ID would never match them
+		groupID := g.InternalID
+		if groupID == "" {
+			log.Printf("Directory worker: group %s has no internal ID, skipping", g.Name)
+			continue
+		}
+
+		// Groups carry no avatar version, so the picture is fetched every
+		// sync; the old file goes when the new one lands elsewhere or the
+		// avatar was removed
+		avatar := avatars[groupID]
+		if path, err := w.downloader.DownloadGroupAvatar(g.ID); err != nil {
+			log.Printf("Directory worker: avatar for group %s failed: %v", g.Name, err)
+		} else {
+			if path != avatar {
+				w.removeAvatar(avatar)
+			}
+			avatar = path
+		}
+
+		if err := w.store.UpsertGroup(ctx, store.GroupRecord{
+			GroupID:     groupID,
+			Name:        g.Name,
+			Description: g.Description,
+			AvatarPath:  avatar,
+			MemberCount: len(g.Members),
+		}); err != nil {
+			log.Printf("Directory worker: upsert group %s failed: %v", g.Name, err)
+			continue
+		}
+
+		// An empty list means signal-cli couldn't load the group, not that
+		// everyone left
+		if len(g.Members) == 0 {
+			continue
+		}
+		if err := w.store.SyncGroupMembers(ctx, groupID, w.members(ctx, g)); err != nil {
+			log.Printf("Directory worker: members of group %s failed: %v", g.Name, err)
+		}
+	}
+	log.Printf("Directory worker: synced %d groups", len(groups))
+}
+
+// members lists a group's members with their admin flag and person.
+func (w *Worker) members(ctx context.Context, g signal.GroupDetail) []store.GroupMember {
+	admins := make(map[string]bool, len(g.Admins))
+	for _, a := range g.Admins {
+		admins[a] = true
+	}
+	members := make([]store.GroupMember, 0, len(g.Members))
+	for _, id := range g.Members {
+		m := store.GroupMember{MemberID: id, IsAdmin: admins[id]}
+		if personID, err := w.store.ResolvePerson(ctx, id, nil, false); err != nil {
+			log.Printf("Directory worker: link person %s failed: %v", store.ShortID(id), err)
+		} else if personID != "" {
+			m.PersonID = &personID
+		}
+		members = append(members, m)
+	}
+	return members
+}
@@ -0,0 +1,98 @@
+package extract
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopwords holds common function words for the Postgres text-search
+// configurations we can detect. Chat messages are short, so a handful of
+// high-frequency words is a better signal than character n-grams.
+var stopwords = map[string][]string{
+	"english": {
+		"the", "and", "is", "are", "was", "were", "you", "that", "this", "with",
+		"for", "have", "not", "but", "what", "they", "will", "just", "it's", "i'm",
+		"of", "to", "in", "on", "my", "your", "be", "do", "does", "did",
+		"about", "there", "their", "would", "could", "should", "can", "if", "so", "we",
+	},
+	"spanish": {
+		"el", "la", "los", "las", "que", "de", "del", "y", "en", "un",
+		"una", "es", "por", "con", "para", "pero", "como", "más", "muy", "está",
+		"estoy", "yo", "tú", "mi", "qué", "sí", "también", "hay", "eso", "esto",
+		"porque", "cuando", "donde", "nos", "todo", "bien", "ya", "se", "lo", "al",
+	},
+	"french": {
+		"le", "la", "les", "des", "et", "est", "une", "un", "que", "qui",
+		"dans", "pour", "pas", "avec", "sur", "mais", "je", "tu", "il", "elle",
+		"nous", "vous", "ils", "c'est", "ce", "cette", "du", "au", "aux", "très",
+	},
+	"german": {
+		"der", "die", "das", "und", "ist", "nicht", "ich", "du", "er", "sie",
+		"wir", "ihr", "mit", "auf", "für", "ein", "eine", "auch", "aber", "noch",
+		"wie", "was", "dass", "den", "dem", "sich", "schon", "nur", "mal", "bin",
+	},
+	"portuguese": {
+		"o", "os", "as", "que", "de", "do", "da", "dos", "das", "e",
+		"em", "um", "uma", "é", "não", "para", "com", "mas", "como", "mais",
+		"eu", "você", "ele", "ela", "nós", "isso", "isto", "muito", "também", "está",
+	},
+	"italian": {
+		"il", "lo", "la", "gli", "le", "che", "di", "del", "della", "e",
+		"è", "un", "una", "non", "per", "con", "ma", "come", "più", "sono",
+		"io", "tu", "lui", "lei", "noi", "voi", "questo", "quello", "anche", "molto",
+	},
+	"dutch": {
+		"de", "het", "een", "en", "is", "niet", "ik", "je", "jij", "hij",
+		"zij", "wij", "met", "op", "voor", "van", "maar", "ook", "nog", "wat",
+		"dat", "die", "er", "zijn", "heb", "heeft", "naar", "om", "al", "wel",
+	},
+}
+
+// DetectLanguage guesses which of the candidate text-search configurations best
+// fits text by counting stopword hits. It returns candidates[0] when the text is
+// too short or no candidate clearly wins.
+func DetectLanguage(text string, candidates []string) string {
+	if len(candidates) == 0 {
+		return ""
+	}
+	fallback := candidates[0]
+	if len(candidates) == 1 {
+		return fallback
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) < 3 {
+		return fallback
+	}
+
+	best, bestScore, runnerUp := fallback, 0, 0
+	for _, lang := range candidates {
+		list, ok := stopwords[lang]
+		if !ok {
+			continue
+		}
+		set := make(map[string]bool, len(list))
+		for _, w := range list {
+			set[w] = true
+		}
+		score := 0
+		for _, w := range words {
+			if set[w] {
+				score++
+			}
+		}
+		switch {
+		case score > bestScore:
+			best, runnerUp, bestScore = lang, bestScore, score
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+
+	if bestScore < 2 || bestScore == runnerUp {
+		return fallback
+	}
+	return best
+}
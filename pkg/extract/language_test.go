@@ -0,0 +1,27 @@
+package extract
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	candidates := []string{"english", "spanish"}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Are you coming to the cabin this weekend or not?", "english"},
+		{"¿Qué hora es la cena? Yo creo que está muy tarde para eso", "spanish"},
+		{"ok", "english"},
+		{"jajaja 🙌", "english"},
+	}
+
+	for _, tt := range tests {
+		if got := DetectLanguage(tt.text, candidates); got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if got := DetectLanguage("¿Qué hora es la cena?", []string{"english"}); got != "english" {
+		t.Errorf("single candidate should always win, got %q", got)
+	}
+}
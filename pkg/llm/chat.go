@@ -0,0 +1,221 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ChatProvider talks to any OpenAI-compatible /chat/completions API. The
+// OpenAI, xAI, Perplexity and generic compatible providers are all instances.
+type ChatProvider struct {
+	name   string
+	client *openai.Client
+	model  string
+	// jsonMode is set when the API accepts response_format json_object
+	jsonMode bool
+	// tools is set when the API supports function calling
+	tools       bool
+	chunkTokens int
+}
+
+// chatFeatures lists the optional API features a chat endpoint supports.
+type chatFeatures struct {
+	jsonMode bool
+	tools    bool
+}
+
+func newChatProvider(name string, cfg Config, features chatFeatures) *ChatProvider {
+	oc := openai.DefaultConfig(cfg.APIKey)
+	if cfg.BaseURL != "" {
+		oc.BaseURL = strings.TrimRight(cfg.BaseURL, "/")
+	}
+	oc.HTTPClient = cfg.httpClient()
+	return &ChatProvider{
+		name:        name,
+		client:      openai.NewClientWithConfig(oc),
+		model:       cfg.Model,
+		jsonMode:    features.jsonMode,
+		tools:       features.tools,
+		chunkTokens: cfg.ChunkTokens,
+	}
+}
+
+func (c *ChatProvider) Name() string { return c.name }
+
+// Model returns the configured model name.
+func (c *ChatProvider) Model() string { return c.model }
+
+// ChunkTokens returns the configured transcript budget per request.
+func (c *ChatProvider) ChunkTokens() int { return c.chunkTokens }
+
+func (c *ChatProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if onDelta := streamTo(ctx); onDelta != nil && len(req.Tools) == 0 {
+		return c.CompleteStream(ctx, req, onDelta)
+	}
+	chatReq, err := c.request(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, retryAfter := captureRetryAfter(ctx)
+	resp, err := c.client.CreateChatCompletion(ctx, chatReq)
+	if err != nil {
+		return nil, statusError(c.name, err, retryAfter.get())
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty response from %s", c.name)
+	}
+
+	out := &CompletionResponse{
+		Content:      resp.Choices[0].Message.Content,
+		Model:        resp.Model,
+		Provider:     c.name,
+		InputTokens:  resp.Usage.PromptTokens,
+		OutputTokens: resp.Usage.CompletionTokens,
+	}
+	for _, tc := range resp.Choices[0].Message.ToolCalls {
+		out.ToolCalls = append(out.ToolCalls, ToolCall{
+			ID:        tc.ID,
+			Name:      tc.Function.Name,
+			Arguments: json.RawMessage(tc.Function.Arguments),
+		})
+	}
+	return out, nil
+}
+
+// CompleteStream streams the reply text to onDelta. Tool calls are not
+// streamed; use Complete for requests with Tools.
+func (c *ChatProvider) CompleteStream(ctx context.Context, req CompletionRequest, onDelta StreamFunc) (*CompletionResponse, error) {
+	chatReq, err := c.request(req)
+	if err != nil {
+		return nil, err
+	}
+	chatReq.Stream = true
+	chatReq.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+
+	ctx, retryAfter := captureRetryAfter(ctx)
+	stream, err := c.client.CreateChatCompletionStream(ctx, chatReq)
+	if err != nil {
+		return nil, statusError(c.name, err, retryAfter.get())
+	}
+	defer stream.Close()
+
+	out := &CompletionResponse{Model: c.model, Provider: c.name}
+	var content strings.Builder
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, statusError(c.name, err, retryAfter.get())
+		}
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			out.InputTokens = chunk.Usage.PromptTokens
+			out.OutputTokens = chunk.Usage.CompletionTokens
+		}
+		for _, choice := range chunk.Choices {
+			if d := choice.Delta.Content; d != "" {
+				content.WriteString(d)
+				onDelta(d)
+			}
+		}
+	}
+	if content.Len() == 0 {
+		return nil, fmt.Errorf("empty response from %s", c.name)
+	}
+	out.Content = content.String()
+	return out, nil
+}
+
+// request builds the chat request shared by Complete and CompleteStream.
+func (c *ChatProvider) request(req CompletionRequest) (openai.ChatCompletionRequest, error) {
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 4096
+	}
+
+	if len(req.Tools) > 0 && !c.tools {
+		return openai.ChatCompletionRequest{}, fmt.Errorf("%s: %w", c.name, ErrToolsUnsupported)
+	}
+
+	system, conv := req.conversation()
+	messages := []openai.ChatCompletionMessage{}
+	if system != "" {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: system,
+		})
+	}
+	for _, m := range conv {
+		messages = append(messages, chatMessage(m))
+	}
+
+	chatReq := openai.ChatCompletionRequest{
+		Model:     c.model,
+		Messages:  messages,
+		MaxTokens: maxTokens,
+	}
+	if t := req.Temperature; t != nil {
+		// go-openai omits a zero temperature; the smallest non-zero value
+		// is its documented way to ask for 0
+		chatReq.Temperature = max(float32(*t), math.SmallestNonzeroFloat32)
+	}
+	for _, t := range req.Tools {
+		chatReq.Tools = append(chatReq.Tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			},
+		})
+	}
+	if req.JSON && c.jsonMode {
+		chatReq.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+	return chatReq, nil
+}
+
+// chatMessage converts one turn, switching to multi-part content when
+// images are attached.
+func chatMessage(m Message) openai.ChatCompletionMessage {
+	msg := openai.ChatCompletionMessage{Role: m.Role, Content: m.Content, ToolCallID: m.ToolCallID}
+	for _, tc := range m.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, openai.ToolCall{
+			ID:   tc.ID,
+			Type: openai.ToolTypeFunction,
+			Function: openai.FunctionCall{
+				Name:      tc.Name,
+				Arguments: string(tc.Arguments),
+			},
+		})
+	}
+	if len(m.Images) == 0 {
+		return msg
+	}
+	var parts []openai.ChatMessagePart
+	for _, img := range m.Images {
+		parts = append(parts, openai.ChatMessagePart{
+			Type: openai.ChatMessagePartTypeImageURL,
+			ImageURL: &openai.ChatMessageImageURL{
+				URL:    img.dataURI(),
+				Detail: openai.ImageURLDetailLow,
+			},
+		})
+	}
+	msg.Content = ""
+	msg.MultiContent = append(parts, openai.ChatMessagePart{Type: openai.ChatMessagePartTypeText, Text: m.Content})
+	return msg
+}
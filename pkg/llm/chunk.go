@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"context"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultChunkTokens is the transcript budget used when a provider has no
+// configured chunk size.
+const DefaultChunkTokens = 24000
+
+// defaultChunkTokens is each provider's input budget per request, leaving
+// room in the context window for the system prompt and the reply.
+var defaultChunkTokens = map[string]int{
+	"claude":     120000,
+	"openai":     80000,
+	"xai":        80000,
+	"perplexity": 80000,
+	"compatible": 6000,
+}
+
+// EstimateTokens approximates the token count of s. It assumes ~4 bytes per
+// token, which over-counts slightly for English and is safe for chunking.
+func EstimateTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// ChunkTokens returns the per-request transcript budget for p. For a
+// fallback chain it is the smallest budget in the chain, since any member
+// may end up answering.
+func ChunkTokens(p Provider) int {
+	for {
+		switch v := p.(type) {
+		case *fallbackProvider:
+			n := 0
+			for _, member := range v.providers {
+				if c := ChunkTokens(member); n == 0 || c < n {
+					n = c
+				}
+			}
+			return n
+		case interface{ ChunkTokens() int }:
+			if n := v.ChunkTokens(); n > 0 {
+				return n
+			}
+			return DefaultChunkTokens
+		case Wrapper:
+			p = v.Unwrap()
+		default:
+			return DefaultChunkTokens
+		}
+	}
+}
+
+// Chunk joins lines into newline-separated chunks of at most maxTokens
+// (estimated). Lines are never reordered; a single line over the budget is
+// cut into budget-sized pieces, on character boundaries.
+func Chunk(lines []string, maxTokens int) []string {
+	if maxTokens <= 0 {
+		maxTokens = DefaultChunkTokens
+	}
+	maxBytes := maxTokens * 4
+
+	var chunks []string
+	var sb strings.Builder
+	flush := func() {
+		if sb.Len() > 0 {
+			chunks = append(chunks, sb.String())
+			sb.Reset()
+		}
+	}
+	for _, line := range lines {
+		for len(line) > maxBytes {
+			flush()
+			cut := maxBytes
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = maxBytes // not UTF-8; any cut is as good as another
+			}
+			chunks = append(chunks, line[:cut])
+			line = line[cut:]
+		}
+		if sb.Len()+len(line)+1 > maxBytes {
+			flush()
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	flush()
+	return chunks
+}
+
+// ProgressFunc receives progress for a long-running, multi-call operation.
+// stage names the current step ("map", "reduce"...).
+type ProgressFunc func(stage string, done, total int)
+
+type progressKey struct{}
+
+// WithProgress attaches fn to ctx; ReportProgress calls it.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress logs progress for op and forwards it to the ProgressFunc on
+// ctx, if any.
+func ReportProgress(ctx context.Context, op, stage string, done, total int) {
+	log.Printf("%s: %s %d/%d", op, stage, done, total)
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok && fn != nil {
+		fn(stage, done, total)
+	}
+}
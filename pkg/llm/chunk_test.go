@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestChunk(t *testing.T) {
+	lines := []string{strings.Repeat("a", 30), strings.Repeat("b", 30), strings.Repeat("c", 30)}
+	chunks := Chunk(lines, 16) // 64 bytes: two lines per chunk
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2: %q", len(chunks), chunks)
+	}
+	if strings.Join(chunks, "") != strings.Join(lines, "\n")+"\n" {
+		t.Errorf("chunks lost or reordered lines: %q", chunks)
+	}
+
+	long := Chunk([]string{strings.Repeat("x", 100)}, 10)
+	if len(long) != 3 || len(long[0]) != 40 {
+		t.Errorf("oversized line split into %d pieces", len(long))
+	}
+
+	// 40-byte pieces of 3-byte runes must back up to a rune boundary
+	wide := Chunk([]string{strings.Repeat("€", 20)}, 10)
+	for _, c := range wide {
+		if !utf8.ValidString(c) {
+			t.Fatalf("cut mid-rune: %q", c)
+		}
+	}
+	if strings.Join(wide, "") != strings.Repeat("€", 20)+"\n" {
+		t.Errorf("multibyte line lost text: %q", wide)
+	}
+}
+
+func TestChunkTokensFallbackChain(t *testing.T) {
+	big := &ChatProvider{name: "a", chunkTokens: 80000}
+	small := &ChatProvider{name: "b", chunkTokens: 6000}
+	p := Fallback(Wrap(big, WithRetry(2, 0)), small)
+	if got := ChunkTokens(p); got != 6000 {
+		t.Errorf("ChunkTokens = %d, want smallest in chain 6000", got)
+	}
+	if got := ChunkTokens(&fakeProvider{}); got != DefaultChunkTokens {
+		t.Errorf("ChunkTokens = %d, want default", got)
+	}
+}
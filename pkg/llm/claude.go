@@ -1,75 +1,272 @@
 package llm
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
+	"strings"
 )
 
+const defaultClaudeModel = "claude-sonnet-4-5-20250929"
+
 type ClaudeProvider struct {
-	apiKey string
-	model  string
-	client *http.Client
+	apiKey      string
+	baseURL     string
+	model       string
+	client      *http.Client
+	chunkTokens int
 }
 
-func NewClaudeProvider(apiKey string) *ClaudeProvider {
+func NewClaudeProvider(cfg Config) *ClaudeProvider {
 	return &ClaudeProvider{
-		apiKey: apiKey,
-		model:  "claude-sonnet-4-5-20250929",
-		client: &http.Client{Timeout: 120 * time.Second},
+		apiKey:      cfg.APIKey,
+		baseURL:     strings.TrimRight(orDefault(cfg.BaseURL, "https://api.anthropic.com"), "/"),
+		model:       orDefault(cfg.Model, defaultClaudeModel),
+		client:      cfg.httpClient(),
+		chunkTokens: cfg.ChunkTokens,
 	}
 }
 
 func (c *ClaudeProvider) Name() string { return "claude" }
 
+// Model returns the configured model name.
+func (c *ClaudeProvider) Model() string { return c.model }
+
+// ChunkTokens returns the configured transcript budget per request.
+func (c *ClaudeProvider) ChunkTokens() int { return c.chunkTokens }
+
 type claudeRequest struct {
-	Model     string           `json:"model"`
-	MaxTokens int              `json:"max_tokens"`
-	System    string           `json:"system,omitempty"`
-	Messages  []claudeMessage  `json:"messages"`
+	Model       string          `json:"model"`
+	MaxTokens   int             `json:"max_tokens"`
+	System      string          `json:"system,omitempty"`
+	Messages    []claudeMessage `json:"messages"`
+	Tools       []claudeTool    `json:"tools,omitempty"`
+	Stream      bool            `json:"stream,omitempty"`
+	Temperature *float64        `json:"temperature,omitempty"`
+}
+
+type claudeTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
 }
 
 type claudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role string `json:"role"`
+	// Content is a string, or []claudeBlock for images and tool use
+	Content any `json:"content"`
+}
+
+type claudeBlock struct {
+	Type   string             `json:"type"`
+	Text   string             `json:"text,omitempty"`
+	Source *claudeImageSource `json:"source,omitempty"`
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+type claudeImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
 }
 
+// claudeJSONPrefill starts the assistant turn so the reply continues a JSON
+// object; Claude has no response_format switch.
+const claudeJSONPrefill = "{"
+
 type claudeResponse struct {
-	Content []struct {
-		Text string `json:"text"`
-	} `json:"content"`
-	Model string `json:"model"`
-	Usage struct {
+	Content []claudeBlock `json:"content"`
+	Model   string        `json:"model"`
+	Usage   struct {
 		InputTokens  int `json:"input_tokens"`
 		OutputTokens int `json:"output_tokens"`
 	} `json:"usage"`
 }
 
 func (c *ClaudeProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if onDelta := streamTo(ctx); onDelta != nil && len(req.Tools) == 0 {
+		return c.CompleteStream(ctx, req, onDelta)
+	}
+	body, prefill := c.request(req)
+
+	resp, err := c.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var claudeResp claudeResponse
+	if err := json.Unmarshal(respBody, &claudeResp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	if len(claudeResp.Content) == 0 {
+		return nil, fmt.Errorf("empty response from claude")
+	}
+
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range claudeResp.Content {
+		switch block.Type {
+		case "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{ID: block.ID, Name: block.Name, Arguments: block.Input})
+		}
+	}
+	content := text.String()
+	if prefill {
+		content = claudeJSONPrefill + content
+	}
+
+	return &CompletionResponse{
+		Content:      content,
+		Model:        claudeResp.Model,
+		Provider:     "claude",
+		InputTokens:  claudeResp.Usage.InputTokens,
+		OutputTokens: claudeResp.Usage.OutputTokens,
+		ToolCalls:    toolCalls,
+	}, nil
+}
+
+// claudeStreamEvent covers the SSE events CompleteStream reads.
+type claudeStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Model string `json:"model"`
+		Usage struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// CompleteStream streams the reply text to onDelta. Tool calls are not
+// streamed; use Complete for requests with Tools.
+func (c *ClaudeProvider) CompleteStream(ctx context.Context, req CompletionRequest, onDelta StreamFunc) (*CompletionResponse, error) {
+	body, prefill := c.request(req)
+	body.Stream = true
+
+	resp, err := c.post(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	out := &CompletionResponse{Model: c.model, Provider: "claude"}
+	var content strings.Builder
+	if prefill {
+		content.WriteString(claudeJSONPrefill)
+		onDelta(claudeJSONPrefill)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var ev claudeStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			continue
+		}
+		switch ev.Type {
+		case "message_start":
+			out.Model = ev.Message.Model
+			out.InputTokens = ev.Message.Usage.InputTokens
+		case "content_block_delta":
+			if ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
+				content.WriteString(ev.Delta.Text)
+				onDelta(ev.Delta.Text)
+			}
+		case "message_delta":
+			out.OutputTokens = ev.Usage.OutputTokens
+		case "error":
+			return nil, fmt.Errorf("claude stream: %s", ev.Error.Message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read stream: %w", err)
+	}
+
+	out.Content = content.String()
+	if prefill && out.Content == claudeJSONPrefill || out.Content == "" {
+		return nil, fmt.Errorf("empty response from claude")
+	}
+	return out, nil
+}
+
+// request builds the Messages API body. prefill reports whether an assistant
+// "{" was added, which the caller must prepend to the reply.
+func (c *ClaudeProvider) request(req CompletionRequest) (body claudeRequest, prefill bool) {
 	maxTokens := req.MaxTokens
 	if maxTokens <= 0 {
 		maxTokens = 4096
 	}
 
-	body := claudeRequest{
+	system, conv := req.conversation()
+	body = claudeRequest{
 		Model:     c.model,
 		MaxTokens: maxTokens,
-		System:    req.SystemPrompt,
-		Messages: []claudeMessage{
-			{Role: "user", Content: req.UserPrompt},
-		},
+		System:    system,
+		Messages:  claudeMessages(conv),
+	}
+	// Claude takes 0-1 where OpenAI takes 0-2
+	if req.Temperature != nil {
+		t := min(*req.Temperature, 1)
+		body.Temperature = &t
+	}
+	for _, t := range req.Tools {
+		schema := t.Parameters
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object"}`)
+		}
+		body.Tools = append(body.Tools, claudeTool{Name: t.Name, Description: t.Description, InputSchema: schema})
+	}
+	// Prefill only when the model answers a user turn without tools
+	prefill = req.JSON && len(req.Tools) == 0 && len(conv) > 0 && conv[len(conv)-1].Role == RoleUser
+	if prefill {
+		body.Messages = append(body.Messages, claudeMessage{Role: "assistant", Content: claudeJSONPrefill})
 	}
+	return body, prefill
+}
 
+// post sends body to /v1/messages and converts non-200 replies to
+// *StatusError. The caller closes the response body.
+func (c *ClaudeProvider) post(ctx context.Context, body claudeRequest) (*http.Response, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewReader(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, err
 	}
@@ -81,30 +278,59 @@ func (c *ClaudeProvider) Complete(ctx context.Context, req CompletionRequest) (*
 	if err != nil {
 		return nil, fmt.Errorf("claude api call: %w", err)
 	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
-
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("claude api error (status %d): %s", resp.StatusCode, respBody)
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{
+			Provider:   "claude",
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Err:        errors.New(string(respBody)),
+		}
 	}
+	return resp, nil
+}
 
-	var claudeResp claudeResponse
-	if err := json.Unmarshal(respBody, &claudeResp); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
-	}
+// claudeMessages converts a conversation to Messages API turns. Tool results
+// travel as tool_result blocks in a user turn; consecutive results share one.
+func claudeMessages(conv []Message) []claudeMessage {
+	var out []claudeMessage
+	for _, m := range conv {
+		switch {
+		case m.Role == RoleTool:
+			block := claudeBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}
+			if n := len(out); n > 0 && out[n-1].Role == "user" {
+				if blocks, ok := out[n-1].Content.([]claudeBlock); ok && len(blocks) > 0 && blocks[0].Type == "tool_result" {
+					out[n-1].Content = append(blocks, block)
+					continue
+				}
+			}
+			out = append(out, claudeMessage{Role: "user", Content: []claudeBlock{block}})
 
-	if len(claudeResp.Content) == 0 {
-		return nil, fmt.Errorf("empty response from claude")
-	}
+		case len(m.Images) > 0 || len(m.ToolCalls) > 0:
+			var blocks []claudeBlock
+			for _, img := range m.Images {
+				blocks = append(blocks, claudeBlock{Type: "image", Source: &claudeImageSource{
+					Type:      "base64",
+					MediaType: img.MimeType,
+					Data:      base64.StdEncoding.EncodeToString(img.Data),
+				}})
+			}
+			if m.Content != "" {
+				blocks = append(blocks, claudeBlock{Type: "text", Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				input := tc.Arguments
+				if len(input) == 0 {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, claudeBlock{Type: "tool_use", ID: tc.ID, Name: tc.Name, Input: input})
+			}
+			out = append(out, claudeMessage{Role: m.Role, Content: blocks})
 
-	return &CompletionResponse{
-		Content:      claudeResp.Content[0].Text,
-		Model:        claudeResp.Model,
-		InputTokens:  claudeResp.Usage.InputTokens,
-		OutputTokens: claudeResp.Usage.OutputTokens,
-	}, nil
+		default:
+			out = append(out, claudeMessage{Role: m.Role, Content: m.Content})
+		}
+	}
+	return out
 }
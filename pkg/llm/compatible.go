@@ -0,0 +1,11 @@
+package llm
+
+// NewCompatibleProvider targets a self-hosted OpenAI-compatible server
+// (Ollama's /v1, llama.cpp server, vLLM, LM Studio...). BaseURL and Model are
+// required; most local servers ignore the API key.
+func NewCompatibleProvider(cfg Config) *ChatProvider {
+	if cfg.APIKey == "" {
+		cfg.APIKey = "none"
+	}
+	return newChatProvider("compatible", cfg, chatFeatures{jsonMode: true, tools: true})
+}
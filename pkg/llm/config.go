@@ -0,0 +1,162 @@
+package llm
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Use cases let each task pick its own provider and model.
+const (
+	UseDigest     = "digest"
+	UseInsights   = "insights"
+	UseExtraction = "extraction"
+	UseEnrichment = "enrichment"
+	UseVision     = "vision"
+	UseAsk        = "ask"
+)
+
+// useCaseModels are per-provider model defaults for use cases the provider's
+// general default can't serve, e.g. image input.
+var useCaseModels = map[string]map[string]string{
+	UseVision: {
+		"xai":    "grok-2-vision-1212",
+		"openai": "gpt-4o-mini",
+	},
+}
+
+const defaultTimeout = 120 * time.Second
+
+// Config holds the connection settings for one provider instance.
+type Config struct {
+	APIKey  string
+	BaseURL string
+	Model   string
+	Headers map[string]string
+	Timeout time.Duration
+	// RPM caps requests per minute to this provider; 0 means unlimited.
+	RPM int
+	// ChunkTokens is the transcript budget per request; longer inputs are
+	// split and summarized map-reduce style. See ChunkTokens.
+	ChunkTokens int
+}
+
+// envPrefix maps a provider name to the prefix of its environment variables.
+var envPrefix = map[string]string{
+	"claude":     "ANTHROPIC",
+	"openai":     "OPENAI",
+	"xai":        "XAI",
+	"perplexity": "PERPLEXITY",
+	"compatible": "LLM",
+	"replay":     "LLM_REPLAY",
+}
+
+// canonicalName folds provider aliases onto the names used in envPrefix.
+func canonicalName(name string) string {
+	switch name = strings.ToLower(strings.TrimSpace(name)); name {
+	case "anthropic":
+		return "claude"
+	case "grok":
+		return "xai"
+	case "ollama", "vllm", "llamacpp", "llama.cpp":
+		return "compatible"
+	case "cassette", "fake":
+		return "replay"
+	}
+	return name
+}
+
+// ProviderName returns the provider configured for useCase:
+// LLM_PROVIDER_<USECASE>, falling back to LLM_PROVIDER.
+func ProviderName(useCase string) string {
+	if useCase != "" {
+		if v := os.Getenv("LLM_PROVIDER_" + strings.ToUpper(useCase)); v != "" {
+			return v
+		}
+	}
+	return os.Getenv("LLM_PROVIDER")
+}
+
+// ConfigFromEnv reads provider settings, preferring use-case specific values:
+//
+//	<PREFIX>_API_KEY
+//	<PREFIX>_MODEL_<USECASE>, then <PREFIX>_MODEL
+//	<PREFIX>_BASE_URL
+//	<PREFIX>_HEADERS   comma-separated Name=Value pairs
+//	<PREFIX>_TIMEOUT   Go duration, e.g. 90s
+//	<PREFIX>_RPM       requests per minute
+//	<PREFIX>_CHUNK_TOKENS  transcript tokens per request
+//
+// where PREFIX is ANTHROPIC, OPENAI, XAI, PERPLEXITY, LLM (compatible) or
+// LLM_REPLAY.
+func ConfigFromEnv(useCase, name string) Config {
+	prefix := envPrefix[canonicalName(name)]
+	cfg := Config{
+		APIKey:  os.Getenv(prefix + "_API_KEY"),
+		BaseURL: os.Getenv(prefix + "_BASE_URL"),
+		Model:   os.Getenv(prefix + "_MODEL"),
+		Headers: parseHeaders(os.Getenv(prefix + "_HEADERS")),
+	}
+	if useCase != "" {
+		if m := os.Getenv(prefix + "_MODEL_" + strings.ToUpper(useCase)); m != "" {
+			cfg.Model = m
+		} else if m := useCaseModels[useCase][canonicalName(name)]; m != "" && cfg.Model == "" {
+			cfg.Model = m
+		}
+	}
+	if d, err := time.ParseDuration(os.Getenv(prefix + "_TIMEOUT")); err == nil && d > 0 {
+		cfg.Timeout = d
+	}
+	cfg.RPM, _ = strconv.Atoi(os.Getenv(prefix + "_RPM"))
+	cfg.ChunkTokens, _ = strconv.Atoi(os.Getenv(prefix + "_CHUNK_TOKENS"))
+	if cfg.ChunkTokens <= 0 {
+		cfg.ChunkTokens = defaultChunkTokens[canonicalName(name)]
+	}
+	return cfg
+}
+
+func parseHeaders(s string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if k = strings.TrimSpace(k); ok && k != "" {
+			headers[k] = strings.TrimSpace(v)
+		}
+	}
+	return headers
+}
+
+// httpClient builds a client honouring cfg's timeout and extra headers.
+func (cfg Config) httpClient() *http.Client {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	var transport http.RoundTripper = &retryAfterTransport{base: http.DefaultTransport}
+	if len(cfg.Headers) > 0 {
+		transport = &headerTransport{headers: cfg.Headers, base: transport}
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+type headerTransport struct {
+	headers map[string]string
+	base    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func orDefault(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,75 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestConversationShim(t *testing.T) {
+	system, msgs := CompletionRequest{SystemPrompt: "sys", UserPrompt: "hi"}.conversation()
+	if system != "sys" || len(msgs) != 1 || msgs[0].Role != RoleUser || msgs[0].Content != "hi" {
+		t.Errorf("single-turn request = %q, %+v", system, msgs)
+	}
+
+	system, msgs = CompletionRequest{SystemPrompt: "sys", Messages: []Message{
+		{Role: RoleSystem, Content: "more"},
+		{Role: RoleUser, Content: "q"},
+	}}.conversation()
+	if system != "sys\n\nmore" || len(msgs) != 1 {
+		t.Errorf("system messages not folded: %q, %+v", system, msgs)
+	}
+}
+
+func TestClaudeMessagesToolResults(t *testing.T) {
+	msgs := claudeMessages([]Message{
+		{Role: RoleUser, Content: "weather in Paris and Rome?"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{
+			{ID: "a", Name: "weather", Arguments: json.RawMessage(`{"city":"Paris"}`)},
+			{ID: "b", Name: "weather", Arguments: json.RawMessage(`{"city":"Rome"}`)},
+		}},
+		{Role: RoleTool, ToolCallID: "a", Content: "sunny"},
+		{Role: RoleTool, ToolCallID: "b", Content: "rain"},
+	})
+	if len(msgs) != 3 {
+		t.Fatalf("got %d turns, want 3 (tool results share one user turn)", len(msgs))
+	}
+	results, ok := msgs[2].Content.([]claudeBlock)
+	if msgs[2].Role != "user" || !ok || len(results) != 2 || results[1].ToolUseID != "b" {
+		t.Errorf("tool results turn = %+v", msgs[2])
+	}
+	uses, _ := msgs[1].Content.([]claudeBlock)
+	if len(uses) != 2 || uses[0].Type != "tool_use" || uses[0].Name != "weather" {
+		t.Errorf("tool_use turn = %+v", msgs[1])
+	}
+}
+
+func TestClaudeTemperature(t *testing.T) {
+	c := NewClaudeProvider(Config{})
+	for _, tc := range []struct {
+		in   *float64
+		want *float64
+	}{
+		{nil, nil},
+		{ptr(0), ptr(0)},
+		{ptr(0.3), ptr(0.3)},
+		{ptr(1.5), ptr(1.0)},
+	} {
+		body, _ := c.request(CompletionRequest{UserPrompt: "q", Temperature: tc.in})
+		got := body.Temperature
+		if (got == nil) != (tc.want == nil) || got != nil && *got != *tc.want {
+			t.Errorf("temperature %v sent as %v", tc.in, got)
+		}
+	}
+}
+
+func ptr(f float64) *float64 { return &f }
+
+func TestPerplexityRejectsTools(t *testing.T) {
+	p := NewPerplexityProvider(Config{APIKey: "x"})
+	_, err := p.Complete(context.Background(), CompletionRequest{UserPrompt: "q", Tools: []Tool{{Name: "search"}}})
+	if !errors.Is(err, ErrToolsUnsupported) {
+		t.Errorf("err = %v, want ErrToolsUnsupported", err)
+	}
+}
@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// StatusError is an HTTP error response from a provider API.
+type StatusError struct {
+	Provider   string
+	StatusCode int
+	// RetryAfter is the server's Retry-After hint, zero if none was sent.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s api error (status %d): %v", e.Provider, e.StatusCode, e.Err)
+}
+
+func (e *StatusError) Unwrap() error { return e.Err }
+
+// Retryable reports whether an error is worth retrying: rate limits, server
+// errors and transport failures (timeouts, refused or dropped connections).
+// Cancellation by the caller is never retried. The circuit breaker counts
+// the same errors as provider failures.
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
+	}
+	var ne net.Error
+	if errors.As(err, &ne) {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// retryAfterKey carries a per-request slot the transport fills with the
+// Retry-After header, since go-openai's error types don't expose headers.
+type retryAfterKey struct{}
+
+type retryAfterSlot struct {
+	mu sync.Mutex
+	d  time.Duration
+}
+
+func captureRetryAfter(ctx context.Context) (context.Context, *retryAfterSlot) {
+	slot := &retryAfterSlot{}
+	return context.WithValue(ctx, retryAfterKey{}, slot), slot
+}
+
+func (s *retryAfterSlot) get() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.d
+}
+
+type retryAfterTransport struct {
+	base http.RoundTripper
+}
+
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode < 400 {
+		return resp, err
+	}
+	if slot, ok := req.Context().Value(retryAfterKey{}).(*retryAfterSlot); ok {
+		if d := parseRetryAfter(resp.Header.Get("Retry-After")); d > 0 {
+			slot.mu.Lock()
+			slot.d = d
+			slot.mu.Unlock()
+		}
+	}
+	return resp, err
+}
+
+// parseRetryAfter accepts both forms of the header: delay-seconds or an HTTP date.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// statusError converts go-openai errors into *StatusError so wrappers can
+// inspect the status code uniformly.
+func statusError(provider string, err error, retryAfter time.Duration) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return &StatusError{Provider: provider, StatusCode: apiErr.HTTPStatusCode, RetryAfter: retryAfter, Err: err}
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return &StatusError{Provider: provider, StatusCode: reqErr.HTTPStatusCode, RetryAfter: retryAfter, Err: err}
+	}
+	return fmt.Errorf("%s completion: %w", provider, err)
+}
+
+// ErrBudgetExceeded is returned without calling the provider when a usage
+// budget for the feature is spent. Background workers stop their pass on it.
+var ErrBudgetExceeded = errors.New("LLM budget exceeded")
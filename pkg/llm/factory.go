@@ -1,37 +1,146 @@
 package llm
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
-func NewProvider(name string) (Provider, error) {
-	switch name {
-	case "claude", "anthropic":
-		key := os.Getenv("ANTHROPIC_API_KEY")
-		if key == "" {
+// NewProvider builds a provider for useCase. An empty name uses the provider
+// configured for the use case (see ProviderName); settings come from
+// ConfigFromEnv. A comma-separated name ("xai,claude,openai") builds an
+// ordered fallback chain. Each provider is wrapped with retries, its rate
+// limit and a circuit breaker.
+func NewProvider(useCase, name string) (Provider, error) {
+	if name == "" {
+		name = ProviderName(useCase)
+	}
+
+	var chain []Provider
+	var errs []error
+	for _, n := range strings.Split(name, ",") {
+		if n = strings.TrimSpace(n); n == "" {
+			continue
+		}
+		cfg := ConfigFromEnv(useCase, n)
+		p, err := newBaseProvider(useCase, n, cfg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		chain = append(chain, resilient(p, cfg))
+	}
+	if len(chain) == 0 {
+		if len(errs) == 0 {
+			return nil, fmt.Errorf("no LLM provider configured")
+		}
+		return nil, errors.Join(errs...)
+	}
+	// A partially configured chain still works; the broken links are skipped
+	for _, err := range errs {
+		log.Printf("Warning: LLM provider skipped for %s: %v", useCase, err)
+	}
+	return Fallback(chain...), nil
+}
+
+func newBaseProvider(useCase, name string, cfg Config) (Provider, error) {
+	switch canonicalName(name) {
+	case "replay":
+		return newReplayFromEnv(useCase, cfg)
+	case "claude":
+		if cfg.APIKey == "" {
 			return nil, fmt.Errorf("ANTHROPIC_API_KEY not set")
 		}
-		return NewClaudeProvider(key), nil
+		return NewClaudeProvider(cfg), nil
 	case "openai":
-		key := os.Getenv("OPENAI_API_KEY")
-		if key == "" {
+		if cfg.APIKey == "" {
 			return nil, fmt.Errorf("OPENAI_API_KEY not set")
 		}
-		return NewOpenAIProvider(key), nil
-	case "xai", "grok":
-		key := os.Getenv("XAI_API_KEY")
-		if key == "" {
+		return NewOpenAIProvider(cfg), nil
+	case "xai":
+		if cfg.APIKey == "" {
 			return nil, fmt.Errorf("XAI_API_KEY not set")
 		}
-		return NewXAIProvider(key), nil
+		return NewXAIProvider(cfg), nil
 	case "perplexity":
-		key := os.Getenv("PERPLEXITY_API_KEY")
-		if key == "" {
+		if cfg.APIKey == "" {
 			return nil, fmt.Errorf("PERPLEXITY_API_KEY not set")
 		}
-		return NewPerplexityProvider(key), nil
+		return NewPerplexityProvider(cfg), nil
+	case "compatible":
+		if cfg.BaseURL == "" {
+			return nil, fmt.Errorf("LLM_BASE_URL not set")
+		}
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("LLM_MODEL not set")
+		}
+		return NewCompatibleProvider(cfg), nil
 	default:
 		return nil, fmt.Errorf("unknown LLM provider: %s", name)
 	}
 }
+
+// resilient wraps p with the standard middleware stack. Retries sit inside
+// the breaker so one exhausted retry loop counts as a single failure.
+//
+//	LLM_RETRIES            total attempts per call (default 3)
+//	LLM_BREAKER_THRESHOLD  consecutive failures before opening (default 5)
+//	LLM_BREAKER_COOLDOWN   how long the breaker stays open (default 2m)
+func resilient(p Provider, cfg Config) Provider {
+	attempts := envInt("LLM_RETRIES", 3)
+	threshold := envInt("LLM_BREAKER_THRESHOLD", 5)
+	cooldown := 2 * time.Minute
+	if d, err := time.ParseDuration(os.Getenv("LLM_BREAKER_COOLDOWN")); err == nil && d > 0 {
+		cooldown = d
+	}
+
+	mw := []Middleware{WithCircuitBreaker(threshold, cooldown)}
+	if attempts > 1 {
+		mw = append(mw, WithRetry(attempts, 2*time.Second))
+	}
+	if cfg.RPM > 0 {
+		mw = append(mw, WithRateLimit(cfg.RPM, 1))
+	}
+	return Wrap(p, mw...)
+}
+
+func envInt(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
+
+// newReplayFromEnv configures a ReplayProvider:
+//
+//	LLM_REPLAY_DIR           cassette directory (default testdata/cassettes)
+//	LLM_REPLAY_MODE          replay (default) or record
+//	LLM_REPLAY_UPSTREAM      provider to record from, e.g. xai
+//	LLM_REPLAY_CHUNK_TOKENS  chunk size; keep it fixed between record and replay
+func newReplayFromEnv(useCase string, cfg Config) (Provider, error) {
+	dir := os.Getenv("LLM_REPLAY_DIR")
+	if dir == "" {
+		dir = filepath.Join("testdata", "cassettes")
+	}
+	mode := os.Getenv("LLM_REPLAY_MODE")
+
+	var upstream Provider
+	if mode == ReplayModeRecord {
+		name := os.Getenv("LLM_REPLAY_UPSTREAM")
+		if name == "" || canonicalName(name) == "replay" {
+			return nil, fmt.Errorf("LLM_REPLAY_UPSTREAM must name a real provider in record mode")
+		}
+		upCfg := ConfigFromEnv(useCase, name)
+		p, err := newBaseProvider(useCase, name, upCfg)
+		if err != nil {
+			return nil, fmt.Errorf("replay upstream: %w", err)
+		}
+		upstream = resilient(p, upCfg)
+	}
+	return NewReplayProvider(dir, mode, upstream, cfg.ChunkTokens)
+}
@@ -0,0 +1,134 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validator is implemented by CompleteJSON result types that have
+// constraints beyond what decoding checks (required fields, ranges...).
+type Validator interface {
+	Validate() error
+}
+
+// ParseError means the reply contained no decodable JSON for the target type.
+type ParseError struct {
+	Content string
+	Err     error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse json: %v (content: %s)", e.Err, truncate(e.Content, 200))
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// ValidationError means the JSON decoded but failed the type's Validate.
+type ValidationError struct {
+	Content string
+	Err     error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid json: %v", e.Err)
+}
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// CompleteJSON asks p for a JSON object and decodes it into T. Providers
+// with a native JSON mode are switched into it. When the reply does not
+// parse or validate, the model is shown its reply and the error and asked
+// once to correct it. The returned response carries the final reply with
+// token counts summed over both calls.
+//
+// Errors are the provider's own, *ParseError or *ValidationError.
+func CompleteJSON[T any](ctx context.Context, p Provider, req CompletionRequest) (T, *CompletionResponse, error) {
+	var zero T
+	req.JSON = true
+
+	resp, err := complete(ctx, p, req)
+	if err != nil {
+		return zero, nil, err
+	}
+	v, err := ParseJSON[T](resp.Content)
+	if err == nil {
+		return v, resp, nil
+	}
+
+	repair := req
+	if len(req.Messages) > 0 {
+		repair.Messages = append(append([]Message{}, req.Messages...),
+			Message{Role: RoleAssistant, Content: resp.Content},
+			Message{Role: RoleUser, Content: fmt.Sprintf("That reply could not be used: %v\nReply with only the corrected JSON object.", err)})
+	} else {
+		repair.UserPrompt = fmt.Sprintf("%s\n\nYour previous reply was:\n%s\n\nIt could not be used: %v\nReply with only the corrected JSON object.",
+			req.UserPrompt, resp.Content, err)
+	}
+	fixed, rerr := complete(ctx, p, repair)
+	if rerr != nil {
+		return zero, resp, err
+	}
+	fixed.InputTokens += resp.InputTokens
+	fixed.OutputTokens += resp.OutputTokens
+
+	v, err = ParseJSON[T](fixed.Content)
+	if err != nil {
+		return zero, fixed, err
+	}
+	return v, fixed, nil
+}
+
+// ParseJSON extracts the JSON value from a model reply, tolerating markdown
+// code fences and surrounding prose, decodes it into T and runs Validate.
+func ParseJSON[T any](content string) (T, error) {
+	var v T
+	raw := extractJSON(content)
+	if raw == "" {
+		return v, &ParseError{Content: content, Err: errors.New("no JSON found")}
+	}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return v, &ParseError{Content: content, Err: err}
+	}
+	if val, ok := any(&v).(Validator); ok {
+		if err := val.Validate(); err != nil {
+			return v, &ValidationError{Content: content, Err: err}
+		}
+	}
+	return v, nil
+}
+
+// extractJSON returns the outermost JSON object or array in s.
+func extractJSON(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, "```"); i != -1 {
+		inner := s[i+3:]
+		if nl := strings.Index(inner, "\n"); nl != -1 {
+			inner = inner[nl+1:] // drop the ```json language tag line
+		}
+		if end := strings.Index(inner, "```"); end != -1 {
+			inner = inner[:end]
+		}
+		s = strings.TrimSpace(inner)
+	}
+	if json.Valid([]byte(s)) {
+		return s
+	}
+	for _, pair := range [][2]string{{"{", "}"}, {"[", "]"}} {
+		start := strings.Index(s, pair[0])
+		end := strings.LastIndex(s, pair[1])
+		if start != -1 && end > start && json.Valid([]byte(s[start:end+1])) {
+			return s[start : end+1]
+		}
+	}
+	return ""
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type titled struct {
+	Title string `json:"title"`
+}
+
+func (t *titled) Validate() error {
+	if t.Title == "" {
+		return errors.New("title is required")
+	}
+	return nil
+}
+
+func TestParseJSON(t *testing.T) {
+	for _, content := range []string{
+		`{"title":"a"}`,
+		"```json\n{\"title\":\"a\"}\n```",
+		"```\n{\"title\":\"a\"}\n```",
+		`Here you go: {"title":"a"} hope that helps`,
+	} {
+		v, err := ParseJSON[titled](content)
+		if err != nil || v.Title != "a" {
+			t.Errorf("ParseJSON(%q) = %+v, %v", content, v, err)
+		}
+	}
+
+	var parseErr *ParseError
+	if _, err := ParseJSON[titled]("no json here"); !errors.As(err, &parseErr) {
+		t.Errorf("expected ParseError, got %v", err)
+	}
+	var validErr *ValidationError
+	if _, err := ParseJSON[titled](`{"title":""}`); !errors.As(err, &validErr) {
+		t.Errorf("expected ValidationError, got %v", err)
+	}
+}
+
+type scriptedProvider struct {
+	replies []string
+	prompts []string
+}
+
+func (s *scriptedProvider) Name() string { return "scripted" }
+
+func (s *scriptedProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	s.prompts = append(s.prompts, req.UserPrompt)
+	reply := s.replies[len(s.prompts)-1]
+	return &CompletionResponse{Content: reply, InputTokens: 10, OutputTokens: 5}, nil
+}
+
+func TestCompleteJSONRepair(t *testing.T) {
+	p := &scriptedProvider{replies: []string{`{"title":""}`, `{"title":"fixed"}`}}
+	v, resp, err := CompleteJSON[titled](context.Background(), p, CompletionRequest{UserPrompt: "summarise"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Title != "fixed" {
+		t.Errorf("title = %q, want fixed", v.Title)
+	}
+	if len(p.prompts) != 2 || !strings.Contains(p.prompts[1], "title is required") {
+		t.Errorf("repair prompt missing error: %q", p.prompts)
+	}
+	if resp.InputTokens != 20 || resp.OutputTokens != 10 {
+		t.Errorf("tokens = %d/%d, want summed 20/10", resp.InputTokens, resp.OutputTokens)
+	}
+}
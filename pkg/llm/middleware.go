@@ -0,0 +1,264 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Middleware decorates a Provider with extra behaviour.
+type Middleware func(Provider) Provider
+
+// Wrap applies middleware in order; the first is outermost.
+func Wrap(p Provider, mw ...Middleware) Provider {
+	for i := len(mw) - 1; i >= 0; i-- {
+		p = mw[i](p)
+	}
+	return p
+}
+
+// Wrapper is implemented by providers that decorate another provider.
+type Wrapper interface {
+	Unwrap() Provider
+}
+
+// Base strips all middleware and returns the innermost provider.
+func Base(p Provider) Provider {
+	for {
+		w, ok := p.(Wrapper)
+		if !ok {
+			return p
+		}
+		p = w.Unwrap()
+	}
+}
+
+// --- Retry ---
+
+type retryProvider struct {
+	next     Provider
+	attempts int
+	base     time.Duration
+	max      time.Duration
+}
+
+// WithRetry retries retryable errors (see Retryable) up to attempts times in
+// total, backing off exponentially from base with jitter. A Retry-After hint
+// from the server takes precedence when it is longer.
+func WithRetry(attempts int, base time.Duration) Middleware {
+	return func(p Provider) Provider {
+		return &retryProvider{next: p, attempts: attempts, base: base, max: time.Minute}
+	}
+}
+
+func (r *retryProvider) Name() string     { return r.next.Name() }
+func (r *retryProvider) Unwrap() Provider { return r.next }
+
+func (r *retryProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	delay := r.base
+	for attempt := 1; ; attempt++ {
+		resp, err := r.next.Complete(ctx, req)
+		if err == nil || attempt >= r.attempts || !Retryable(err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		wait := delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+		var se *StatusError
+		if errors.As(err, &se) && se.RetryAfter > wait {
+			wait = se.RetryAfter
+		}
+		if wait > r.max {
+			wait = r.max
+		}
+		log.Printf("LLM %s: attempt %d failed (%v), retrying in %s", r.next.Name(), attempt, err, wait.Round(time.Millisecond))
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+		delay *= 2
+		restartStream(ctx)
+	}
+}
+
+// --- Rate limit ---
+
+type rateLimitProvider struct {
+	next Provider
+
+	mu       sync.Mutex
+	tokens   float64
+	burst    float64
+	rate     float64 // tokens per second
+	lastFill time.Time
+}
+
+// WithRateLimit limits requests with a token bucket refilled at perMinute
+// tokens per minute, holding at most burst tokens. Callers wait for a token.
+func WithRateLimit(perMinute, burst int) Middleware {
+	if burst < 1 {
+		burst = 1
+	}
+	return func(p Provider) Provider {
+		return &rateLimitProvider{
+			next:     p,
+			tokens:   float64(burst),
+			burst:    float64(burst),
+			rate:     float64(perMinute) / 60,
+			lastFill: time.Now(),
+		}
+	}
+}
+
+func (l *rateLimitProvider) Name() string     { return l.next.Name() }
+func (l *rateLimitProvider) Unwrap() Provider { return l.next }
+
+func (l *rateLimitProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.next.Complete(ctx, req)
+}
+
+// wait blocks until a token is available or ctx is done.
+func (l *rateLimitProvider) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.lastFill).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+		l.lastFill = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// --- Circuit breaker ---
+
+// ErrCircuitOpen is returned without calling the provider while its breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type breakerProvider struct {
+	next      Provider
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// WithCircuitBreaker stops calling a provider after threshold consecutive
+// failures: transport errors, 429s and 5xx, as classified by Retryable. Once cooldown has passed a single probe request is let through;
+// success closes the breaker, failure re-opens it for another cooldown.
+func WithCircuitBreaker(threshold int, cooldown time.Duration) Middleware {
+	return func(p Provider) Provider {
+		return &breakerProvider{next: p, threshold: threshold, cooldown: cooldown}
+	}
+}
+
+func (b *breakerProvider) Name() string     { return b.next.Name() }
+func (b *breakerProvider) Unwrap() Provider { return b.next }
+
+func (b *breakerProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	b.mu.Lock()
+	if b.failures >= b.threshold {
+		if time.Now().Before(b.openUntil) || b.probing {
+			b.mu.Unlock()
+			return nil, fmt.Errorf("%s: %w", b.next.Name(), ErrCircuitOpen)
+		}
+		b.probing = true
+	}
+	b.mu.Unlock()
+
+	resp, err := b.next.Complete(ctx, req)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	switch {
+	case ctx.Err() != nil:
+		// The caller giving up says nothing about the provider's health
+	case Retryable(err):
+		b.failures++
+		if b.failures >= b.threshold {
+			b.openUntil = time.Now().Add(b.cooldown)
+			log.Printf("LLM %s: circuit open for %s after %d failures", b.next.Name(), b.cooldown, b.failures)
+		}
+	default:
+		// Success, or the provider answered and refused this one request
+		// (bad input, tools unsupported, budget): it is healthy
+		b.failures = 0
+	}
+	return resp, err
+}
+
+// --- Fallback ---
+
+type fallbackProvider struct {
+	providers []Provider
+}
+
+// Fallback tries providers in order and returns the first successful answer.
+// The response's Provider field names the provider that answered.
+func Fallback(providers ...Provider) Provider {
+	if len(providers) == 1 {
+		return providers[0]
+	}
+	return &fallbackProvider{providers: providers}
+}
+
+func (f *fallbackProvider) Name() string {
+	names := make([]string, len(f.providers))
+	for i, p := range f.providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ",")
+}
+
+// Unwrap returns the primary provider.
+func (f *fallbackProvider) Unwrap() Provider { return f.providers[0] }
+
+func (f *fallbackProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	var errs []error
+	for i, p := range f.providers {
+		if i > 0 {
+			restartStream(ctx)
+		}
+		resp, err := p.Complete(ctx, req)
+		if err == nil {
+			if resp.Provider == "" {
+				resp.Provider = p.Name()
+			}
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, err)
+		if i < len(f.providers)-1 {
+			log.Printf("LLM %s failed (%v), falling back to %s", p.Name(), err, f.providers[i+1].Name())
+		}
+	}
+	return nil, errors.Join(errs...)
+}
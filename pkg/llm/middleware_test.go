@@ -0,0 +1,136 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type fakeProvider struct {
+	name  string
+	errs  []error // returned in order; nil entries succeed
+	calls int
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	f.calls++
+	if i := f.calls - 1; i < len(f.errs) && f.errs[i] != nil {
+		return nil, f.errs[i]
+	}
+	return &CompletionResponse{Content: "ok", Provider: f.name}, nil
+}
+
+func status(code int) error {
+	return &StatusError{Provider: "fake", StatusCode: code, Err: errors.New("boom")}
+}
+
+func TestRetry(t *testing.T) {
+	f := &fakeProvider{name: "a", errs: []error{status(http.StatusTooManyRequests), status(http.StatusBadGateway)}}
+	p := Wrap(f, WithRetry(3, time.Millisecond))
+	if _, err := p.Complete(context.Background(), CompletionRequest{}); err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if f.calls != 3 {
+		t.Fatalf("calls = %d, want 3", f.calls)
+	}
+
+	f = &fakeProvider{name: "a", errs: []error{status(http.StatusBadRequest)}}
+	p = Wrap(f, WithRetry(3, time.Millisecond))
+	if _, err := p.Complete(context.Background(), CompletionRequest{}); err == nil {
+		t.Fatal("expected 400 to fail without retry")
+	}
+	if f.calls != 1 {
+		t.Fatalf("calls = %d, want 1", f.calls)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	f := &fakeProvider{name: "a", errs: []error{status(500), status(500), nil}}
+	p := Wrap(f, WithCircuitBreaker(2, 20*time.Millisecond))
+	ctx := context.Background()
+
+	p.Complete(ctx, CompletionRequest{})
+	p.Complete(ctx, CompletionRequest{})
+	if _, err := p.Complete(ctx, CompletionRequest{}); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open circuit, got %v", err)
+	}
+	if f.calls != 2 {
+		t.Fatalf("calls = %d, want 2 while open", f.calls)
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	if _, err := p.Complete(ctx, CompletionRequest{}); err != nil {
+		t.Fatalf("expected probe to succeed, got %v", err)
+	}
+	if _, err := p.Complete(ctx, CompletionRequest{}); err != nil {
+		t.Fatalf("expected closed circuit, got %v", err)
+	}
+}
+
+func TestCircuitBreakerIgnoresRequestErrors(t *testing.T) {
+	f := &fakeProvider{name: "a", errs: []error{status(400), status(400), status(422)}}
+	p := Wrap(f, WithCircuitBreaker(2, time.Hour))
+	ctx := context.Background()
+
+	for range 3 {
+		if _, err := p.Complete(ctx, CompletionRequest{}); errors.Is(err, ErrCircuitOpen) {
+			t.Fatal("client errors opened the circuit")
+		}
+	}
+	if _, err := p.Complete(ctx, CompletionRequest{}); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if f.calls != 4 {
+		t.Fatalf("calls = %d, want 4", f.calls)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	a := &fakeProvider{name: "xai", errs: []error{status(503)}}
+	b := &fakeProvider{name: "claude"}
+	p := Fallback(a, b)
+
+	resp, err := p.Complete(context.Background(), CompletionRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Provider != "claude" {
+		t.Fatalf("Provider = %q, want claude", resp.Provider)
+	}
+	if p.Name() != "xai,claude" {
+		t.Fatalf("Name = %q", p.Name())
+	}
+	if Base(p) != Provider(a) {
+		t.Fatal("Base should return the primary provider")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	f := &fakeProvider{name: "a"}
+	p := Wrap(f, WithRateLimit(600, 1)) // one token every 100ms
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.Complete(ctx, CompletionRequest{}); err != nil {
+		t.Fatalf("first call should use the burst token: %v", err)
+	}
+	if _, err := p.Complete(ctx, CompletionRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("second call should wait past the deadline, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("7"); d != 7*time.Second {
+		t.Fatalf("seconds form: %v", d)
+	}
+	if d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); d < 55*time.Second {
+		t.Fatalf("date form: %v", d)
+	}
+	if d := parseRetryAfter("soon"); d != 0 {
+		t.Fatalf("invalid: %v", d)
+	}
+}
@@ -1,62 +1,8 @@
 package llm
 
-import (
-	"context"
-	"fmt"
+const defaultOpenAIModel = "gpt-4o"
 
-	openai "github.com/sashabaranov/go-openai"
-)
-
-type OpenAIProvider struct {
-	client *openai.Client
-	model  string
-}
-
-func NewOpenAIProvider(apiKey string) *OpenAIProvider {
-	return &OpenAIProvider{
-		client: openai.NewClient(apiKey),
-		model:  "gpt-4o",
-	}
-}
-
-func (o *OpenAIProvider) Name() string { return "openai" }
-
-func (o *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
-	maxTokens := req.MaxTokens
-	if maxTokens <= 0 {
-		maxTokens = 4096
-	}
-
-	messages := []openai.ChatCompletionMessage{}
-	if req.SystemPrompt != "" {
-		messages = append(messages, openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleSystem,
-			Content: req.SystemPrompt,
-		})
-	}
-	messages = append(messages, openai.ChatCompletionMessage{
-		Role:    openai.ChatMessageRoleUser,
-		Content: req.UserPrompt,
-	})
-
-	resp, err := o.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       o.model,
-		Messages:    messages,
-		MaxTokens:   maxTokens,
-		Temperature: float32(req.Temperature),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("openai completion: %w", err)
-	}
-
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("empty response from openai")
-	}
-
-	return &CompletionResponse{
-		Content:      resp.Choices[0].Message.Content,
-		Model:        resp.Model,
-		InputTokens:  resp.Usage.PromptTokens,
-		OutputTokens: resp.Usage.CompletionTokens,
-	}, nil
+func NewOpenAIProvider(cfg Config) *ChatProvider {
+	cfg.Model = orDefault(cfg.Model, defaultOpenAIModel)
+	return newChatProvider("openai", cfg, chatFeatures{jsonMode: true, tools: true})
 }
@@ -1,22 +1,119 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
 
 type CompletionRequest struct {
 	SystemPrompt string
 	UserPrompt   string
 	MaxTokens    int
-	Temperature  float64
+	// Temperature is sent when set, including 0; nil leaves the provider's
+	// default.
+	Temperature *float64
+	// JSON asks for a single JSON object, using the provider's native JSON
+	// mode where it has one. See CompleteJSON.
+	JSON bool
+	// Images are sent alongside UserPrompt to vision-capable models.
+	Images []Image
+	// Messages is the conversation for multi-turn requests. When set,
+	// UserPrompt and Images are ignored; SystemPrompt still applies.
+	Messages []Message
+	// Tools the model may call. Calls come back in CompletionResponse.ToolCalls
+	// and their results go back as RoleTool messages.
+	Tools []Tool
+}
+
+// Temperature returns t as a CompletionRequest.Temperature.
+func Temperature(t float64) *float64 { return &t }
+
+// Message roles.
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Message is one turn of a conversation.
+type Message struct {
+	Role    string
+	Content string
+	// Images attach to user messages.
+	Images []Image
+	// ToolCalls are the calls an assistant turn made.
+	ToolCalls []ToolCall
+	// ToolCallID links a RoleTool result to the call it answers.
+	ToolCallID string
+}
+
+// Tool describes a function the model may call. Parameters is a JSON Schema
+// object.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+// ToolCall is a model's request to run a tool. Arguments is a JSON object.
+type ToolCall struct {
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// ErrToolsUnsupported is returned by providers without function calling.
+var ErrToolsUnsupported = errors.New("provider does not support tool calls")
+
+// conversation returns the system prompt and message list for req. Requests
+// built the single-turn way (UserPrompt, Images) become one user message;
+// system messages in Messages are folded into the system prompt.
+func (req CompletionRequest) conversation() (string, []Message) {
+	if len(req.Messages) == 0 {
+		return req.SystemPrompt, []Message{{Role: RoleUser, Content: req.UserPrompt, Images: req.Images}}
+	}
+	system := req.SystemPrompt
+	msgs := make([]Message, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		if m.Role == RoleSystem {
+			if system != "" {
+				system += "\n\n"
+			}
+			system += m.Content
+			continue
+		}
+		msgs = append(msgs, m)
+	}
+	return system, msgs
+}
+
+// Image is an inline image attached to a request.
+type Image struct {
+	MimeType string
+	Data     []byte
 }
 
 type CompletionResponse struct {
-	Content     string
-	Model       string
-	InputTokens int
+	Content string
+	Model   string
+	// Provider is the name of the provider that actually answered, which
+	// differs from the wrapper's Name() when a fallback chain is in use.
+	Provider     string
+	InputTokens  int
 	OutputTokens int
+	// ToolCalls are set when the model asks to call tools instead of (or as
+	// well as) answering.
+	ToolCalls []ToolCall
 }
 
 type Provider interface {
 	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
 	Name() string
 }
+
+func (i Image) dataURI() string {
+	return "data:" + i.MimeType + ";base64," + base64.StdEncoding.EncodeToString(i.Data)
+}
@@ -0,0 +1,218 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Replay modes.
+const (
+	ReplayModeReplay = "replay" // serve cassettes only; a miss is an error
+	ReplayModeRecord = "record" // serve cassettes, record misses from upstream
+)
+
+// ErrNoCassette is returned in replay mode when a request has no recording.
+var ErrNoCassette = errors.New("no cassette for request")
+
+// ReplayProvider answers from cassette files, one JSON file per request
+// keyed by RequestKey. In record mode, misses go to an upstream provider and
+// the answer is saved, so a pipeline can be recorded once against real APIs
+// and then run offline (CI, demos). Image requests are keyed by image
+// content, so the same provider covers vision.
+type ReplayProvider struct {
+	dir         string
+	mode        string
+	upstream    Provider
+	chunkTokens int
+}
+
+// Cassette is the on-disk recording of one call. Request is informational;
+// lookups use Key only.
+type Cassette struct {
+	Key      string          `json:"key"`
+	Request  cassetteRequest `json:"request"`
+	Response cassetteReply   `json:"response"`
+}
+
+type cassetteRequest struct {
+	SystemPrompt string   `json:"system_prompt"`
+	UserPrompt   string   `json:"user_prompt"`
+	MaxTokens    int      `json:"max_tokens"`
+	Temperature  float64  `json:"temperature"`
+	JSON         bool     `json:"json,omitempty"`
+	Images       []string `json:"images,omitempty"`
+	// Messages and Tools are recorded as sent; images in messages are
+	// reduced to hashes like Images above.
+	Messages []cassetteMessage `json:"messages,omitempty"`
+	Tools    []Tool            `json:"tools,omitempty"`
+}
+
+type cassetteMessage struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	Images     []string   `json:"images,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+type cassetteReply struct {
+	Content      string     `json:"content"`
+	Model        string     `json:"model"`
+	Provider     string     `json:"provider"`
+	InputTokens  int        `json:"input_tokens"`
+	OutputTokens int        `json:"output_tokens"`
+	ToolCalls    []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// NewReplayProvider serves cassettes from dir. upstream is required in
+// record mode and ignored otherwise. ChunkTokens should match between
+// recording and replay, since chunking changes the prompts.
+func NewReplayProvider(dir, mode string, upstream Provider, chunkTokens int) (*ReplayProvider, error) {
+	switch mode {
+	case "", ReplayModeReplay:
+		mode = ReplayModeReplay
+	case ReplayModeRecord:
+		if upstream == nil {
+			return nil, fmt.Errorf("replay: record mode needs an upstream provider")
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("replay: create cassette dir: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("replay: unknown mode %q (use %s or %s)", mode, ReplayModeReplay, ReplayModeRecord)
+	}
+	return &ReplayProvider{dir: dir, mode: mode, upstream: upstream, chunkTokens: chunkTokens}, nil
+}
+
+func (r *ReplayProvider) Name() string { return "replay" }
+
+// Model reports the upstream model when recording.
+func (r *ReplayProvider) Model() string {
+	if m, ok := Base(r.upstream).(interface{ Model() string }); ok {
+		return "replay:" + m.Model()
+	}
+	return "replay"
+}
+
+func (r *ReplayProvider) ChunkTokens() int { return r.chunkTokens }
+
+func (r *ReplayProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	key := RequestKey(req)
+	path := filepath.Join(r.dir, key+".json")
+
+	if c, err := loadCassette(path); err == nil {
+		provider := c.Response.Provider
+		if provider == "" {
+			provider = "replay"
+		}
+		return &CompletionResponse{
+			Content:      c.Response.Content,
+			Model:        c.Response.Model,
+			Provider:     provider,
+			InputTokens:  c.Response.InputTokens,
+			OutputTokens: c.Response.OutputTokens,
+			ToolCalls:    c.Response.ToolCalls,
+		}, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+
+	if r.mode != ReplayModeRecord {
+		return nil, fmt.Errorf("%w %s (prompt: %s)", ErrNoCassette, key, truncate(req.UserPrompt, 80))
+	}
+
+	resp, err := r.upstream.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Provider == "" {
+		resp.Provider = r.upstream.Name()
+	}
+	c := Cassette{
+		Key:     key,
+		Request: newCassetteRequest(req),
+		Response: cassetteReply{
+			Content:      resp.Content,
+			Model:        resp.Model,
+			Provider:     resp.Provider,
+			InputTokens:  resp.InputTokens,
+			OutputTokens: resp.OutputTokens,
+			ToolCalls:    resp.ToolCalls,
+		},
+	}
+	if err := saveCassette(path, c); err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+	return resp, nil
+}
+
+// RequestKey identifies a request by everything that affects the answer:
+// prompts, messages, tools, limits, JSON mode and image content.
+func RequestKey(req CompletionRequest) string {
+	b, _ := json.Marshal(newCassetteRequest(req))
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:12])
+}
+
+func newCassetteRequest(req CompletionRequest) cassetteRequest {
+	c := cassetteRequest{
+		SystemPrompt: req.SystemPrompt,
+		UserPrompt:   req.UserPrompt,
+		MaxTokens:    req.MaxTokens,
+		JSON:         req.JSON,
+	}
+	if req.Temperature != nil {
+		c.Temperature = *req.Temperature
+	}
+	c.Images = imageHashes(req.Images)
+	for _, m := range req.Messages {
+		c.Messages = append(c.Messages, cassetteMessage{
+			Role:       m.Role,
+			Content:    m.Content,
+			Images:     imageHashes(m.Images),
+			ToolCalls:  m.ToolCalls,
+			ToolCallID: m.ToolCallID,
+		})
+	}
+	c.Tools = req.Tools
+	return c
+}
+
+func imageHashes(images []Image) []string {
+	var out []string
+	for _, img := range images {
+		sum := sha256.Sum256(img.Data)
+		out = append(out, img.MimeType+" sha256:"+hex.EncodeToString(sum[:]))
+	}
+	return out
+}
+
+func loadCassette(path string) (*Cassette, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", filepath.Base(path), err)
+	}
+	return &c, nil
+}
+
+func saveCassette(path string, c Cassette) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
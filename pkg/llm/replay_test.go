@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestReplayRecordThenReplay(t *testing.T) {
+	dir := t.TempDir()
+	req := CompletionRequest{UserPrompt: "hello", MaxTokens: 10, Images: []Image{{MimeType: "image/png", Data: []byte{1, 2, 3}}}}
+
+	up := &fakeProvider{name: "up"}
+	rec, err := NewReplayProvider(dir, ReplayModeRecord, up, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	// A recorded request is served from the cassette, not upstream
+	if _, err := rec.Complete(context.Background(), req); err != nil || up.calls != 1 {
+		t.Fatalf("upstream calls = %d, err = %v; want cassette hit", up.calls, err)
+	}
+
+	play, err := NewReplayProvider(dir, ReplayModeReplay, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := play.Complete(context.Background(), req)
+	if err != nil || resp.Content != "ok" || resp.Provider != "up" {
+		t.Fatalf("replay = %+v, %v", resp, err)
+	}
+
+	other := req
+	other.Images = []Image{{MimeType: "image/png", Data: []byte{9}}}
+	if _, err := play.Complete(context.Background(), other); !errors.Is(err, ErrNoCassette) {
+		t.Fatalf("different image should miss, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package llm
+
+import "context"
+
+// StreamFunc receives reply text as the model produces it.
+type StreamFunc func(delta string)
+
+// Streamer is implemented by providers that can stream a reply.
+type Streamer interface {
+	CompleteStream(ctx context.Context, req CompletionRequest, onDelta StreamFunc) (*CompletionResponse, error)
+}
+
+type streamKey struct{}
+
+type streamSink struct {
+	fn StreamFunc
+	// reset, when set, drops what this sink has seen (see restartStream).
+	reset    func()
+	streamed bool
+	// parent is the sink this one rewrites for (see MapStream); streaming
+	// into either counts as streaming for both.
+	parent *streamSink
+}
+
+// WithStream asks every completion made with ctx to stream its text to fn.
+// The request still flows through the usual middleware (retries, fallback,
+// metering, JSON repair), so a later attempt or call streams again from the
+// start. reset, when not nil, is called first so the caller can discard the
+// text it has shown.
+func WithStream(ctx context.Context, fn StreamFunc, reset func()) context.Context {
+	return context.WithValue(ctx, streamKey{}, &streamSink{fn: fn, reset: reset})
+}
+
+// CompleteStream completes req through p, passing text to onDelta as it is
+// produced. Providers that can't stream deliver the reply as one delta.
+func CompleteStream(ctx context.Context, p Provider, req CompletionRequest, onDelta StreamFunc) (*CompletionResponse, error) {
+	return complete(WithStream(ctx, onDelta, nil), p, req)
+}
+
+// complete is p.Complete, plus delivering the whole reply as a single delta
+// when a stream was requested but nothing in the chain streamed.
+func complete(ctx context.Context, p Provider, req CompletionRequest) (*CompletionResponse, error) {
+	sink, _ := ctx.Value(streamKey{}).(*streamSink)
+	if sink == nil {
+		return p.Complete(ctx, req)
+	}
+	restartStream(ctx)
+	resp, err := p.Complete(ctx, req)
+	if err == nil && !sink.streamed && resp.Content != "" {
+		streamTo(ctx)(resp.Content)
+	}
+	return resp, err
+}
+
+// MapStream returns ctx with its stream callback replaced by wrap(callback),
+// for middleware that rewrites reply text on its way to the caller. reset,
+// when not nil, drops any text the rewriter holds back when the stream
+// restarts. It returns ctx unchanged when no stream was requested.
+func MapStream(ctx context.Context, wrap func(StreamFunc) StreamFunc, reset func()) context.Context {
+	sink, _ := ctx.Value(streamKey{}).(*streamSink)
+	if sink == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, streamKey{}, &streamSink{fn: wrap(sink.fn), reset: reset, parent: sink})
+}
+
+// restartStream is called before a completion that will stream its reply
+// from the start: a new call, a retry, or the next provider in a fallback
+// chain. If the stream has delivered anything, every sink up to the
+// caller's is reset.
+func restartStream(ctx context.Context) {
+	sink, _ := ctx.Value(streamKey{}).(*streamSink)
+	if sink == nil || !sink.streamed {
+		return
+	}
+	for s := sink; s != nil; s = s.parent {
+		s.streamed = false
+		if s.reset != nil {
+			s.reset()
+		}
+	}
+}
+
+// streamTo returns the callback a streaming-capable provider should send
+// deltas to, or nil when the caller didn't ask for a stream.
+func streamTo(ctx context.Context) StreamFunc {
+	sink, _ := ctx.Value(streamKey{}).(*streamSink)
+	if sink == nil {
+		return nil
+	}
+	return func(delta string) {
+		for s := sink; s != nil; s = s.parent {
+			s.streamed = true
+		}
+		sink.fn(delta)
+	}
+}
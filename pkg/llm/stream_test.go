@@ -0,0 +1,104 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClaudeCompleteStream(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"model":"claude-test","usage":{"input_tokens":12}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"\"title\":"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"\"hi\"}"}}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}`,
+		`{"type":"message_stop"}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, e := range events {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", e)
+		}
+	}))
+	defer srv.Close()
+
+	p := NewClaudeProvider(Config{APIKey: "k", BaseURL: srv.URL})
+	var deltas []string
+	resp, err := CompleteStream(context.Background(), p, CompletionRequest{UserPrompt: "go", JSON: true}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"title":"hi"}`; resp.Content != want || strings.Join(deltas, "") != want {
+		t.Fatalf("content = %q, deltas = %q, want %q", resp.Content, deltas, want)
+	}
+	if resp.Model != "claude-test" || resp.InputTokens != 12 || resp.OutputTokens != 7 {
+		t.Fatalf("unexpected metadata: %+v", resp)
+	}
+}
+
+func TestCompleteStreamFallsBackToOneDelta(t *testing.T) {
+	var deltas []string
+	resp, err := CompleteStream(context.Background(), &fakeProvider{name: "a"}, CompletionRequest{}, func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deltas) != 1 || deltas[0] != resp.Content {
+		t.Fatalf("deltas = %q, want one delta of %q", deltas, resp.Content)
+	}
+}
+
+// halfStreamer streams part of a reply and fails on its first call, then
+// streams a whole reply.
+type halfStreamer struct{ calls int }
+
+func (h *halfStreamer) Name() string { return "half" }
+
+func (h *halfStreamer) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	h.calls++
+	onDelta := streamTo(ctx)
+	if h.calls == 1 {
+		onDelta("par")
+		return nil, status(http.StatusBadGateway)
+	}
+	onDelta("full")
+	return &CompletionResponse{Content: "full"}, nil
+}
+
+func TestStreamResetsBeforeRetryAndRepair(t *testing.T) {
+	var events []string
+	newCtx := func() context.Context {
+		events = nil
+		return WithStream(context.Background(), func(d string) {
+			events = append(events, d)
+		}, func() {
+			events = append(events, "<reset>")
+		})
+	}
+
+	ctx := newCtx()
+	p := Wrap(&halfStreamer{}, WithRetry(2, time.Millisecond))
+	if _, err := complete(ctx, p, CompletionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(events, "|"); got != "par|<reset>|full" {
+		t.Errorf("retry events = %q", got)
+	}
+
+	ctx = newCtx()
+	s := &scriptedProvider{replies: []string{`{"title":""}`, `{"title":"fixed"}`}}
+	if _, _, err := CompleteJSON[titled](ctx, s, CompletionRequest{UserPrompt: "summarise"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(events, "|"); got != `{"title":""}|<reset>|{"title":"fixed"}` {
+		t.Errorf("repair events = %q", got)
+	}
+}
@@ -2,33 +2,31 @@ package media
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
 	"time"
 
-	openai "github.com/sashabaranov/go-openai"
+	"signal-sideband/pkg/llm"
 	"signal-sideband/pkg/store"
 )
 
 type AnalyzeWorker struct {
 	store     *store.Store
-	client    *openai.Client
-	model     string
+	provider  llm.Provider
 	interval  time.Duration
 	mediaPath string
 }
 
-func NewAnalyzeWorker(s *store.Store, xaiAPIKey string, interval time.Duration, mediaPath string) *AnalyzeWorker {
-	cfg := openai.DefaultConfig(xaiAPIKey)
-	cfg.BaseURL = "https://api.x.ai/v1"
+// NewAnalyzeWorker analyzes images with a vision-capable provider. Budget
+// enforcement and usage recording happen in the provider (usage.Ledger.Meter).
+func NewAnalyzeWorker(s *store.Store, provider llm.Provider, interval time.Duration, mediaPath string) *AnalyzeWorker {
 	return &AnalyzeWorker{
 		store:     s,
-		client:    openai.NewClientWithConfig(cfg),
-		model:     "grok-2-vision-1212",
+		provider:  provider,
 		interval:  interval,
 		mediaPath: mediaPath,
 	}
@@ -78,6 +76,10 @@ func (w *AnalyzeWorker) process(ctx context.Context) {
 		}
 
 		analysis, err := w.analyzeImage(ctx, imagePath, a.ContentType)
+		if errors.Is(err, llm.ErrBudgetExceeded) {
+			log.Printf("Analysis worker: paused: %v", err)
+			return
+		}
 		if err != nil {
 			log.Printf("Analysis worker: analyze %s failed: %v", a.ID, err)
 			continue
@@ -91,82 +93,48 @@ func (w *AnalyzeWorker) process(ctx context.Context) {
 	}
 }
 
+// imageAnalysis is the vision model's JSON reply. It stays a map so fields
+// the model adds beyond the prompt are kept.
+type imageAnalysis map[string]any
+
+func (a imageAnalysis) Validate() error {
+	if d, _ := a["description"].(string); d == "" {
+		return errors.New("description is required")
+	}
+	return nil
+}
+
+const analysisSystemPrompt = `You are an image analysis assistant. Analyze the image and respond with ONLY a JSON object (no markdown, no code blocks) with these fields:
+- "description": A 1-2 sentence description of what the image shows
+- "text_content": Any text visible in the image (empty string if none)
+- "colors": Dominant colors as a comma-separated string
+- "objects": Key objects/subjects as a comma-separated string
+- "scene": The type of scene (e.g. "outdoor landscape", "screenshot", "food photo", "selfie", "meme", "document")`
+
 func (w *AnalyzeWorker) analyzeImage(ctx context.Context, imagePath, contentType string) (json.RawMessage, error) {
 	data, err := os.ReadFile(imagePath)
 	if err != nil {
 		return nil, fmt.Errorf("read image: %w", err)
 	}
 
-	// Determine mime type for the data URI
 	mime := contentType
 	if strings.HasPrefix(contentType, "video/") {
 		mime = "image/jpeg" // thumbnails are JPEG
 	}
 
-	dataURI := fmt.Sprintf("data:%s;base64,%s", mime, base64.StdEncoding.EncodeToString(data))
-
-	resp, err := w.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model: w.model,
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: `You are an image analysis assistant. Analyze the image and respond with ONLY a JSON object (no markdown, no code blocks) with these fields:
-- "description": A 1-2 sentence description of what the image shows
-- "text_content": Any text visible in the image (empty string if none)
-- "colors": Dominant colors as a comma-separated string
-- "objects": Key objects/subjects as a comma-separated string
-- "scene": The type of scene (e.g. "outdoor landscape", "screenshot", "food photo", "selfie", "meme", "document")`,
-			},
-			{
-				Role: openai.ChatMessageRoleUser,
-				MultiContent: []openai.ChatMessagePart{
-					{
-						Type: openai.ChatMessagePartTypeImageURL,
-						ImageURL: &openai.ChatMessageImageURL{
-							URL:    dataURI,
-							Detail: openai.ImageURLDetailLow,
-						},
-					},
-					{
-						Type: openai.ChatMessagePartTypeText,
-						Text: "Analyze this image.",
-					},
-				},
-			},
-		},
-		MaxTokens:   512,
-		Temperature: 0.2,
+	parsed, resp, err := llm.CompleteJSON[imageAnalysis](ctx, w.provider, llm.CompletionRequest{
+		SystemPrompt: analysisSystemPrompt,
+		UserPrompt:   "Analyze this image.",
+		Images:       []llm.Image{{MimeType: mime, Data: data}},
+		MaxTokens:    512,
+		Temperature:  llm.Temperature(0.2),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("vision api: %w", err)
-	}
-
-	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("empty response from vision api")
-	}
-
-	content := resp.Choices[0].Message.Content
-
-	// Strip markdown code blocks if present
-	content = strings.TrimSpace(content)
-	if strings.HasPrefix(content, "```") {
-		if idx := strings.Index(content[3:], "\n"); idx != -1 {
-			content = content[3+idx+1:]
-		}
-		if idx := strings.LastIndex(content, "```"); idx != -1 {
-			content = content[:idx]
-		}
-		content = strings.TrimSpace(content)
-	}
-
-	// Validate JSON
-	var parsed map[string]any
-	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
-		return nil, fmt.Errorf("invalid json response: %w (content: %s)", err, content)
+		return nil, fmt.Errorf("vision analysis: %w", err)
 	}
 
 	// Add metadata
-	parsed["model"] = w.model
+	parsed["model"] = resp.Model
 	parsed["analyzed_at"] = time.Now().UTC().Format(time.RFC3339)
 
 	result, err := json.Marshal(parsed)
@@ -0,0 +1,32 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"signal-sideband/pkg/llm"
+)
+
+func TestAnalyzeImageReplay(t *testing.T) {
+	p, err := llm.NewReplayProvider("testdata/cassettes", llm.ReplayModeReplay, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &AnalyzeWorker{provider: p}
+
+	raw, err := w.analyzeImage(context.Background(), "testdata/pixel.png", "image/png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var analysis map[string]any
+	if err := json.Unmarshal(raw, &analysis); err != nil {
+		t.Fatal(err)
+	}
+	if analysis["description"] != "A single red pixel." {
+		t.Errorf("description = %v", analysis["description"])
+	}
+	if analysis["model"] != "grok-2-vision-1212" || analysis["analyzed_at"] == nil {
+		t.Errorf("metadata missing: %v", analysis)
+	}
+}
@@ -1,6 +1,8 @@
 package media
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -57,6 +59,98 @@ func (d *Downloader) Download(attachmentID, contentType, filename string) (strin
 	return localPath, nil
 }
 
+// DownloadContactAvatar saves a contact's profile picture under
+// avatars/contacts and returns its path, or "" when they have none.
+func (d *Downloader) DownloadContactAvatar(uuid string) (string, error) {
+	body, contentType, err := d.api.ContactAvatar(uuid)
+	if errors.Is(err, signal.ErrNoAvatar) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	return d.saveAvatar("contacts", uuid, contentType, body)
+}
+
+// DownloadGroupAvatar saves a group's picture under avatars/groups and
+// returns its path, or "" when it has none.
+func (d *Downloader) DownloadGroupAvatar(groupID string) (string, error) {
+	body, contentType, err := d.api.GroupAvatar(groupID)
+	if errors.Is(err, signal.ErrNoAvatar) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	return d.saveAvatar("groups", groupID, contentType, body)
+}
+
+// saveAvatar replaces the stored avatar for key. The file is written aside
+// and renamed so a failed download never leaves a truncated picture.
+func (d *Downloader) saveAvatar(kind, key, contentType string, body io.Reader) (string, error) {
+	dir := filepath.Join(d.mediaPath, "avatars", kind)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("mkdir: %w", err)
+	}
+
+	ext := extFromContentType(contentType)
+	if ext == ".bin" {
+		ext = ".jpg" // signal-cli often omits the type; avatars are JPEGs
+	}
+	localPath := filepath.Join(dir, safeName(key)+ext)
+
+	tmp, err := os.CreateTemp(dir, ".avatar-*")
+	if err != nil {
+		return "", fmt.Errorf("create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), localPath); err != nil {
+		return "", fmt.Errorf("rename: %w", err)
+	}
+	return localPath, nil
+}
+
+// RemoveAvatar deletes an avatar file saveAvatar wrote. Paths outside the
+// avatars directory and files already gone are ignored.
+func (d *Downloader) RemoveAvatar(path string) error {
+	if path == "" {
+		return nil
+	}
+	rel, err := filepath.Rel(filepath.Join(d.mediaPath, "avatars"), path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// safeName turns an ID into a file name. Group IDs are standard base64,
+// whose "+", "/" and "=" can't share one replacement without two groups
+// landing on the same file, so they are re-encoded URL-safe. Other IDs
+// (contact UUIDs) keep their safe characters.
+func safeName(id string) string {
+	if raw, err := base64.StdEncoding.Strict().DecodeString(id); err == nil && len(raw) > 0 {
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '_'
+	}, id)
+}
+
 func extFromContentType(ct string) string {
 	switch ct {
 	case "image/jpeg":
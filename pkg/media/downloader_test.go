@@ -0,0 +1,17 @@
+package media
+
+import "testing"
+
+func TestSafeName(t *testing.T) {
+	// These differ only in characters a plain replacement maps alike
+	a, b := safeName("ab+/cQ=="), safeName("ab/+cQ==")
+	if a == b {
+		t.Errorf("distinct group IDs share a file name: %q", a)
+	}
+	if got := safeName("ab+/cQ=="); got != "ab-_cQ" {
+		t.Errorf("safeName = %q, want URL-safe base64", got)
+	}
+	if got := safeName("0b9f1c2e-7d3a-4e5f-9a1b-2c3d4e5f6a7b"); got != "0b9f1c2e-7d3a-4e5f-9a1b-2c3d4e5f6a7b" {
+		t.Errorf("UUID changed: %q", got)
+	}
+}
@@ -13,6 +13,9 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"signal-sideband/pkg/redact"
+	"signal-sideband/pkg/usage"
 )
 
 // PicOfDayGenerator generates a daily "nano banana" themed image via Gemini.
@@ -20,13 +23,17 @@ type PicOfDayGenerator struct {
 	apiKey    string
 	mediaPath string
 	model     string
+	ledger    *usage.Ledger
+	redactor  *redact.Redactor
 }
 
-func NewPicOfDayGenerator(apiKey, mediaPath string) *PicOfDayGenerator {
+func NewPicOfDayGenerator(apiKey, mediaPath string, ledger *usage.Ledger, redactor *redact.Redactor) *PicOfDayGenerator {
 	return &PicOfDayGenerator{
 		apiKey:    apiKey,
 		mediaPath: mediaPath,
 		model:     "gemini-2.5-flash-image",
+		ledger:    ledger,
+		redactor:  redactor,
 	}
 }
 
@@ -60,6 +67,10 @@ type geminiResponse struct {
 			} `json:"parts"`
 		} `json:"content"`
 	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
 	Error *struct {
 		Message string `json:"message"`
 		Code    int    `json:"code"`
@@ -86,6 +97,12 @@ func (g *PicOfDayGenerator) Generate(ctx context.Context, themes []string, overv
 			`No text or words in the image.`,
 		themeList, truncateStr(overview, 200),
 	)
+	// Themes and overview come from restored insight text, so mask them again
+	prompt = g.redactor.Redact(prompt, nil)
+
+	if err := g.ledger.Allow(ctx, usage.FeaturePicOfDay); err != nil {
+		return "", err
+	}
 
 	log.Printf("PicOfDay: generating with themes [%s]", themeList)
 
@@ -127,13 +144,17 @@ func (g *PicOfDayGenerator) Generate(ctx context.Context, themes []string, overv
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("gemini API error (status %d): %s", resp.StatusCode, string(respBytes))
+		err := fmt.Errorf("gemini API error (status %d): %s", resp.StatusCode, string(respBytes))
+		g.ledger.Record(ctx, usage.FeaturePicOfDay, "gemini", g.model, 0, 0, err)
+		return "", err
 	}
 
 	var gemResp geminiResponse
 	if err := json.Unmarshal(respBytes, &gemResp); err != nil {
 		return "", fmt.Errorf("parse response: %w", err)
 	}
+	g.ledger.Record(ctx, usage.FeaturePicOfDay, "gemini", g.model,
+		gemResp.UsageMetadata.PromptTokenCount, gemResp.UsageMetadata.CandidatesTokenCount, nil)
 
 	if gemResp.Error != nil {
 		return "", fmt.Errorf("gemini error: %s", gemResp.Error.Message)
@@ -0,0 +1,179 @@
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"signal-sideband/pkg/ai"
+	"signal-sideband/pkg/llm"
+)
+
+// Wrap redacts every request p sends and restores placeholders in its
+// replies, including streamed text. feature names the caller in audit logs.
+func (r *Redactor) Wrap(feature string, p llm.Provider) llm.Provider {
+	if r == nil || p == nil {
+		return p
+	}
+	return &redactedProvider{next: p, redactor: r, feature: feature}
+}
+
+type redactedProvider struct {
+	next     llm.Provider
+	redactor *Redactor
+	feature  string
+}
+
+func (p *redactedProvider) Name() string         { return p.next.Name() }
+func (p *redactedProvider) Unwrap() llm.Provider { return p.next }
+
+func (p *redactedProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	r := p.redactor
+	counts := make(map[string]int)
+	fields := r.redactRequest(&req, counts)
+	if r.audit {
+		log.Printf("redact audit: %s -> %s: sent %s; masked %s",
+			p.feature, p.next.Name(), strings.Join(fields, ", "), summary(counts))
+	}
+
+	var stream *streamRestorer
+	ctx = llm.MapStream(ctx, func(fn llm.StreamFunc) llm.StreamFunc {
+		stream = &streamRestorer{redactor: r, fn: fn}
+		return stream.write
+	}, func() { stream.pending = "" })
+
+	resp, err := p.next.Complete(ctx, req)
+	if stream != nil {
+		stream.flush()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON replies get their restored values escaped as string content
+	out := *resp
+	out.Content = r.restore(resp.Content, req.JSON)
+	if len(resp.ToolCalls) > 0 {
+		out.ToolCalls = make([]llm.ToolCall, len(resp.ToolCalls))
+		for i, tc := range resp.ToolCalls {
+			tc.Arguments = json.RawMessage(r.restore(string(tc.Arguments), true))
+			out.ToolCalls[i] = tc
+		}
+	}
+	return &out, nil
+}
+
+// redactRequest masks the text fields of req in place and describes what is
+// about to be sent, for the audit log. Images can't be masked and are
+// reported as sent as-is.
+func (r *Redactor) redactRequest(req *llm.CompletionRequest, counts map[string]int) []string {
+	var fields []string
+	field := func(name, text string) {
+		if text != "" {
+			fields = append(fields, fmt.Sprintf("%s (%d chars)", name, len(text)))
+		}
+	}
+
+	req.SystemPrompt = r.Redact(req.SystemPrompt, counts)
+	field("system_prompt", req.SystemPrompt)
+	req.UserPrompt = r.Redact(req.UserPrompt, counts)
+	field("user_prompt", req.UserPrompt)
+	if len(req.Images) > 0 {
+		fields = append(fields, fmt.Sprintf("images (%d, unredacted)", len(req.Images)))
+	}
+
+	if len(req.Messages) > 0 {
+		msgs := make([]llm.Message, len(req.Messages))
+		for i, m := range req.Messages {
+			m.Content = r.Redact(m.Content, counts)
+			field(fmt.Sprintf("messages[%d].%s", i, m.Role), m.Content)
+			if len(m.Images) > 0 {
+				fields = append(fields, fmt.Sprintf("messages[%d].images (%d, unredacted)", i, len(m.Images)))
+			}
+			if len(m.ToolCalls) > 0 {
+				calls := make([]llm.ToolCall, len(m.ToolCalls))
+				for j, tc := range m.ToolCalls {
+					tc.Arguments = json.RawMessage(r.Redact(string(tc.Arguments), counts))
+					calls[j] = tc
+				}
+				m.ToolCalls = calls
+				fields = append(fields, fmt.Sprintf("messages[%d].tool_calls (%d)", i, len(calls)))
+			}
+			msgs[i] = m
+		}
+		req.Messages = msgs
+	}
+	if len(req.Tools) > 0 {
+		fields = append(fields, fmt.Sprintf("tools (%d)", len(req.Tools)))
+	}
+	return fields
+}
+
+// streamRestorer restores placeholders in streamed text. A placeholder can
+// be split across deltas, so text from an unclosed "<" is held back until
+// the rest arrives.
+type streamRestorer struct {
+	redactor *Redactor
+	fn       llm.StreamFunc
+	pending  string
+}
+
+// maxPlaceholder bounds how much text is held back waiting for a ">".
+const maxPlaceholder = 80
+
+func (s *streamRestorer) write(delta string) {
+	s.pending += delta
+	emit := s.pending
+	if i := strings.LastIndexByte(s.pending, '<'); i >= 0 && !strings.Contains(s.pending[i:], ">") && len(s.pending)-i < maxPlaceholder {
+		emit, s.pending = s.pending[:i], s.pending[i:]
+	} else {
+		s.pending = ""
+	}
+	if emit != "" {
+		s.fn(s.redactor.Restore(emit))
+	}
+}
+
+func (s *streamRestorer) flush() {
+	if s.pending != "" {
+		s.fn(s.redactor.Restore(s.pending))
+		s.pending = ""
+	}
+}
+
+// Embedder masks text before it is sent for embedding. Nothing comes back to
+// restore; stable placeholders keep the vectors comparable.
+func (r *Redactor) Embedder(e ai.Embedder) ai.Embedder {
+	if r == nil || e == nil {
+		return e
+	}
+	return &redactedEmbedder{Embedder: e, redactor: r}
+}
+
+type redactedEmbedder struct {
+	ai.Embedder
+	redactor *Redactor
+}
+
+func (e *redactedEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	counts := make(map[string]int)
+	masked := e.redactor.Redact(text, counts)
+	if e.redactor.audit {
+		log.Printf("redact audit: embeddings -> %s: sent 1 text (%d chars); masked %s", e.Embedder.Model(), len(masked), summary(counts))
+	}
+	return e.Embedder.Embed(ctx, masked)
+}
+
+func (e *redactedEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	counts := make(map[string]int)
+	masked := make([]string, len(texts))
+	for i, t := range texts {
+		masked[i] = e.redactor.Redact(t, counts)
+	}
+	if e.redactor.audit {
+		log.Printf("redact audit: embeddings -> %s: sent %d texts; masked %s", e.Embedder.Model(), len(texts), summary(counts))
+	}
+	return e.Embedder.EmbedBatch(ctx, masked)
+}
@@ -0,0 +1,292 @@
+// Package redact masks personal details before text leaves for an external
+// model and restores them in what comes back.
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Pattern is one kind of PII. Matches are replaced by <LABEL_xxxxxx>
+// placeholders.
+type Pattern struct {
+	Name  string
+	Label string
+	re    *regexp.Regexp
+	// valid filters out matches that only look like PII (e.g. card numbers
+	// that fail the Luhn check).
+	valid func(string) bool
+}
+
+// builtinPatterns are the patterns REDACT_PATTERNS can name. Phone numbers
+// and Signal UUIDs identify senders, so they share the PERSON label.
+var builtinPatterns = []Pattern{
+	{Name: "email", Label: "EMAIL", re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{Name: "phone", Label: "PERSON", re: regexp.MustCompile(`\+\d{7,15}\b|\(?\b\d{3}\)?[ .-]\d{3}[ .-]\d{4}\b`)},
+	{Name: "uuid", Label: "PERSON", re: regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)},
+	{Name: "card", Label: "CARD", re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: luhn},
+	{Name: "address", Label: "ADDRESS", re: regexp.MustCompile(`(?i)\b\d{1,5}(?: [A-Za-z0-9.]+){1,4} (?:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|way|place|pl|terrace|parkway|pkwy)\b\.?`)},
+	{Name: "ip", Label: "IP", re: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
+}
+
+// DefaultPatterns are used when REDACT_PATTERNS is empty.
+var DefaultPatterns = []string{"email", "card", "phone", "uuid", "address"}
+
+// ParsePatterns resolves a comma-separated list of built-in pattern names
+// (empty means DefaultPatterns) plus custom patterns given as
+// "label=regex;label=regex".
+func ParsePatterns(names, custom string) ([]Pattern, error) {
+	var list []string
+	for _, n := range strings.Split(names, ",") {
+		if n = strings.ToLower(strings.TrimSpace(n)); n != "" {
+			list = append(list, n)
+		}
+	}
+	if len(list) == 0 {
+		list = DefaultPatterns
+	}
+
+	var patterns []Pattern
+	for _, n := range list {
+		found := false
+		for _, p := range builtinPatterns {
+			if p.Name == n {
+				patterns = append(patterns, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown redaction pattern %q", n)
+		}
+	}
+
+	for _, entry := range strings.Split(custom, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, expr, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || expr == "" {
+			return nil, fmt.Errorf("invalid custom redaction pattern %q (want label=regex)", entry)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("custom redaction pattern %q: %w", name, err)
+		}
+		patterns = append(patterns, Pattern{Name: name, Label: label(name), re: re})
+	}
+	return patterns, nil
+}
+
+// label upper-cases name and drops anything that can't appear in a
+// placeholder.
+func label(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "PII"
+	}
+	return b.String()
+}
+
+// maxTokens bounds the placeholders a Redactor remembers per generation.
+// When the current generation fills up it becomes the previous one and the
+// oldest is dropped, so a long-running process keeps at most twice this many
+// while replies to requests still in flight can be restored.
+const maxTokens = 10000
+
+// Redactor replaces PII with placeholders that are stable for a given value,
+// so the same person gets the same pseudonym in every request, and remembers
+// them so replies can be restored. A nil *Redactor is valid and leaves text
+// alone.
+type Redactor struct {
+	patterns []Pattern
+	key      []byte
+	audit    bool
+
+	mu         sync.RWMutex
+	aliases    map[string]string
+	limit      int
+	tokens     map[string]string // token -> original
+	values     map[string]string // original -> token
+	prevTokens map[string]string // previous generation of tokens
+	prevValues map[string]string
+}
+
+// New builds a Redactor. key seeds the pseudonyms (an HMAC key); without it
+// they are a plain hash, stable across installs but guessable for short
+// values like phone numbers. audit logs every outgoing request's fields.
+func New(patterns []Pattern, key string, audit bool) *Redactor {
+	return &Redactor{
+		patterns: patterns,
+		key:      []byte(key),
+		audit:    audit,
+		aliases:  make(map[string]string),
+		limit:    maxTokens,
+		tokens:   make(map[string]string),
+		values:   make(map[string]string),
+	}
+}
+
+// SetAliases maps sender identifiers (phone numbers, UUIDs) to display names
+// used instead of a pseudonym, replacing the previous set. Aliases are not
+// restored in replies.
+func (r *Redactor) SetAliases(aliases map[string]string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aliases = make(map[string]string, len(aliases))
+	for k, v := range aliases {
+		if k != "" && v != "" {
+			r.aliases[k] = v
+		}
+	}
+}
+
+// Redact masks text. counts, if non-nil, is incremented per label.
+func (r *Redactor) Redact(text string, counts map[string]int) string {
+	if r == nil || text == "" {
+		return text
+	}
+	for _, p := range r.patterns {
+		text = p.re.ReplaceAllStringFunc(text, func(m string) string {
+			if p.valid != nil && !p.valid(m) {
+				return m
+			}
+			if counts != nil {
+				counts[p.Label]++
+			}
+			r.mu.RLock()
+			alias := r.aliases[m]
+			r.mu.RUnlock()
+			if alias != "" {
+				return alias
+			}
+			return "<" + r.token(p.Label, m) + ">"
+		})
+	}
+	return text
+}
+
+// token returns the placeholder name for value, lengthening the hash on the
+// rare collision so every token restores to exactly one value.
+func (r *Redactor) token(label, value string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.values[value]; ok {
+		return t
+	}
+	if t, ok := r.prevValues[value]; ok {
+		r.remember(t, value)
+		return t
+	}
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	sum := hex.EncodeToString(mac.Sum(nil))
+	for n := 6; n <= len(sum); n += 2 {
+		t := label + "_" + sum[:n]
+		_, taken := r.tokens[t]
+		_, takenBefore := r.prevTokens[t]
+		if !taken && !takenBefore {
+			r.remember(t, value)
+			return t
+		}
+	}
+	return label + "_" + sum
+}
+
+// remember records token for value, starting a new generation once the
+// current one holds limit tokens. The caller holds r.mu.
+func (r *Redactor) remember(token, value string) {
+	if len(r.tokens) >= r.limit {
+		r.prevTokens, r.prevValues = r.tokens, r.values
+		r.tokens, r.values = make(map[string]string), make(map[string]string)
+	}
+	r.tokens[token] = value
+	r.values[value] = token
+}
+
+var placeholder = regexp.MustCompile(`<?([A-Z]+_[0-9a-f]{6,64})>?`)
+
+// Restore puts the original values back for any placeholders in text,
+// with or without the angle brackets models sometimes drop.
+func (r *Redactor) Restore(text string) string {
+	return r.restore(text, false)
+}
+
+func (r *Redactor) restore(text string, jsonString bool) string {
+	if r == nil || !strings.Contains(text, "_") {
+		return text
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return placeholder.ReplaceAllStringFunc(text, func(m string) string {
+		t := strings.TrimSuffix(strings.TrimPrefix(m, "<"), ">")
+		v, ok := r.tokens[t]
+		if !ok {
+			if v, ok = r.prevTokens[t]; !ok {
+				return m
+			}
+		}
+		if jsonString {
+			b, _ := json.Marshal(v)
+			v = string(b[1 : len(b)-1])
+		}
+		return v
+	})
+}
+
+// summary renders per-label counts as "EMAIL×1 PERSON×3".
+func summary(counts map[string]int) string {
+	if len(counts) == 0 {
+		return "none"
+	}
+	labels := make([]string, 0, len(counts))
+	for l := range counts {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+	parts := make([]string, len(labels))
+	for i, l := range labels {
+		parts[i] = fmt.Sprintf("%s×%d", l, counts[l])
+	}
+	return strings.Join(parts, " ")
+}
+
+// luhn reports whether the digits in s pass the card-number checksum.
+func luhn(s string) bool {
+	var digits []int
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, int(r-'0'))
+		}
+	}
+	if len(digits) < 13 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := digits[i]
+		if (len(digits)-1-i)%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
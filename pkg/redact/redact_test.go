@@ -0,0 +1,135 @@
+package redact
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"signal-sideband/pkg/llm"
+)
+
+func newTestRedactor(t *testing.T) *Redactor {
+	t.Helper()
+	patterns, err := ParsePatterns("", "codename=Project [A-Z][a-z]+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(patterns, "test-key", false)
+}
+
+func TestRedactRestore(t *testing.T) {
+	r := newTestRedactor(t)
+	in := "[15:04] +15551234567: mail me at jo@example.com about Project Falcon, card 4111 1111 1111 1111, order 1234 5678 9012 3456"
+
+	counts := make(map[string]int)
+	out := r.Redact(in, counts)
+	for _, leak := range []string{"+15551234567", "jo@example.com", "Falcon", "4111"} {
+		if strings.Contains(out, leak) {
+			t.Fatalf("%q leaked: %s", leak, out)
+		}
+	}
+	// Not a valid card number, so it stays
+	if !strings.Contains(out, "1234 5678 9012 3456") {
+		t.Fatalf("non-card number was masked: %s", out)
+	}
+	if counts["PERSON"] != 1 || counts["EMAIL"] != 1 || counts["CODENAME"] != 1 || counts["CARD"] != 1 {
+		t.Fatalf("counts = %v", counts)
+	}
+	if got := r.Restore(out); got != in {
+		t.Fatalf("restore = %q, want %q", got, in)
+	}
+
+	// Pseudonyms are stable, and models that drop the brackets still restore
+	again := r.Redact("+15551234567", nil)
+	if !strings.Contains(out, again) {
+		t.Fatalf("pseudonym changed: %s not in %s", again, out)
+	}
+	bare := strings.Trim(again, "<>")
+	if got := r.Restore(bare + " said hi"); got != "+15551234567 said hi" {
+		t.Fatalf("bare restore = %q", got)
+	}
+}
+
+func TestTokenGenerations(t *testing.T) {
+	r := newTestRedactor(t)
+	r.limit = 2
+
+	first := r.Redact("a@example.com", nil)
+	r.Redact("b@example.com", nil)
+	r.Redact("c@example.com", nil) // starts a new generation
+	if got := r.Restore(first); got != "a@example.com" {
+		t.Fatalf("previous generation not restored: %q", got)
+	}
+
+	r.Redact("d@example.com", nil)
+	r.Redact("e@example.com", nil) // drops the generation holding a@
+	if got := r.Restore(first); got != first {
+		t.Fatalf("dropped token still restored: %q", got)
+	}
+	if len(r.tokens)+len(r.prevTokens) > 2*r.limit {
+		t.Fatalf("remembered %d tokens", len(r.tokens)+len(r.prevTokens))
+	}
+	// Pseudonyms stay stable across generations
+	if again := r.Redact("a@example.com", nil); again != first {
+		t.Fatalf("pseudonym changed: %s, was %s", again, first)
+	}
+}
+
+func TestAliases(t *testing.T) {
+	r := newTestRedactor(t)
+	r.SetAliases(map[string]string{"+15551234567": "Jo"})
+	if got := r.Redact("[09:00] +15551234567: hi", nil); got != "[09:00] Jo: hi" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+type echoProvider struct {
+	got llm.CompletionRequest
+}
+
+func (e *echoProvider) Name() string { return "echo" }
+
+func (e *echoProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	e.got = req
+	return &llm.CompletionResponse{Content: req.UserPrompt}, nil
+}
+
+func TestWrapRestoresReply(t *testing.T) {
+	r := newTestRedactor(t)
+	echo := &echoProvider{}
+	p := r.Wrap("digest", echo)
+
+	prompt := "ask +15551234567 or jo@example.com"
+	var streamed strings.Builder
+	resp, err := llm.CompleteStream(context.Background(), p, llm.CompletionRequest{UserPrompt: prompt}, func(d string) {
+		streamed.WriteString(d)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(echo.got.UserPrompt, "+1555") || strings.Contains(echo.got.UserPrompt, "jo@") {
+		t.Fatalf("provider saw PII: %s", echo.got.UserPrompt)
+	}
+	if resp.Content != prompt {
+		t.Fatalf("content = %q, want %q", resp.Content, prompt)
+	}
+	if streamed.String() != prompt {
+		t.Fatalf("streamed = %q, want %q", streamed.String(), prompt)
+	}
+}
+
+func TestStreamRestorer(t *testing.T) {
+	r := newTestRedactor(t)
+	text := "call " + r.Redact("+15551234567", nil) + " now <3"
+
+	var out strings.Builder
+	s := &streamRestorer{redactor: r, fn: func(d string) { out.WriteString(d) }}
+	// Small deltas split the placeholder
+	for i := 0; i < len(text); i += 3 {
+		s.write(text[i:min(i+3, len(text))])
+	}
+	s.flush()
+	if want := "call +15551234567 now <3"; out.String() != want {
+		t.Fatalf("streamed = %q, want %q", out.String(), want)
+	}
+}
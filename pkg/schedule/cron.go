@@ -0,0 +1,187 @@
+// Package schedule runs background jobs on cron schedules in a fixed time
+// zone, remembering the last run of each job so restarts neither repeat nor
+// skip work.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed five-field cron expression: minute, hour, day of month,
+// month, day of week.
+type Cron struct {
+	minute [60]bool
+	hour   [24]bool
+	dom    [32]bool
+	month  [13]bool
+	dow    [7]bool
+	// When both day fields are restricted a day matches either, as in
+	// classic cron.
+	domAny, dowAny bool
+}
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var dayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// Parse reads a cron expression such as "0 0 * * *", "*/15 9-17 * * mon-fri"
+// or "@daily". Fields accept lists, ranges, steps and month/day names;
+// day of week 7 is Sunday.
+func Parse(expr string) (*Cron, error) {
+	spec := strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(spec)]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron %q: want 5 fields, got %d", expr, len(fields))
+	}
+
+	c := &Cron{domAny: strings.HasPrefix(fields[2], "*"), dowAny: strings.HasPrefix(fields[4], "*")}
+	var dow [8]bool
+	for _, f := range []struct {
+		name     string
+		text     string
+		set      []bool
+		min, max int
+		names    map[string]int
+	}{
+		{"minute", fields[0], c.minute[:], 0, 59, nil},
+		{"hour", fields[1], c.hour[:], 0, 23, nil},
+		{"day of month", fields[2], c.dom[:], 1, 31, nil},
+		{"month", fields[3], c.month[:], 1, 12, monthNames},
+		{"day of week", fields[4], dow[:], 0, 7, dayNames},
+	} {
+		if err := parseField(f.text, f.set, f.min, f.max, f.names); err != nil {
+			return nil, fmt.Errorf("cron %q: %s: %w", expr, f.name, err)
+		}
+	}
+	copy(c.dow[:], dow[:7])
+	if dow[7] {
+		c.dow[0] = true
+	}
+	return c, nil
+}
+
+// parseField marks the values a field matches in set.
+func parseField(text string, set []bool, min, max int, names map[string]int) error {
+	for _, item := range strings.Split(text, ",") {
+		rng, stepText, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step %q", stepText)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = fieldValue(a, names); err != nil {
+				return err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = fieldValue(b, names); err != nil {
+					return err
+				}
+			} else if hasStep {
+				hi = max // "5/15" means from 5 to the end in steps of 15
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("%q out of range %d-%d", item, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+func fieldValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
+
+// Next returns the first matching minute after t, in t's location. Times
+// that don't exist on a daylight-saving change are skipped, and a repeated
+// hour only matches once. It returns the zero time if nothing matches
+// within five years (e.g. "0 0 31 2 *").
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	after := wallClock(t)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case !c.month[t.Month()]:
+			t = forward(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		case !c.dayMatches(t):
+			t = forward(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		case !c.hour[t.Hour()]:
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case !c.minute[t.Minute()] || !wallClock(t).After(after):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// forward returns next, or one minute past t when next falls in a
+// daylight-saving gap and time.Date normalized it backwards.
+func forward(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Minute)
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom, dow := c.dom[t.Day()], c.dow[t.Weekday()]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	}
+	return dom || dow
+}
+
+// wallClock is t's local date and time with the zone stripped, so times in
+// a repeated daylight-saving hour compare by what the clock showed.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
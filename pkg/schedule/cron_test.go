@@ -0,0 +1,105 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@sometimes",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		t.Helper()
+		v, err := time.ParseInLocation("2006-01-02 15:04", s, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	cases := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"0 0 * * *", "2026-03-10 12:00", "2026-03-11 00:00"},
+		{"@daily", "2026-03-10 23:59", "2026-03-11 00:00"},
+		{"0 0 * * *", "2026-03-11 00:00", "2026-03-12 00:00"},
+		{"*/15 * * * *", "2026-03-10 12:07", "2026-03-10 12:15"},
+		{"5/20 * * * *", "2026-03-10 12:46", "2026-03-10 13:05"},
+		{"0 9-17/4 * * *", "2026-03-10 13:30", "2026-03-10 17:00"},
+		{"30 8 * * mon-fri", "2026-03-13 09:00", "2026-03-16 08:30"}, // Friday -> Monday
+		{"0 0 * * 7", "2026-03-10 00:00", "2026-03-15 00:00"},        // 7 is Sunday
+		{"0 0 1 jan,jul *", "2026-03-10 00:00", "2026-07-01 00:00"},
+		{"0 0 31 * *", "2026-04-01 00:00", "2026-05-31 00:00"},
+		{"@monthly", "2026-12-15 00:00", "2027-01-01 00:00"},
+		// Both day fields restricted: either matches
+		{"0 0 13 * fri", "2026-03-10 00:00", "2026-03-13 00:00"},
+		{"0 0 1 * sun", "2026-03-10 00:00", "2026-03-15 00:00"},
+		// 02:30 doesn't exist on 2026-03-08 in New York
+		{"30 2 * * *", "2026-03-07 12:00", "2026-03-09 02:30"},
+		{"0 3 * * *", "2026-03-08 00:00", "2026-03-08 03:00"},
+	}
+	for _, c := range cases {
+		cron, err := Parse(c.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", c.expr, err)
+		}
+		got := cron.Next(at(c.from))
+		if want := at(c.want); !got.Equal(want) {
+			t.Errorf("%q after %s = %s, want %s", c.expr, c.from, got.Format(time.RFC3339), want.Format(time.RFC3339))
+		}
+	}
+
+	// 01:30 happens twice on 2026-11-01 in New York; it matches once
+	cron, _ := Parse("30 1 * * *")
+	first := cron.Next(at("2026-11-01 00:00"))
+	if got := cron.Next(first); got.Day() != 2 {
+		t.Errorf("after the first 01:30 of a repeated hour, next = %s, want the next day", got.Format(time.RFC3339))
+	}
+
+	cron, _ = Parse("0 0 30 2 *")
+	if got := cron.Next(at("2026-01-01 00:00")); !got.IsZero() {
+		t.Errorf("February 30th matched %s", got)
+	}
+}
+
+func TestSkipAhead(t *testing.T) {
+	cron, _ := Parse("0 0 * * *")
+	now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+
+	last := time.Date(2026, 3, 18, 0, 0, 0, 0, time.UTC)
+	if got := skipAhead(cron, last, now, 5); !got.Equal(last) {
+		t.Errorf("within limit: skipAhead = %s, want %s", got, last)
+	}
+
+	// Ten missed days, keep the last three (18th, 19th, 20th)
+	last = time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 3, 17, 0, 0, 0, 0, time.UTC)
+	if got := skipAhead(cron, last, now, 3); !got.Equal(want) {
+		t.Errorf("over limit: skipAhead = %s, want %s", got, want)
+	}
+}
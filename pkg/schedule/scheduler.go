@@ -0,0 +1,206 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"signal-sideband/pkg/store"
+)
+
+// Off disables a job when given as its schedule.
+const Off = "off"
+
+// maxBackfill caps how many missed runs a Backfill job replays after a long
+// outage; older ones are skipped.
+const maxBackfill = 31
+
+// pollInterval bounds each wait so a suspended host or a clock change is
+// noticed within a minute.
+const pollInterval = time.Minute
+
+// A Backfill job's failed occurrence is retried after retryDelay, up to
+// maxAttempts runs in all, before the job moves on to the next one.
+const (
+	retryDelay  = 15 * time.Minute
+	maxAttempts = 4
+)
+
+// Job is a unit of scheduled work. Run gets the occurrence it runs for, in
+// the scheduler's time zone, which is in the past when catching up.
+type Job struct {
+	Name string
+	Spec string
+	// Backfill replays every run missed during downtime, oldest first (for
+	// jobs that cover a period, like daily digests), and retries a failed
+	// run, so Run must skip work it already did. Otherwise only the latest
+	// missed run is made up.
+	Backfill bool
+	Run      func(ctx context.Context, at time.Time) error
+}
+
+type entry struct {
+	Job
+	cron *Cron
+}
+
+type Scheduler struct {
+	store *store.Store
+	loc   *time.Location
+	jobs  []entry
+}
+
+func New(s *store.Store, loc *time.Location) *Scheduler {
+	if loc == nil {
+		loc = time.Local
+	}
+	return &Scheduler{store: s, loc: loc}
+}
+
+// Add registers j. A Spec of Off leaves the job disabled.
+func (s *Scheduler) Add(j Job) error {
+	if strings.EqualFold(strings.TrimSpace(j.Spec), Off) {
+		log.Printf("Scheduler: %s disabled", j.Name)
+		return nil
+	}
+	c, err := Parse(j.Spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", j.Name, err)
+	}
+	s.jobs = append(s.jobs, entry{Job: j, cron: c})
+	return nil
+}
+
+// Start runs every job on its schedule until ctx is cancelled. Jobs run
+// independently, so a slow one doesn't hold up the rest.
+func (s *Scheduler) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, e := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, e)
+		}()
+	}
+	log.Printf("Scheduler started (%d jobs, %s)", len(s.jobs), s.loc)
+	wg.Wait()
+	log.Println("Scheduler stopped")
+}
+
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	last := s.lastRun(ctx, e)
+	attempt := 0
+	for {
+		next := e.cron.Next(last)
+		if next.IsZero() {
+			log.Printf("Scheduler: %s never matches %q, not running it", e.Name, e.Spec)
+			return
+		}
+		log.Printf("Scheduler: %s next runs %s", e.Name, next.Format(time.RFC3339))
+
+		for wait := time.Until(next); wait > 0; wait = time.Until(next) {
+			timer := time.NewTimer(min(wait, pollInterval))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		if !e.Backfill {
+			now := time.Now()
+			for n := e.cron.Next(next); !n.IsZero() && !n.After(now); n = e.cron.Next(n) {
+				next = n
+			}
+		}
+		attempt++
+		retry := e.Backfill && attempt < maxAttempts
+		if err := s.execute(ctx, e, next, last, retry); err != nil && retry && ctx.Err() == nil {
+			log.Printf("Scheduler: retrying %s for %s in %s (attempt %d of %d failed)", e.Name, next.Format(time.RFC3339), retryDelay, attempt, maxAttempts)
+			timer := time.NewTimer(retryDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+		attempt = 0
+		last = next
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// lastRun is the occurrence a job last ran for. A job that never ran starts
+// from now rather than running immediately.
+func (s *Scheduler) lastRun(ctx context.Context, e entry) time.Time {
+	now := time.Now().In(s.loc)
+	run, err := s.store.GetScheduleRun(ctx, e.Name)
+	if err != nil {
+		log.Printf("Scheduler: %s: failed to load last run, starting from now: %v", e.Name, err)
+		return now
+	}
+	if run == nil {
+		return now
+	}
+
+	last := run.ScheduledFor.In(s.loc)
+	if e.Backfill {
+		last = skipAhead(e.cron, last, now, maxBackfill)
+	}
+	if next := e.cron.Next(last); !next.IsZero() && !next.After(now) {
+		log.Printf("Scheduler: %s missed runs since %s, catching up", e.Name, last.Format(time.RFC3339))
+	}
+	return last
+}
+
+// skipAhead moves last forward so that at most limit occurrences remain
+// between it and now.
+func skipAhead(c *Cron, last, now time.Time, limit int) time.Time {
+	var missed []time.Time
+	for n := c.Next(last); !n.IsZero() && !n.After(now); n = c.Next(n) {
+		missed = append(missed, n)
+	}
+	if len(missed) <= limit {
+		return last
+	}
+	log.Printf("Scheduler: skipping %d missed runs older than %s", len(missed)-limit, missed[len(missed)-limit].Format(time.RFC3339))
+	return missed[len(missed)-limit-1]
+}
+
+// execute runs one occurrence and records it. A failure is recorded too.
+// Unless it will be retried, it counts as the occurrence's run, so a broken
+// job can't run in a tight loop and the next occurrence tries again. One that
+// will be retried is recorded against prev, the occurrence before, so a
+// restart in the meantime still makes it up.
+func (s *Scheduler) execute(ctx context.Context, e entry, at, prev time.Time, retry bool) error {
+	run := store.ScheduleRun{Job: e.Name, ScheduledFor: at, StartedAt: time.Now()}
+	log.Printf("Scheduler: running %s for %s", e.Name, at.Format(time.RFC3339))
+
+	err := e.Run(ctx, at)
+	run.FinishedAt = time.Now()
+	if err != nil {
+		if ctx.Err() != nil {
+			return err // shutting down; run it again after the restart
+		}
+		run.LastError = err.Error()
+		if retry {
+			run.ScheduledFor = prev
+		}
+		log.Printf("Scheduler: %s failed: %v", e.Name, err)
+	} else {
+		log.Printf("Scheduler: %s done in %s", e.Name, run.FinishedAt.Sub(run.StartedAt).Round(time.Second))
+	}
+
+	if err := s.store.SaveScheduleRun(ctx, run); err != nil {
+		log.Printf("Scheduler: %s: failed to save run: %v", e.Name, err)
+	}
+	return err
+}
@@ -2,9 +2,12 @@ package signal
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -36,12 +39,37 @@ type GroupDetail struct {
 	PermissionStr string   `json:"permission_add_member"`
 }
 
+// ErrNoAvatar is returned when a contact or group has no avatar set.
+var ErrNoAvatar = errors.New("no avatar")
+
 type ContactDetail struct {
-	Number      string `json:"number"`
-	UUID        string `json:"uuid"`
-	ProfileName string `json:"name"`
-	Color       string `json:"color"`
-	Blocked     bool   `json:"blocked"`
+	Number      string         `json:"number"`
+	UUID        string         `json:"uuid"`
+	Name        string         `json:"name"`
+	ProfileName string         `json:"profile_name"`
+	Color       string         `json:"color"`
+	Blocked     bool           `json:"blocked"`
+	Profile     ContactProfile `json:"profile"`
+}
+
+type ContactProfile struct {
+	GivenName            string `json:"given_name"`
+	Lastname             string `json:"lastname"`
+	About                string `json:"about"`
+	HasAvatar            bool   `json:"has_avatar"`
+	LastUpdatedTimestamp int64  `json:"last_updated_timestamp"`
+}
+
+// DisplayName is the name the contact set on their Signal profile, falling
+// back to the name saved for them in the address book.
+func (c ContactDetail) DisplayName() string {
+	if name := strings.TrimSpace(c.Profile.GivenName + " " + c.Profile.Lastname); name != "" {
+		return name
+	}
+	if c.ProfileName != "" {
+		return c.ProfileName
+	}
+	return c.Name
 }
 
 func (a *APIClient) ListGroups() ([]GroupDetail, error) {
@@ -104,6 +132,35 @@ func (a *APIClient) ListContacts() ([]ContactDetail, error) {
 	return contacts, nil
 }
 
+// ContactAvatar fetches a contact's profile picture.
+func (a *APIClient) ContactAvatar(uuid string) (io.ReadCloser, string, error) {
+	return a.avatar(fmt.Sprintf("%s/v1/contacts/%s/%s/avatar", a.baseURL, a.number, url.PathEscape(uuid)))
+}
+
+// GroupAvatar fetches a group's picture. groupID is the API's "group.…" ID.
+func (a *APIClient) GroupAvatar(groupID string) (io.ReadCloser, string, error) {
+	return a.avatar(fmt.Sprintf("%s/v1/groups/%s/%s/avatar", a.baseURL, a.number, url.PathEscape(groupID)))
+}
+
+func (a *APIClient) avatar(u string) (io.ReadCloser, string, error) {
+	resp, err := a.client.Get(u)
+	if err != nil {
+		return nil, "", fmt.Errorf("get avatar: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNoContent {
+		resp.Body.Close()
+		return nil, "", ErrNoAvatar
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("get avatar: status %d: %s", resp.StatusCode, body)
+	}
+
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
 func (a *APIClient) DownloadAttachment(attachmentID string) (io.ReadCloser, string, error) {
 	url := fmt.Sprintf("%s/v1/attachments/%s", a.baseURL, attachmentID)
 	resp, err := a.client.Get(url)
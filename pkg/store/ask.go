@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+)
+
+func (s *Store) CreateAskSession(ctx context.Context, title string, groupID *string) (*AskSession, error) {
+	var a AskSession
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO ask_sessions (title, group_id)
+		VALUES ($1, $2)
+		RETURNING id, title, group_id, created_at, updated_at
+	`, title, groupID).Scan(&a.ID, &a.Title, &a.GroupID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListAskSessions returns the most recently active sessions, without turns.
+func (s *Store) ListAskSessions(ctx context.Context, limit int) ([]AskSession, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, title, group_id, created_at, updated_at
+		FROM ask_sessions
+		ORDER BY updated_at DESC
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []AskSession
+	for rows.Next() {
+		var a AskSession
+		if err := rows.Scan(&a.ID, &a.Title, &a.GroupID, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, a)
+	}
+	return sessions, rows.Err()
+}
+
+// GetAskSession returns a session with its turns, oldest first.
+func (s *Store) GetAskSession(ctx context.Context, id string) (*AskSession, error) {
+	var a AskSession
+	err := s.pool.QueryRow(ctx, `
+		SELECT id, title, group_id, created_at, updated_at
+		FROM ask_sessions WHERE id = $1
+	`, id).Scan(&a.ID, &a.Title, &a.GroupID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT id, session_id, question, answer, citations, llm_provider, llm_model, token_count, created_at
+		FROM ask_turns
+		WHERE session_id = $1
+		ORDER BY created_at
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t AskTurn
+		if err := rows.Scan(&t.ID, &t.SessionID, &t.Question, &t.Answer, &t.Citations,
+			&t.LLMProvider, &t.LLMModel, &t.TokenCount, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		a.Turns = append(a.Turns, t)
+	}
+	return &a, rows.Err()
+}
+
+// SaveAskTurn appends a turn to its session and marks the session active.
+func (s *Store) SaveAskTurn(ctx context.Context, t AskTurn) (string, error) {
+	if t.Citations == nil {
+		t.Citations = []byte("[]")
+	}
+
+	var id string
+	err := s.pool.QueryRow(ctx, `
+		INSERT INTO ask_turns (session_id, question, answer, citations, llm_provider, llm_model, token_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, t.SessionID, t.Question, t.Answer, t.Citations, t.LLMProvider, t.LLMModel, t.TokenCount).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.pool.Exec(ctx, `UPDATE ask_sessions SET updated_at = now() WHERE id = $1`, t.SessionID)
+	return id, err
+}
+
+func (s *Store) DeleteAskSession(ctx context.Context, id string) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM ask_sessions WHERE id = $1`, id)
+	return err
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+
+	pgvector "github.com/pgvector/pgvector-go"
 )
 
 const attachmentCols = `id, message_id, signal_attachment_id, content_type, COALESCE(filename,''), size,
@@ -221,3 +223,40 @@ func (s *Store) SearchMedia(ctx context.Context, query string, limit int) ([]Med
 	}
 	return results, nil
 }
+
+// SemanticSearchMedia ranks analyzed attachments by cosine similarity between
+// embedding and their analysis embedding. Rank holds the similarity.
+func (s *Store) SemanticSearchMedia(ctx context.Context, embedding []float32, threshold float64, limit int) ([]MediaSearchResult, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	sqlQuery := fmt.Sprintf(`
+		SELECT %s, 1 - (embedding <=> $1) AS rank
+		FROM attachments
+		WHERE embedding IS NOT NULL AND embedding_model = $2
+		AND 1 - (embedding <=> $1) > $3
+		ORDER BY embedding <=> $1
+		LIMIT $4
+	`, attachmentCols)
+
+	rows, err := s.pool.Query(ctx, sqlQuery, pgvector.NewVector(embedding), s.embeddingModel, threshold, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []MediaSearchResult
+	for rows.Next() {
+		var r MediaSearchResult
+		err := rows.Scan(
+			&r.ID, &r.MessageID, &r.SignalAttachmentID, &r.ContentType, &r.Filename, &r.Size,
+			&r.LocalPath, &r.Downloaded, &r.ThumbnailPath, &r.Analyzed, &r.Analysis, &r.CreatedAt,
+			&r.Rank,
+		)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
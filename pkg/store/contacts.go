@@ -4,28 +4,32 @@ import (
 	"context"
 )
 
+// UpsertContact saves what signal-cli knows about a contact. The alias is
+// only set on insert; existing aliases are kept.
 func (s *Store) UpsertContact(ctx context.Context, c ContactRecord) error {
 	query := `
-		INSERT INTO contacts (source_uuid, phone_number, profile_name, alias, avatar_path)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO contacts (source_uuid, phone_number, profile_name, alias, avatar_path, avatar_updated_ms)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT (source_uuid) DO UPDATE SET
 			phone_number = EXCLUDED.phone_number,
 			profile_name = EXCLUDED.profile_name,
 			avatar_path = EXCLUDED.avatar_path,
+			avatar_updated_ms = EXCLUDED.avatar_updated_ms,
 			updated_at = now()
 	`
-	_, err := s.pool.Exec(ctx, query, c.SourceUUID, c.PhoneNumber, c.ProfileName, c.Alias, c.AvatarPath)
+	_, err := s.pool.Exec(ctx, query, c.SourceUUID, c.PhoneNumber, c.ProfileName, c.Alias, c.AvatarPath, c.AvatarUpdatedMs)
 	return err
 }
 
 func (s *Store) GetContactByUUID(ctx context.Context, uuid string) (*ContactRecord, error) {
 	query := `
-		SELECT id, source_uuid, phone_number, profile_name, COALESCE(alias, ''), avatar_path, created_at, updated_at
+		SELECT id, source_uuid, COALESCE(phone_number, ''), COALESCE(profile_name, ''), COALESCE(alias, ''),
+			COALESCE(avatar_path, ''), COALESCE(avatar_updated_ms, 0), created_at, updated_at
 		FROM contacts WHERE source_uuid = $1
 	`
 	var c ContactRecord
 	err := s.pool.QueryRow(ctx, query, uuid).Scan(
-		&c.ID, &c.SourceUUID, &c.PhoneNumber, &c.ProfileName, &c.Alias, &c.AvatarPath,
+		&c.ID, &c.SourceUUID, &c.PhoneNumber, &c.ProfileName, &c.Alias, &c.AvatarPath, &c.AvatarUpdatedMs,
 		&c.CreatedAt, &c.UpdatedAt,
 	)
 	if err != nil {
@@ -36,7 +40,8 @@ func (s *Store) GetContactByUUID(ctx context.Context, uuid string) (*ContactReco
 
 func (s *Store) ListContacts(ctx context.Context) ([]ContactRecord, error) {
 	query := `
-		SELECT id, source_uuid, phone_number, profile_name, COALESCE(alias, ''), avatar_path, created_at, updated_at
+		SELECT id, source_uuid, COALESCE(phone_number, ''), COALESCE(profile_name, ''), COALESCE(alias, ''),
+			COALESCE(avatar_path, ''), COALESCE(avatar_updated_ms, 0), created_at, updated_at
 		FROM contacts ORDER BY COALESCE(NULLIF(alias, ''), profile_name) ASC
 	`
 	rows, err := s.pool.Query(ctx, query)
@@ -49,7 +54,7 @@ func (s *Store) ListContacts(ctx context.Context) ([]ContactRecord, error) {
 	for rows.Next() {
 		var c ContactRecord
 		if err := rows.Scan(
-			&c.ID, &c.SourceUUID, &c.PhoneNumber, &c.ProfileName, &c.Alias, &c.AvatarPath,
+			&c.ID, &c.SourceUUID, &c.PhoneNumber, &c.ProfileName, &c.Alias, &c.AvatarPath, &c.AvatarUpdatedMs,
 			&c.CreatedAt, &c.UpdatedAt,
 		); err != nil {
 			return nil, err
@@ -59,6 +64,26 @@ func (s *Store) ListContacts(ctx context.Context) ([]ContactRecord, error) {
 	return contacts, nil
 }
 
+// ContactAliases maps the phone number and UUID of every contact with an
+// alias to that alias.
+func (s *Store) ContactAliases(ctx context.Context) (map[string]string, error) {
+	contacts, err := s.ListContacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]string)
+	for _, c := range contacts {
+		if c.Alias == "" {
+			continue
+		}
+		if c.PhoneNumber != "" {
+			aliases[c.PhoneNumber] = c.Alias
+		}
+		aliases[c.SourceUUID] = c.Alias
+	}
+	return aliases, nil
+}
+
 func (s *Store) UpdateContactAlias(ctx context.Context, uuid, alias string) error {
 	query := `
 		INSERT INTO contacts (source_uuid, alias)
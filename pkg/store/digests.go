@@ -2,41 +2,75 @@ package store
 
 import (
 	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Digest period kinds. Weekly and monthly digests are rolled up from the
+// daily ones; custom covers any range requested through the API.
+const (
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+	PeriodCustom  = "custom"
 )
 
+const digestColumns = `id, title, summary, topics, decisions, action_items,
+	period_start, period_end, period_kind, group_id, llm_provider, llm_model, token_count, created_at`
+
+func scanDigest(row pgx.Row) (*DigestRecord, error) {
+	var d DigestRecord
+	if err := row.Scan(
+		&d.ID, &d.Title, &d.Summary, &d.Topics, &d.Decisions, &d.ActionItems,
+		&d.PeriodStart, &d.PeriodEnd, &d.PeriodKind, &d.GroupID, &d.LLMProvider, &d.LLMModel,
+		&d.TokenCount, &d.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
 func (s *Store) SaveDigest(ctx context.Context, d DigestRecord) (string, error) {
+	if d.PeriodKind == "" {
+		d.PeriodKind = PeriodCustom
+	}
 	query := `
 		INSERT INTO digests (title, summary, topics, decisions, action_items,
-			period_start, period_end, group_id, llm_provider, llm_model, token_count)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			period_start, period_end, period_kind, group_id, llm_provider, llm_model, token_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id
 	`
 	var id string
 	err := s.pool.QueryRow(ctx, query,
 		d.Title, d.Summary, d.Topics, d.Decisions, d.ActionItems,
-		d.PeriodStart, d.PeriodEnd, d.GroupID, d.LLMProvider, d.LLMModel, d.TokenCount,
+		d.PeriodStart, d.PeriodEnd, d.PeriodKind, d.GroupID, d.LLMProvider, d.LLMModel, d.TokenCount,
 	).Scan(&id)
 	return id, err
 }
 
-func (s *Store) ListDigests(ctx context.Context, limit, offset int) ([]DigestRecord, int, error) {
+// ListDigests returns digests newest first, only those of kind and of the
+// group when they are set.
+func (s *Store) ListDigests(ctx context.Context, kind string, groupID *string, limit, offset int) ([]DigestRecord, int, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 
 	var total int
-	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM digests").Scan(&total); err != nil {
+	where := "($1 = '' OR period_kind = $1) AND ($2::text IS NULL OR group_id = $2)"
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM digests WHERE "+where, kind, groupID).Scan(&total); err != nil {
 		return nil, 0, err
 	}
 
-	query := `
-		SELECT id, title, summary, topics, decisions, action_items,
-			period_start, period_end, group_id, llm_provider, llm_model, token_count, created_at
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM digests
+		WHERE %s
 		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`
-	rows, err := s.pool.Query(ctx, query, limit, offset)
+		LIMIT $3 OFFSET $4
+	`, digestColumns, where)
+	rows, err := s.pool.Query(ctx, query, kind, groupID, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -44,33 +78,59 @@ func (s *Store) ListDigests(ctx context.Context, limit, offset int) ([]DigestRec
 
 	var digests []DigestRecord
 	for rows.Next() {
-		var d DigestRecord
-		if err := rows.Scan(
-			&d.ID, &d.Title, &d.Summary, &d.Topics, &d.Decisions, &d.ActionItems,
-			&d.PeriodStart, &d.PeriodEnd, &d.GroupID, &d.LLMProvider, &d.LLMModel,
-			&d.TokenCount, &d.CreatedAt,
-		); err != nil {
+		d, err := scanDigest(rows)
+		if err != nil {
 			return nil, 0, err
 		}
-		digests = append(digests, d)
+		digests = append(digests, *d)
 	}
-	return digests, total, nil
+	return digests, total, rows.Err()
 }
 
-func (s *Store) GetDigest(ctx context.Context, id string) (*DigestRecord, error) {
-	query := `
-		SELECT id, title, summary, topics, decisions, action_items,
-			period_start, period_end, group_id, llm_provider, llm_model, token_count, created_at
-		FROM digests WHERE id = $1
-	`
-	var d DigestRecord
-	err := s.pool.QueryRow(ctx, query, id).Scan(
-		&d.ID, &d.Title, &d.Summary, &d.Topics, &d.Decisions, &d.ActionItems,
-		&d.PeriodStart, &d.PeriodEnd, &d.GroupID, &d.LLMProvider, &d.LLMModel,
-		&d.TokenCount, &d.CreatedAt,
-	)
+// DigestsInPeriod returns the digests of kind that start within [start, end)
+// for the group (all groups when nil), oldest first. A period digested more
+// than once keeps only its latest digest.
+func (s *Store) DigestsInPeriod(ctx context.Context, kind string, start, end time.Time, groupID *string) ([]DigestRecord, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM (
+			SELECT DISTINCT ON (period_start) *
+			FROM digests
+			WHERE period_kind = $1 AND period_start >= $2 AND period_start < $3
+			  AND group_id IS NOT DISTINCT FROM $4
+			ORDER BY period_start, created_at DESC
+		) d
+		ORDER BY period_start
+	`, digestColumns)
+	rows, err := s.pool.Query(ctx, query, kind, start, end, groupID)
 	if err != nil {
 		return nil, err
 	}
-	return &d, nil
+	defer rows.Close()
+
+	var digests []DigestRecord
+	for rows.Next() {
+		d, err := scanDigest(rows)
+		if err != nil {
+			return nil, err
+		}
+		digests = append(digests, *d)
+	}
+	return digests, rows.Err()
+}
+
+// HasDigest reports whether the group (all groups when nil) has a digest of
+// kind for the period starting at start.
+func (s *Store) HasDigest(ctx context.Context, kind string, start time.Time, groupID *string) (bool, error) {
+	var exists bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM digests
+			WHERE period_kind = $1 AND period_start = $2 AND group_id IS NOT DISTINCT FROM $3
+		)
+	`, kind, start, groupID).Scan(&exists)
+	return exists, err
+}
+
+func (s *Store) GetDigest(ctx context.Context, id string) (*DigestRecord, error) {
+	return scanDigest(s.pool.QueryRow(ctx, "SELECT "+digestColumns+" FROM digests WHERE id = $1", id))
 }
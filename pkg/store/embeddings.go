@@ -0,0 +1,177 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	pgvector "github.com/pgvector/pgvector-go"
+)
+
+// Embedding kinds: each names a table whose rows carry an embedding.
+const (
+	EmbedMessages    = "messages"
+	EmbedAttachments = "attachments"
+	EmbedURLs        = "urls"
+)
+
+// EmbeddingKinds lists every kind the backfill worker maintains.
+var EmbeddingKinds = []string{EmbedMessages, EmbedAttachments, EmbedURLs}
+
+// embeddingSource describes how to build the embedded text for a kind and
+// which rows are eligible.
+type embeddingSource struct {
+	text  string
+	where string
+}
+
+var embeddingSources = map[string]embeddingSource{
+	EmbedMessages: {
+		text:  "content",
+		where: "content != '' AND (expires_at IS NULL OR expires_at > now())",
+	},
+	// Description, objects, OCR text and scene from the vision analysis
+	EmbedAttachments: {
+		text: `concat_ws(E'\n', NULLIF(analysis->>'description', ''), NULLIF(analysis->>'objects', ''),
+			NULLIF(analysis->>'text_content', ''), NULLIF(analysis->>'scene', ''))`,
+		where: "analyzed = true AND analysis IS NOT NULL",
+	},
+	EmbedURLs: {
+		text:  `concat_ws(E'\n', NULLIF(title, ''), NULLIF(description, ''), url)`,
+		where: "fetched = true AND (COALESCE(title, '') != '' OR COALESCE(description, '') != '')",
+	},
+}
+
+// EmbeddingJob is a row whose embedding is missing or was produced by a
+// different model than the one currently configured.
+type EmbeddingJob struct {
+	ID      string
+	Content string
+}
+
+// SetEmbeddingModel sets the model semantic search compares against. Vectors
+// from other models live in a different space and are ignored until re-embedded.
+func (s *Store) SetEmbeddingModel(model string) {
+	s.embeddingModel = model
+}
+
+// EmbeddingModel returns the configured embedding model.
+func (s *Store) EmbeddingModel() string {
+	return s.embeddingModel
+}
+
+// GetEmbeddingJobs returns rows of kind with no embedding, a zero embedding,
+// or one produced by a model other than model, newest first. Rows model
+// rejected are skipped.
+func (s *Store) GetEmbeddingJobs(ctx context.Context, kind, model string, limit int) ([]EmbeddingJob, error) {
+	src, ok := embeddingSources[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding kind: %s", kind)
+	}
+	query := fmt.Sprintf(`
+		SELECT id, text FROM (
+			SELECT id, %s AS text, created_at FROM %s
+			WHERE %s
+			AND (embedding IS NULL OR embedding_model IS DISTINCT FROM $1 OR vector_norm(embedding) = 0)
+			AND embedding_rejected_model IS DISTINCT FROM $1
+		) j
+		WHERE text != ''
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, src.text, kind, src.where)
+	rows, err := s.pool.Query(ctx, query, model, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []EmbeddingJob
+	for rows.Next() {
+		var j EmbeddingJob
+		if err := rows.Scan(&j.ID, &j.Content); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// SetEmbedding stores a row's embedding along with its model and dimension.
+func (s *Store) SetEmbedding(ctx context.Context, kind, id string, embedding []float32, model string) error {
+	if _, ok := embeddingSources[kind]; !ok {
+		return fmt.Errorf("unknown embedding kind: %s", kind)
+	}
+	vec := embeddingVector(embedding)
+	dim := len(embedding)
+	if vec == nil {
+		model, dim = "", 0
+	}
+	query := fmt.Sprintf(`
+		UPDATE %s SET embedding = $2, embedding_model = NULLIF($3, ''), embedding_dim = NULLIF($4, 0)
+		WHERE id = $1
+	`, kind)
+	_, err := s.pool.Exec(ctx, query, id, vec, model, dim)
+	return err
+}
+
+// RejectEmbedding records that model refused to embed a row so the backfill
+// skips it until the model changes.
+func (s *Store) RejectEmbedding(ctx context.Context, kind, id, model string) error {
+	if _, ok := embeddingSources[kind]; !ok {
+		return fmt.Errorf("unknown embedding kind: %s", kind)
+	}
+	query := fmt.Sprintf(`UPDATE %s SET embedding_rejected_model = $2 WHERE id = $1`, kind)
+	_, err := s.pool.Exec(ctx, query, id, model)
+	return err
+}
+
+// CountNeedingEmbedding reports how much backfill work remains for kind.
+func (s *Store) CountNeedingEmbedding(ctx context.Context, kind, model string) (int, error) {
+	src, ok := embeddingSources[kind]
+	if !ok {
+		return 0, fmt.Errorf("unknown embedding kind: %s", kind)
+	}
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) FROM %s
+		WHERE %s AND %s != ''
+		AND (embedding IS NULL OR embedding_model IS DISTINCT FROM $1 OR vector_norm(embedding) = 0)
+		AND embedding_rejected_model IS DISTINCT FROM $1
+	`, kind, src.where, src.text)
+	var n int
+	err := s.pool.QueryRow(ctx, query, model).Scan(&n)
+	return n, err
+}
+
+// embeddingVector wraps an embedding for pgvector, returning nil for empty or
+// all-zero vectors so placeholders are never stored.
+func embeddingVector(embedding []float32) *pgvector.Vector {
+	nonZero := false
+	for _, f := range embedding {
+		if f != 0 {
+			nonZero = true
+			break
+		}
+	}
+	if !nonZero {
+		return nil
+	}
+	v := pgvector.NewVector(embedding)
+	return &v
+}
+
+// EmbeddingColumnDimensions returns the declared size of each kind's
+// embedding column (the n in vector(n)), or 0 where it is unconstrained.
+func (s *Store) EmbeddingColumnDimensions(ctx context.Context) (map[string]int, error) {
+	dims := make(map[string]int, len(EmbeddingKinds))
+	for _, kind := range EmbeddingKinds {
+		var typmod int
+		err := s.pool.QueryRow(ctx, `
+			SELECT atttypmod FROM pg_attribute
+			WHERE attrelid = $1::regclass AND attname = 'embedding' AND NOT attisdropped
+		`, kind).Scan(&typmod)
+		if err != nil {
+			return nil, fmt.Errorf("%s.embedding: %w", kind, err)
+		}
+		dims[kind] = max(typmod, 0)
+	}
+	return dims, nil
+}
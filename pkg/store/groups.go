@@ -2,6 +2,9 @@ package store
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"time"
 )
 
 func (s *Store) UpsertGroup(ctx context.Context, g GroupRecord) error {
@@ -54,6 +57,41 @@ func (s *Store) ListGroups(ctx context.Context) ([]GroupWithCount, error) {
 	return groups, nil
 }
 
+// DirectGroupID is the pseudo group that 1:1 messages (group_id IS NULL)
+// belong to when results are scoped to a group. Scheduled digests and
+// insights cover direct messages as one more "group", saved under this ID,
+// rather than folding them into every group's run or dropping them. Group
+// filters on messages match it against group_id IS NULL, passing it as a
+// parameter so the plain group_id index still serves real groups.
+const DirectGroupID = "direct"
+
+// ActiveGroupIDs lists the groups with messages in [start, end), busiest
+// first, with DirectGroupID standing in for 1:1 messages. Scheduled digests
+// and insights run once for each.
+func (s *Store) ActiveGroupIDs(ctx context.Context, start, end time.Time) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT COALESCE(group_id, $3) AS gid FROM messages
+		WHERE created_at >= $1 AND created_at < $2
+		AND (expires_at IS NULL OR expires_at > now())
+		GROUP BY gid
+		ORDER BY COUNT(*) DESC
+	`, start, end, DirectGroupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func (s *Store) GetGroupByID(ctx context.Context, groupID string) (*GroupRecord, error) {
 	query := `
 		SELECT id, group_id, name, description, avatar_path, member_count, created_at, updated_at
@@ -69,3 +107,118 @@ func (s *Store) GetGroupByID(ctx context.Context, groupID string) (*GroupRecord,
 	}
 	return &g, nil
 }
+
+// EnsureGroup records a group seen in a message without touching the details
+// the group sync keeps.
+func (s *Store) EnsureGroup(ctx context.Context, groupID string) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO groups (group_id, name, description, avatar_path)
+		VALUES ($1, '', '', '')
+		ON CONFLICT (group_id) DO NOTHING
+	`, groupID)
+	return err
+}
+
+// SyncGroupMembers records the current member list of a group. New members
+// and returning ones get joined_at = now(); members missing from the list
+// get left_at = now().
+func (s *Store) SyncGroupMembers(ctx context.Context, groupID string, members []GroupMember) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	current := make([]string, len(members))
+	for i, m := range members {
+		current[i] = m.MemberID
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO group_members (group_id, member_id, person_id, is_admin)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (group_id, member_id) DO UPDATE SET
+				person_id = COALESCE(EXCLUDED.person_id, group_members.person_id),
+				is_admin = EXCLUDED.is_admin,
+				joined_at = CASE WHEN group_members.left_at IS NULL THEN group_members.joined_at ELSE now() END,
+				left_at = NULL,
+				updated_at = now()
+		`, groupID, m.MemberID, m.PersonID, m.IsAdmin); err != nil {
+			return fmt.Errorf("upsert member: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE group_members SET left_at = now(), is_admin = false, updated_at = now()
+		WHERE group_id = $1 AND left_at IS NULL AND NOT (member_id = ANY($2))
+	`, groupID, current); err != nil {
+		return fmt.Errorf("mark departed members: %w", err)
+	}
+	return tx.Commit(ctx)
+}
+
+// GetGroupDetail returns a group with its members, current ones first. Each
+// member is matched to a contact by number, UUID or any identifier of their
+// person, so members who never post are still named.
+func (s *Store) GetGroupDetail(ctx context.Context, groupID string) (*GroupDetail, error) {
+	g, err := s.GetGroupByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	detail := &GroupDetail{GroupRecord: *g, Members: []GroupMember{}}
+	if err := s.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM messages WHERE group_id = $1
+	`, groupID).Scan(&detail.MessageCount); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT gm.member_id, gm.person_id::text, gm.is_admin, gm.joined_at, gm.left_at,
+			COALESCE(c.source_uuid, ''), COALESCE(c.phone_number, ''), COALESCE(c.profile_name, ''),
+			COALESCE(c.alias, ''), COALESCE(c.avatar_path, ''),
+			(SELECT COUNT(*) FROM messages m
+				WHERE m.group_id = gm.group_id
+				AND (m.person_id = gm.person_id OR m.sender_id = gm.member_id))::int
+		FROM group_members gm
+		LEFT JOIN LATERAL (
+			SELECT * FROM contacts c
+			WHERE c.source_uuid = gm.member_id
+			OR c.phone_number = gm.member_id
+			OR c.source_uuid IN (
+				SELECT value FROM person_identifiers
+				WHERE person_id = gm.person_id AND kind = 'uuid'
+			)
+			LIMIT 1
+		) c ON true
+		WHERE gm.group_id = $1
+		ORDER BY gm.left_at IS NOT NULL, gm.is_admin DESC, gm.joined_at, gm.member_id
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m GroupMember
+		if err := rows.Scan(&m.MemberID, &m.PersonID, &m.IsAdmin, &m.JoinedAt, &m.LeftAt,
+			&m.SourceUUID, &m.PhoneNumber, &m.ProfileName, &m.Alias, &m.AvatarPath,
+			&m.MessageCount); err != nil {
+			return nil, err
+		}
+		detail.Members = append(detail.Members, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.LoadIdentities(ctx)
+	if err != nil {
+		log.Printf("Group detail: failed to load contact names: %v", err)
+	}
+	for i, m := range detail.Members {
+		key := m.MemberID
+		if m.PersonID != nil {
+			key = *m.PersonID
+		}
+		detail.Members[i].Name = ids.Name(key)
+	}
+	return detail, nil
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"context"
+	"strings"
+)
+
+// SelfName is how the account owner appears in prompts and display fields.
+const SelfName = "me"
+
+// Identities resolves person IDs and sender identifiers (phone numbers,
+// Signal UUIDs) to display names: the contact alias, then the Signal profile
+// name, then a short form of the ID. A name known for any of a person's
+// identifiers applies to all of them. A nil *Identities resolves everything
+// to short IDs.
+type Identities struct {
+	names map[string]string
+}
+
+// LoadIdentities snapshots the contact book and persons for name lookups.
+func (s *Store) LoadIdentities(ctx context.Context) (*Identities, error) {
+	contacts, err := s.ListContacts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	persons, err := s.ListPersons(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewIdentities(contacts, persons), nil
+}
+
+func NewIdentities(contacts []ContactRecord, persons []Person) *Identities {
+	ids := &Identities{names: make(map[string]string)}
+	for _, c := range contacts {
+		name := strings.TrimSpace(c.Alias)
+		if name == "" {
+			name = strings.TrimSpace(c.ProfileName)
+		}
+		if name == "" {
+			continue
+		}
+		if c.SourceUUID != "" {
+			ids.names[c.SourceUUID] = name
+		}
+		if c.PhoneNumber != "" {
+			ids.names[c.PhoneNumber] = name
+		}
+	}
+
+	for _, p := range persons {
+		name := ""
+		if p.IsSelf {
+			name = SelfName
+		}
+		for _, id := range p.Identifiers {
+			if name == "" {
+				name = ids.names[id.Value]
+			}
+		}
+		if name == "" {
+			ids.names[p.ID] = personShortID(p)
+			continue
+		}
+		ids.names[p.ID] = name
+		for _, id := range p.Identifiers {
+			ids.names[id.Value] = name
+		}
+	}
+	return ids
+}
+
+// personShortID abbreviates an unnamed person by their phone number, or
+// their UUID when no number is known.
+func personShortID(p Person) string {
+	short := p.ID
+	for _, id := range p.Identifiers {
+		if id.Kind == IdentifierPhone {
+			return ShortID(id.Value)
+		}
+		short = id.Value
+	}
+	return ShortID(short)
+}
+
+// Name returns the display name for a person ID, sender_id or source_uuid.
+func (ids *Identities) Name(id string) string {
+	if id == "self" {
+		return SelfName
+	}
+	if ids != nil {
+		if name, ok := ids.names[id]; ok {
+			return name
+		}
+	}
+	return ShortID(id)
+}
+
+// Sender names a message's author, preferring the UUID (aliases are keyed by
+// it) and using SelfName for the account owner's own messages.
+func (ids *Identities) Sender(senderID string, sourceUUID *string, outgoing bool) string {
+	if outgoing {
+		return SelfName
+	}
+	if sourceUUID != nil && *sourceUUID != "" && ids != nil {
+		if name, ok := ids.names[*sourceUUID]; ok {
+			return name
+		}
+	}
+	return ids.Name(senderID)
+}
+
+// ShortID abbreviates an unnamed sender: the last four digits of a phone
+// number or the first block of a UUID.
+func ShortID(id string) string {
+	switch {
+	case strings.HasPrefix(id, "+") && len(id) > 4:
+		return "…" + id[len(id)-4:]
+	case isUUID(id):
+		return id[:8]
+	}
+	return id
+}
+
+func isUUID(id string) bool {
+	return len(id) == 36 && strings.Count(id, "-") == 4
+}
@@ -0,0 +1,89 @@
+package store
+
+import "testing"
+
+func TestIdentities(t *testing.T) {
+	uuid := "0b8f6a2e-1c3d-4e5f-8a9b-0c1d2e3f4a5b"
+	ids := NewIdentities([]ContactRecord{
+		{SourceUUID: uuid, PhoneNumber: "+15551234567", ProfileName: "Jonathan", Alias: "Jo"},
+		{SourceUUID: "c4d5e6f7-0000-1111-2222-333344445555", PhoneNumber: "+15559876543", ProfileName: "Sam"},
+		{SourceUUID: "d1e2f3a4-0000-1111-2222-333344445555", Alias: "Ana"},
+	}, []Person{
+		// Ana's number isn't in her contact record; the person links it
+		{ID: "p-ana", Identifiers: []PersonIdentifier{
+			{Kind: IdentifierPhone, Value: "+15552223333"},
+			{Kind: IdentifierUUID, Value: "d1e2f3a4-0000-1111-2222-333344445555"},
+		}},
+		{ID: "p-self", IsSelf: true, Identifiers: []PersonIdentifier{
+			{Kind: IdentifierPhone, Value: "+15550009999"},
+			{Kind: IdentifierSelf, Value: "self"},
+		}},
+		{ID: "p-unknown", Identifiers: []PersonIdentifier{
+			{Kind: IdentifierPhone, Value: "+15554447777"},
+			{Kind: IdentifierUUID, Value: "e5f6a7b8-0000-1111-2222-333344445555"},
+		}},
+	})
+
+	cases := []struct {
+		id   string
+		want string
+	}{
+		{"+15551234567", "Jo"},
+		{uuid, "Jo"},
+		{"+15559876543", "Sam"},
+		{"+15550001111", "…1111"},
+		{"9a8b7c6d-0000-1111-2222-333344445555", "9a8b7c6d"},
+		{"self", SelfName},
+		{"p-ana", "Ana"},
+		{"+15552223333", "Ana"},
+		{"p-self", SelfName},
+		{"+15550009999", SelfName},
+		{"p-unknown", "…7777"},
+	}
+	for _, c := range cases {
+		if got := ids.Name(c.id); got != c.want {
+			t.Errorf("Name(%q) = %q, want %q", c.id, got, c.want)
+		}
+	}
+
+	// The UUID wins over an unknown number, and outgoing messages are the owner
+	if got := ids.Sender("+15550001111", &uuid, false); got != "Jo" {
+		t.Errorf("Sender by uuid = %q, want Jo", got)
+	}
+	if got := ids.Sender("self", nil, true); got != SelfName {
+		t.Errorf("Sender outgoing = %q, want %q", got, SelfName)
+	}
+
+	var none *Identities
+	if got := none.Name("+15551234567"); got != "…4567" {
+		t.Errorf("nil Name = %q", got)
+	}
+}
+
+func TestSenderIdentifiers(t *testing.T) {
+	uuid := "0b8f6a2e-1c3d-4e5f-8a9b-0c1d2e3f4a5b"
+	cases := []struct {
+		sender   string
+		uuid     *string
+		outgoing bool
+		want     []PersonIdentifier
+	}{
+		{"+15551234567", &uuid, false, []PersonIdentifier{{IdentifierPhone, "+15551234567"}, {IdentifierUUID, uuid}}},
+		{uuid, &uuid, false, []PersonIdentifier{{IdentifierUUID, uuid}}},
+		{"+15551234567", nil, false, []PersonIdentifier{{IdentifierPhone, "+15551234567"}}},
+		{"self", nil, true, []PersonIdentifier{{IdentifierSelf, "self"}}},
+		{"", nil, false, nil},
+	}
+	for _, c := range cases {
+		got := senderIdentifiers(c.sender, c.uuid, c.outgoing)
+		if len(got) != len(c.want) {
+			t.Errorf("senderIdentifiers(%q) = %v, want %v", c.sender, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("senderIdentifiers(%q)[%d] = %v, want %v", c.sender, i, got[i], c.want[i])
+			}
+		}
+	}
+}
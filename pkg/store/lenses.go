@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrBuiltinLens is returned when deleting a seeded lens; built-ins can be
+// edited but not removed, since startup would re-create them.
+var ErrBuiltinLens = errors.New("built-in lenses cannot be deleted")
+
+const lensColumns = `name, label, system_prompt, temperature, schema_variant, builtin, created_at, updated_at`
+
+func scanLens(row pgx.Row) (*DigestLens, error) {
+	var l DigestLens
+	if err := row.Scan(&l.Name, &l.Label, &l.SystemPrompt, &l.Temperature, &l.SchemaVariant,
+		&l.Builtin, &l.CreatedAt, &l.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// SeedLenses inserts the built-in lenses that don't exist yet. Existing rows,
+// including edited built-ins, are left alone.
+func (s *Store) SeedLenses(ctx context.Context, lenses []DigestLens) error {
+	for _, l := range lenses {
+		if _, err := s.pool.Exec(ctx, `
+			INSERT INTO digest_lenses (name, label, system_prompt, temperature, schema_variant, builtin)
+			VALUES ($1, $2, $3, $4, $5, true)
+			ON CONFLICT (name) DO NOTHING
+		`, l.Name, l.Label, l.SystemPrompt, l.Temperature, l.SchemaVariant); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListLenses returns built-ins first, then user lenses by name.
+func (s *Store) ListLenses(ctx context.Context) ([]DigestLens, error) {
+	rows, err := s.pool.Query(ctx, `SELECT `+lensColumns+` FROM digest_lenses ORDER BY builtin DESC, created_at, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lenses []DigestLens
+	for rows.Next() {
+		l, err := scanLens(rows)
+		if err != nil {
+			return nil, err
+		}
+		lenses = append(lenses, *l)
+	}
+	return lenses, rows.Err()
+}
+
+func (s *Store) GetLens(ctx context.Context, name string) (*DigestLens, error) {
+	return scanLens(s.pool.QueryRow(ctx, `SELECT `+lensColumns+` FROM digest_lenses WHERE name = $1`, name))
+}
+
+func (s *Store) CreateLens(ctx context.Context, l DigestLens) (*DigestLens, error) {
+	return scanLens(s.pool.QueryRow(ctx, `
+		INSERT INTO digest_lenses (name, label, system_prompt, temperature, schema_variant)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+lensColumns,
+		l.Name, l.Label, l.SystemPrompt, l.Temperature, l.SchemaVariant))
+}
+
+// UpdateLens replaces a lens's settings; the name is immutable.
+func (s *Store) UpdateLens(ctx context.Context, l DigestLens) (*DigestLens, error) {
+	return scanLens(s.pool.QueryRow(ctx, `
+		UPDATE digest_lenses
+		SET label = $2, system_prompt = $3, temperature = $4, schema_variant = $5, updated_at = now()
+		WHERE name = $1
+		RETURNING `+lensColumns,
+		l.Name, l.Label, l.SystemPrompt, l.Temperature, l.SchemaVariant))
+}
+
+func (s *Store) DeleteLens(ctx context.Context, name string) error {
+	var builtin bool
+	err := s.pool.QueryRow(ctx, `DELETE FROM digest_lenses WHERE name = $1 AND NOT builtin RETURNING builtin`, name).Scan(&builtin)
+	if errors.Is(err, pgx.ErrNoRows) {
+		if _, getErr := s.GetLens(ctx, name); getErr == nil {
+			return ErrBuiltinLens
+		}
+	}
+	return err
+}
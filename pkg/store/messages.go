@@ -2,32 +2,48 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	pgvector "github.com/pgvector/pgvector-go"
 )
 
 func (s *Store) SaveMessage(ctx context.Context, msg MessageRecord) (string, error) {
 	query := `
 		INSERT INTO messages (signal_id, sender_id, content, embedding, expires_at,
-			group_id, source_uuid, is_outgoing, view_once, has_attachments, raw_json)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+			group_id, source_uuid, is_outgoing, view_once, has_attachments, raw_json, ts_config,
+			embedding_model, embedding_dim, person_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
 		ON CONFLICT (signal_id) DO NOTHING
 		RETURNING id
 	`
-	// Wrap embedding for pgvector
-	var vec *pgvector.Vector
-	if len(msg.Embedding) > 0 {
-		v := pgvector.NewVector(msg.Embedding)
-		vec = &v
+	// Wrap embedding for pgvector; zero vectors are left NULL for the backfill worker
+	vec := embeddingVector(msg.Embedding)
+	var embeddingModel *string
+	var embeddingDim *int
+	if vec != nil {
+		model := msg.EmbeddingModel
+		if model == "" {
+			model = s.embeddingModel
+		}
+		dim := len(msg.Embedding)
+		embeddingModel, embeddingDim = &model, &dim
+	}
+
+	tsConfig := msg.TSConfig
+	if tsConfig == "" {
+		tsConfig = s.DefaultSearchLanguage()
 	}
 
 	var id string
 	err := s.pool.QueryRow(ctx, query,
 		msg.SignalID, msg.SenderID, msg.Content, vec, msg.ExpiresAt,
-		msg.GroupID, msg.SourceUUID, msg.IsOutgoing, msg.ViewOnce, msg.HasAttachments, msg.RawJSON,
+		msg.GroupID, msg.SourceUUID, msg.IsOutgoing, msg.ViewOnce, msg.HasAttachments, msg.RawJSON, tsConfig,
+		embeddingModel, embeddingDim, msg.PersonID,
 	).Scan(&id)
 	if err != nil {
 		if err.Error() == "no rows in result set" {
@@ -50,9 +66,9 @@ func (s *Store) ListMessages(ctx context.Context, filter MessageFilter) ([]Messa
 	conditions = append(conditions, "(expires_at IS NULL OR expires_at > now())")
 
 	if filter.GroupID != nil {
-		conditions = append(conditions, fmt.Sprintf("group_id = $%d", argIdx))
-		args = append(args, *filter.GroupID)
-		argIdx++
+		conditions = append(conditions, fmt.Sprintf("(group_id = $%d OR ($%d = $%d AND group_id IS NULL))", argIdx, argIdx, argIdx+1))
+		args = append(args, *filter.GroupID, DirectGroupID)
+		argIdx += 2
 	}
 	if filter.SenderID != nil {
 		conditions = append(conditions, fmt.Sprintf("(sender_id = $%d OR source_uuid = $%d)", argIdx, argIdx))
@@ -114,13 +130,58 @@ func (s *Store) ListMessages(ctx context.Context, filter MessageFilter) ([]Messa
 }
 
 func (s *Store) SemanticSearch(ctx context.Context, embedding []float32, threshold float64, limit int) ([]SearchResult, error) {
-	query := `
-		SELECT id, signal_id, sender_id, content, group_id, source_uuid,
-			is_outgoing, has_attachments, similarity, created_at
-		FROM match_messages($1, $2, $3)
-	`
-	vec := pgvector.NewVector(embedding)
-	rows, err := s.pool.Query(ctx, query, vec, threshold, limit)
+	return s.FilteredSemanticSearch(ctx, embedding, threshold, SearchFilter{}, limit)
+}
+
+func (s *Store) FullTextSearch(ctx context.Context, query string, limit int) ([]SearchResult, error) {
+	return s.FilteredFullTextSearch(ctx, query, SearchFilter{}, limit)
+}
+
+// FilteredFullTextSearch matches messages against the configured text-search
+// languages. When the tsquery finds nothing it falls back to trigram word
+// similarity so typos and partial words still return results.
+func (s *Store) FilteredFullTextSearch(ctx context.Context, query string, filter SearchFilter, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	results, err := s.searchMessages(ctx, query, filter, limit, false)
+	if err != nil || len(results) > 0 {
+		return results, err
+	}
+	return s.searchMessages(ctx, query, filter, limit, true)
+}
+
+func (s *Store) searchMessages(ctx context.Context, query string, filter SearchFilter, limit int, fuzzy bool) ([]SearchResult, error) {
+	args := []any{query}
+
+	var match, rank string
+	if fuzzy {
+		match = "$1 <% m.content"
+		rank = "word_similarity($1, m.content)"
+	} else {
+		tsq := s.tsQuery("$1", filter.Language)
+		match = "m.tsv @@ " + tsq
+		rank = fmt.Sprintf("ts_rank(m.tsv, %s)", tsq)
+	}
+
+	conditions := []string{match}
+	conditions, args = appendSearchFilter(conditions, args, filter)
+	where := strings.Join(conditions, " AND ")
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT m.id, m.signal_id, m.sender_id, m.content, m.group_id, m.source_uuid,
+			m.is_outgoing, m.has_attachments,
+			%s AS rank,
+			m.created_at
+		FROM messages m
+		WHERE %s
+		ORDER BY rank DESC
+		LIMIT $%d
+	`, rank, where, len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.pool.Query(ctx, sqlQuery, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -129,78 +190,79 @@ func (s *Store) SemanticSearch(ctx context.Context, embedding []float32, thresho
 	var results []SearchResult
 	for rows.Next() {
 		var r SearchResult
-		var sim float64
+		var rank float32
 		if err := rows.Scan(
 			&r.ID, &r.SignalID, &r.SenderID, &r.Content, &r.GroupID, &r.SourceUUID,
-			&r.IsOutgoing, &r.HasAttachments, &sim, &r.CreatedAt,
+			&r.IsOutgoing, &r.HasAttachments, &rank, &r.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
-		r.Similarity = &sim
+		r.Rank = &rank
+		r.Fuzzy = fuzzy
 		results = append(results, r)
 	}
 	return results, nil
 }
 
-func (s *Store) FullTextSearch(ctx context.Context, query string, limit int) ([]SearchResult, error) {
-	return s.FilteredFullTextSearch(ctx, query, SearchFilter{}, limit)
-}
-
-func (s *Store) FilteredFullTextSearch(ctx context.Context, query string, filter SearchFilter, limit int) ([]SearchResult, error) {
-	if limit <= 0 {
-		limit = 50
-	}
-
-	var conditions []string
-	var args []any
-	argIdx := 1
-
-	// Full text match
-	conditions = append(conditions, fmt.Sprintf("m.tsv @@ plainto_tsquery('english', $%d)", argIdx))
-	args = append(args, query)
-	argIdx++
-
+// appendSearchFilter adds the SearchFilter conditions (against alias m) and
+// their arguments, numbering placeholders after the existing args.
+func appendSearchFilter(conditions []string, args []any, filter SearchFilter) ([]string, []any) {
 	conditions = append(conditions, "(m.expires_at IS NULL OR m.expires_at > now())")
 
 	if filter.GroupID != nil {
-		conditions = append(conditions, fmt.Sprintf("m.group_id = $%d", argIdx))
-		args = append(args, *filter.GroupID)
-		argIdx++
+		args = append(args, *filter.GroupID, DirectGroupID)
+		conditions = append(conditions, fmt.Sprintf("(m.group_id = $%d OR ($%d = $%d AND m.group_id IS NULL))", len(args)-1, len(args)-1, len(args)))
 	}
 	if filter.SenderID != nil {
-		conditions = append(conditions, fmt.Sprintf("(m.sender_id = $%d OR m.source_uuid = $%d)", argIdx, argIdx))
 		args = append(args, *filter.SenderID)
-		argIdx++
+		conditions = append(conditions, fmt.Sprintf("(m.sender_id = $%d OR m.source_uuid = $%d)", len(args), len(args)))
 	}
 	if filter.After != nil {
-		conditions = append(conditions, fmt.Sprintf("m.created_at > $%d", argIdx))
 		args = append(args, *filter.After)
-		argIdx++
+		conditions = append(conditions, fmt.Sprintf("m.created_at > $%d", len(args)))
 	}
 	if filter.Before != nil {
-		conditions = append(conditions, fmt.Sprintf("m.created_at < $%d", argIdx))
 		args = append(args, *filter.Before)
-		argIdx++
+		conditions = append(conditions, fmt.Sprintf("m.created_at < $%d", len(args)))
 	}
 	if filter.HasMedia != nil && *filter.HasMedia {
 		conditions = append(conditions, "m.has_attachments = true")
 	}
+	return conditions, args
+}
 
-	where := strings.Join(conditions, " AND ")
+// FilteredSemanticSearch ranks messages by cosine similarity to embedding.
+// Only vectors from the configured embedding model are compared; messages
+// awaiting re-embedding are skipped until the backfill worker reaches them.
+func (s *Store) FilteredSemanticSearch(ctx context.Context, embedding []float32, threshold float64, filter SearchFilter, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 20
+	}
 
-	sqlQuery := fmt.Sprintf(`
+	conditions := []string{
+		"m.embedding IS NOT NULL",
+		"1 - (m.embedding <=> $1) > $2",
+	}
+	args := []any{pgvector.NewVector(embedding), threshold}
+	if s.embeddingModel != "" {
+		args = append(args, s.embeddingModel)
+		conditions = append(conditions, fmt.Sprintf("m.embedding_model = $%d", len(args)))
+	}
+	conditions, args = appendSearchFilter(conditions, args, filter)
+
+	query := fmt.Sprintf(`
 		SELECT m.id, m.signal_id, m.sender_id, m.content, m.group_id, m.source_uuid,
 			m.is_outgoing, m.has_attachments,
-			ts_rank(m.tsv, plainto_tsquery('english', $1)) AS rank,
+			1 - (m.embedding <=> $1) AS similarity,
 			m.created_at
 		FROM messages m
 		WHERE %s
-		ORDER BY rank DESC
+		ORDER BY m.embedding <=> $1
 		LIMIT $%d
-	`, where, argIdx)
+	`, strings.Join(conditions, " AND "), len(args)+1)
 	args = append(args, limit)
 
-	rows, err := s.pool.Query(ctx, sqlQuery, args...)
+	rows, err := s.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -209,54 +271,104 @@ func (s *Store) FilteredFullTextSearch(ctx context.Context, query string, filter
 	var results []SearchResult
 	for rows.Next() {
 		var r SearchResult
-		var rank float32
+		var sim float64
 		if err := rows.Scan(
 			&r.ID, &r.SignalID, &r.SenderID, &r.Content, &r.GroupID, &r.SourceUUID,
-			&r.IsOutgoing, &r.HasAttachments, &rank, &r.CreatedAt,
+			&r.IsOutgoing, &r.HasAttachments, &sim, &r.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
-		r.Rank = &rank
+		r.Similarity = &sim
 		results = append(results, r)
 	}
 	return results, nil
 }
 
-func (s *Store) FilteredSemanticSearch(ctx context.Context, embedding []float32, threshold float64, filter SearchFilter, limit int) ([]SearchResult, error) {
-	// Over-fetch then post-filter
-	fetchLimit := limit * 5
-	if fetchLimit > 200 {
-		fetchLimit = 200
+// ErrNoEmbedding is returned when a message has no usable embedding to compare.
+var ErrNoEmbedding = errors.New("message has no embedding")
+
+// ErrMessageNotFound is returned for an unknown or malformed message ID.
+var ErrMessageNotFound = errors.New("message not found")
+
+// SimilarMessages ranks messages by cosine similarity to the stored embedding
+// of message id. The message itself and up to `neighbours` messages on either
+// side of it in the same conversation are excluded, so the results are other
+// times the topic came up rather than the surrounding thread.
+func (s *Store) SimilarMessages(ctx context.Context, id string, neighbours int, threshold float64, filter SearchFilter, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 20
 	}
 
-	results, err := s.SemanticSearch(ctx, embedding, threshold, fetchLimit)
+	var hasEmbedding bool
+	err := s.pool.QueryRow(ctx, `
+		SELECT embedding IS NOT NULL AND vector_norm(embedding) > 0
+		FROM messages WHERE id = $1
+	`, id).Scan(&hasEmbedding)
+	var pgErr *pgconn.PgError
+	if errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) && pgErr.Code == "22P02" { // invalid UUID
+		return nil, ErrMessageNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
+	if !hasEmbedding {
+		return nil, ErrNoEmbedding
+	}
 
-	var filtered []SearchResult
-	for _, r := range results {
-		if filter.GroupID != nil && (r.GroupID == nil || *r.GroupID != *filter.GroupID) {
-			continue
-		}
-		if filter.SenderID != nil && r.SenderID != *filter.SenderID && (r.SourceUUID == nil || *r.SourceUUID != *filter.SenderID) {
-			continue
-		}
-		if filter.After != nil && !r.CreatedAt.After(*filter.After) {
-			continue
-		}
-		if filter.Before != nil && !r.CreatedAt.Before(*filter.Before) {
-			continue
-		}
-		if filter.HasMedia != nil && *filter.HasMedia && !r.HasAttachments {
-			continue
-		}
-		filtered = append(filtered, r)
-		if len(filtered) >= limit {
-			break
+	conditions := []string{
+		"m.id != t.id",
+		"m.id NOT IN (SELECT id FROM neighbours)",
+		"m.embedding IS NOT NULL",
+		"vector_norm(m.embedding) > 0",
+		"m.embedding_model IS NOT DISTINCT FROM t.embedding_model",
+		"1 - (m.embedding <=> t.embedding) > $3",
+	}
+	conditions, args := appendSearchFilter(conditions, []any{id, neighbours, threshold}, filter)
+
+	query := fmt.Sprintf(`
+		WITH target AS (
+			SELECT id, embedding, embedding_model, group_id, created_at FROM messages WHERE id = $1
+		),
+		neighbours AS (
+			(SELECT n.id FROM messages n, target t
+			WHERE n.created_at < t.created_at AND n.group_id IS NOT DISTINCT FROM t.group_id
+			ORDER BY n.created_at DESC LIMIT $2)
+			UNION ALL
+			(SELECT n.id FROM messages n, target t
+			WHERE n.created_at > t.created_at AND n.group_id IS NOT DISTINCT FROM t.group_id
+			ORDER BY n.created_at ASC LIMIT $2)
+		)
+		SELECT m.id, m.signal_id, m.sender_id, m.content, m.group_id, m.source_uuid,
+			m.is_outgoing, m.has_attachments,
+			1 - (m.embedding <=> t.embedding) AS similarity,
+			m.created_at
+		FROM messages m, target t
+		WHERE %s
+		ORDER BY m.embedding <=> t.embedding
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var sim float64
+		if err := rows.Scan(
+			&r.ID, &r.SignalID, &r.SenderID, &r.Content, &r.GroupID, &r.SourceUUID,
+			&r.IsOutgoing, &r.HasAttachments, &sim, &r.CreatedAt,
+		); err != nil {
+			return nil, err
 		}
+		r.Similarity = &sim
+		results = append(results, r)
 	}
-	return filtered, nil
+	return results, nil
 }
 
 func (s *Store) DeleteMessageBySignalID(ctx context.Context, signalID string) ([]string, error) {
@@ -339,11 +451,11 @@ func (s *Store) GetMessagesByTimeRange(ctx context.Context, start, end time.Time
 			SELECT id, signal_id, sender_id, content, group_id, source_uuid,
 				is_outgoing, has_attachments, created_at
 			FROM messages
-			WHERE created_at >= $1 AND created_at <= $2 AND group_id = $3
+			WHERE created_at >= $1 AND created_at <= $2 AND (group_id = $3 OR ($3 = $4 AND group_id IS NULL))
 			AND (expires_at IS NULL OR expires_at > now())
 			ORDER BY created_at ASC
 		`
-		args = []any{start, end, *groupID}
+		args = []any{start, end, *groupID, DirectGroupID}
 	} else {
 		query = `
 			SELECT id, signal_id, sender_id, content, group_id, source_uuid,
@@ -11,13 +11,16 @@ type MessageRecord struct {
 	SenderID       string          `db:"sender_id" json:"sender_id"`
 	Content        string          `db:"content" json:"content"`
 	Embedding      []float32       `db:"embedding" json:"-"`
+	EmbeddingModel string          `db:"embedding_model" json:"-"`
 	ExpiresAt      *time.Time      `db:"expires_at" json:"expires_at,omitempty"`
 	GroupID        *string         `db:"group_id" json:"group_id,omitempty"`
 	SourceUUID     *string         `db:"source_uuid" json:"source_uuid,omitempty"`
+	PersonID       *string         `db:"person_id" json:"person_id,omitempty"`
 	IsOutgoing     bool            `db:"is_outgoing" json:"is_outgoing"`
 	ViewOnce       bool            `db:"view_once" json:"view_once"`
 	HasAttachments bool            `db:"has_attachments" json:"has_attachments"`
 	RawJSON        json.RawMessage `db:"raw_json" json:"-"`
+	TSConfig       string          `db:"ts_config" json:"-"`
 	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
 }
 
@@ -26,21 +29,62 @@ type GroupRecord struct {
 	GroupID     string    `db:"group_id" json:"group_id"`
 	Name        string    `db:"name" json:"name"`
 	Description string    `db:"description" json:"description"`
-	AvatarPath  string    `db:"avatar_path" json:"avatar_path,omitempty"`
+	AvatarPath  string    `db:"avatar_path" json:"-"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
 	MemberCount int       `db:"member_count" json:"member_count"`
 	CreatedAt   time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
 }
 
+// GroupMember is a group member as last seen by the group sync. The contact
+// fields are empty for members not in the contact list.
+type GroupMember struct {
+	MemberID     string     `db:"member_id" json:"member_id"`
+	PersonID     *string    `db:"person_id" json:"person_id,omitempty"`
+	Name         string     `json:"name"`
+	IsAdmin      bool       `db:"is_admin" json:"is_admin"`
+	JoinedAt     time.Time  `db:"joined_at" json:"joined_at"`
+	LeftAt       *time.Time `db:"left_at" json:"left_at,omitempty"`
+	SourceUUID   string     `json:"source_uuid,omitempty"`
+	PhoneNumber  string     `json:"phone_number,omitempty"`
+	ProfileName  string     `json:"profile_name,omitempty"`
+	Alias        string     `json:"alias,omitempty"`
+	AvatarPath   string     `json:"-"`
+	AvatarURL    string     `json:"avatar_url,omitempty"`
+	MessageCount int        `json:"message_count"`
+}
+
+type GroupDetail struct {
+	GroupRecord
+	MessageCount int           `json:"message_count"`
+	Members      []GroupMember `json:"members"`
+}
+
 type ContactRecord struct {
-	ID          string    `db:"id" json:"id"`
-	SourceUUID  string    `db:"source_uuid" json:"source_uuid"`
-	PhoneNumber string    `db:"phone_number" json:"phone_number"`
-	ProfileName string    `db:"profile_name" json:"profile_name"`
-	Alias       string    `db:"alias" json:"alias"`
-	AvatarPath  string    `db:"avatar_path" json:"avatar_path,omitempty"`
-	CreatedAt   time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
+	ID              string    `db:"id" json:"id"`
+	SourceUUID      string    `db:"source_uuid" json:"source_uuid"`
+	PhoneNumber     string    `db:"phone_number" json:"phone_number"`
+	ProfileName     string    `db:"profile_name" json:"profile_name"`
+	Alias           string    `db:"alias" json:"alias"`
+	AvatarPath      string    `db:"avatar_path" json:"-"`
+	AvatarUpdatedMs int64     `db:"avatar_updated_ms" json:"-"` // profile last_updated_timestamp the avatar is from
+	CreatedAt       time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// Person is one real sender, however they show up in messages.
+type Person struct {
+	ID          string             `json:"id"`
+	IsSelf      bool               `json:"is_self"`
+	Identifiers []PersonIdentifier `json:"identifiers"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// PersonIdentifier is one way a person appears as a sender: a phone number,
+// a Signal UUID, or "self" for the account owner.
+type PersonIdentifier struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
 }
 
 type DistinctSender struct {
@@ -78,6 +122,7 @@ type URLRecord struct {
 	ImageURL    string    `db:"image_url" json:"image_url,omitempty"`
 	Fetched     bool      `db:"fetched" json:"fetched"`
 	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+	Similarity  *float64  `json:"similarity,omitempty"`
 }
 
 type DigestRecord struct {
@@ -89,6 +134,7 @@ type DigestRecord struct {
 	ActionItems json.RawMessage `db:"action_items" json:"action_items"`
 	PeriodStart time.Time       `db:"period_start" json:"period_start"`
 	PeriodEnd   time.Time       `db:"period_end" json:"period_end"`
+	PeriodKind  string          `db:"period_kind" json:"period_kind"`
 	GroupID     *string         `db:"group_id" json:"group_id,omitempty"`
 	LLMProvider string          `db:"llm_provider" json:"llm_provider"`
 	LLMModel    string          `db:"llm_model" json:"llm_model"`
@@ -112,6 +158,7 @@ type SearchFilter struct {
 	After    *time.Time
 	Before   *time.Time
 	HasMedia *bool
+	Language *string // text-search configuration override for full-text queries
 }
 
 type SearchResult struct {
@@ -125,6 +172,7 @@ type SearchResult struct {
 	HasAttachments bool      `json:"has_attachments"`
 	Similarity     *float64  `json:"similarity,omitempty"`
 	Rank           *float32  `json:"rank,omitempty"`
+	Fuzzy          bool      `json:"fuzzy,omitempty"`
 	CreatedAt      time.Time `json:"created_at"`
 }
 
@@ -139,6 +187,7 @@ type DailyInsight struct {
 	Superlatives   json.RawMessage `db:"superlatives" json:"superlatives,omitempty"`
 	Snapshot       json.RawMessage `db:"snapshot" json:"snapshot,omitempty"`
 	SnapshotDate   *time.Time      `db:"snapshot_date" json:"snapshot_date,omitempty"`
+	GroupID        *string         `db:"group_id" json:"group_id,omitempty"`
 	CreatedAt      time.Time       `db:"created_at" json:"created_at"`
 }
 
@@ -157,19 +206,29 @@ type DaySnapshot struct {
 	BusiestDayCount int                   `json:"busiest_day_count,omitempty"`
 }
 
+// Snapshot entries are per person. PersonID is the persons.id (or the raw
+// sender ID for messages not linked yet); SenderID is one of their raw IDs.
 type CrewMember struct {
+	PersonID string `json:"person_id,omitempty"`
 	SenderID string `json:"sender_id"`
+	Name     string `json:"name"`
 	Count    int    `json:"count"`
 }
 
 type ConversationPair struct {
+	PersonA string `json:"person_a,omitempty"`
+	PersonB string `json:"person_b,omitempty"`
 	SenderA string `json:"sender_a"`
 	SenderB string `json:"sender_b"`
+	NameA   string `json:"name_a"`
+	NameB   string `json:"name_b"`
 	Count   int    `json:"count"`
 }
 
 type VerbLeader struct {
+	PersonID string   `json:"person_id,omitempty"`
 	SenderID string   `json:"sender_id"`
+	Name     string   `json:"name"`
 	Count    int      `json:"count"`
 	Samples  []string `json:"samples"`
 }
@@ -178,6 +237,8 @@ type LinkOfDay struct {
 	URL      string `json:"url"`
 	Title    string `json:"title"`
 	SenderID string `json:"sender_id"`
+	PersonID string `json:"person_id,omitempty"`
+	Name     string `json:"name,omitempty"`
 }
 
 type YesterdayRef struct {
@@ -185,11 +246,16 @@ type YesterdayRef struct {
 	Link  *LinkOfDay `json:"link,omitempty"`
 }
 
+// Superlative is a fun stat won by a person. Winner is the display name,
+// PersonID the person and WinnerID one of their raw sender IDs ("self" for
+// the account owner).
 type Superlative struct {
-	Label  string `json:"label"`
-	Icon   string `json:"icon"`
-	Winner string `json:"winner"`
-	Value  string `json:"value"`
+	Label    string `json:"label"`
+	Icon     string `json:"icon"`
+	Winner   string `json:"winner"`
+	WinnerID string `json:"winner_id,omitempty"`
+	PersonID string `json:"person_id,omitempty"`
+	Value    string `json:"value"`
 }
 
 type Stats struct {
@@ -257,3 +323,76 @@ type CerebroConceptDetail struct {
 	Edges       []CerebroEdge       `json:"edges"`
 	Enrichments []CerebroEnrichment `json:"enrichments"`
 }
+
+type UsageRecord struct {
+	ID           string    `db:"id" json:"id"`
+	Feature      string    `db:"feature" json:"feature"`
+	Provider     string    `db:"provider" json:"provider"`
+	Model        string    `db:"model" json:"model"`
+	InputTokens  int       `db:"input_tokens" json:"input_tokens"`
+	OutputTokens int       `db:"output_tokens" json:"output_tokens"`
+	CostUSD      float64   `db:"cost_usd" json:"cost_usd"`
+	Success      bool      `db:"success" json:"success"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+type UsageSummary struct {
+	Feature      string  `json:"feature"`
+	Provider     string  `json:"provider"`
+	Model        string  `json:"model"`
+	Calls        int     `json:"calls"`
+	Failures     int     `json:"failures"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// DigestLens is a persona for digest generation. SchemaVariant selects the
+// JSON format instructions appended to SystemPrompt ("custom" means the
+// prompt carries its own).
+type DigestLens struct {
+	Name          string    `db:"name" json:"name"`
+	Label         string    `db:"label" json:"label"`
+	SystemPrompt  string    `db:"system_prompt" json:"system_prompt"`
+	Temperature   float64   `db:"temperature" json:"temperature"`
+	SchemaVariant string    `db:"schema_variant" json:"schema_variant"`
+	Builtin       bool      `db:"builtin" json:"builtin"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// AskSession is a conversation with the archive; follow-up questions share
+// the session so the model sees the earlier turns.
+type AskSession struct {
+	ID        string    `db:"id" json:"id"`
+	Title     string    `db:"title" json:"title"`
+	GroupID   *string   `db:"group_id" json:"group_id,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	Turns     []AskTurn `json:"turns,omitempty"`
+}
+
+// AskTurn is one question and its answer. Citations is a JSON array of the
+// messages the answer was grounded in.
+type AskTurn struct {
+	ID          string          `db:"id" json:"id"`
+	SessionID   string          `db:"session_id" json:"session_id"`
+	Question    string          `db:"question" json:"question"`
+	Answer      string          `db:"answer" json:"answer"`
+	Citations   json.RawMessage `db:"citations" json:"citations"`
+	LLMProvider string          `db:"llm_provider" json:"llm_provider"`
+	LLMModel    string          `db:"llm_model" json:"llm_model"`
+	TokenCount  int             `db:"token_count" json:"token_count"`
+	CreatedAt   time.Time       `db:"created_at" json:"created_at"`
+}
+
+// ScheduleRun is the last run of a scheduled job. ScheduledFor is the
+// occurrence it ran for, which may be earlier than StartedAt when catching
+// up after downtime.
+type ScheduleRun struct {
+	Job          string    `json:"job"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	LastError    string    `json:"last_error,omitempty"`
+}
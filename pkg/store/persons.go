@@ -0,0 +1,263 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// Identifier kinds in person_identifiers.
+const (
+	IdentifierPhone = "phone"
+	IdentifierUUID  = "uuid"
+	IdentifierSelf  = "self"
+)
+
+// identifierKind classifies a raw sender ID. Senders without a phone number
+// on file arrive with their UUID as sender_id.
+func identifierKind(id string) string {
+	switch {
+	case id == "self":
+		return IdentifierSelf
+	case isUUID(id):
+		return IdentifierUUID
+	}
+	return IdentifierPhone
+}
+
+// senderIdentifiers lists the identifiers a message carries for its author.
+func senderIdentifiers(senderID string, sourceUUID *string, outgoing bool) []PersonIdentifier {
+	var idents []PersonIdentifier
+	if outgoing {
+		idents = append(idents, PersonIdentifier{Kind: IdentifierSelf, Value: "self"})
+	} else if senderID != "" {
+		idents = append(idents, PersonIdentifier{Kind: identifierKind(senderID), Value: senderID})
+	}
+	if sourceUUID != nil && *sourceUUID != "" && *sourceUUID != senderID {
+		idents = append(idents, PersonIdentifier{Kind: IdentifierUUID, Value: *sourceUUID})
+	}
+	return idents
+}
+
+// ResolvePerson returns the person a message's author belongs to, creating
+// it on first sight. A message that carries both a phone number and a UUID
+// seen separately before merges their two persons.
+func (s *Store) ResolvePerson(ctx context.Context, senderID string, sourceUUID *string, outgoing bool) (string, error) {
+	return s.linkIdentifiers(ctx, senderIdentifiers(senderID, sourceUUID, outgoing))
+}
+
+// EnsureSelf links the account's own phone number to the owner, whose sent
+// messages are stored as "self".
+func (s *Store) EnsureSelf(ctx context.Context, number string) (string, error) {
+	idents := []PersonIdentifier{{Kind: IdentifierSelf, Value: "self"}}
+	if number != "" {
+		idents = append(idents, PersonIdentifier{Kind: IdentifierPhone, Value: number})
+	}
+	return s.linkIdentifiers(ctx, idents)
+}
+
+// linkIdentifiers makes idents point at a single person and returns its ID.
+// When they already belong to different persons, the owner or else the
+// oldest person is kept and the others are folded into it.
+func (s *Store) linkIdentifiers(ctx context.Context, idents []PersonIdentifier) (string, error) {
+	if len(idents) == 0 {
+		return "", nil
+	}
+	kinds := make([]string, len(idents))
+	values := make([]string, len(idents))
+	isSelf := false
+	for i, id := range idents {
+		kinds[i], values[i] = id.Kind, id.Value
+		if id.Kind == IdentifierSelf {
+			isSelf = true
+		}
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	// Two first sightings of a sender (ingest and the directory sync) would
+	// otherwise both create a person, and the loser's insert below would
+	// link nothing. Lock the identifiers in a fixed order to avoid deadlocks.
+	if _, err := tx.Exec(ctx, `
+		SELECT pg_advisory_xact_lock(h) FROM (
+			SELECT DISTINCT hashtext(k || ':' || v) AS h
+			FROM unnest($1::text[], $2::text[]) AS t(k, v)
+			ORDER BY h
+		) l
+	`, kinds, values); err != nil {
+		return "", fmt.Errorf("lock identifiers: %w", err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT p.id
+		FROM person_identifiers i
+		JOIN persons p ON p.id = i.person_id
+		WHERE (i.kind, i.value) IN (SELECT * FROM unnest($1::text[], $2::text[]))
+		ORDER BY p.is_self DESC, p.created_at
+	`, kinds, values)
+	if err != nil {
+		return "", fmt.Errorf("find persons: %w", err)
+	}
+	var found []string
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return "", err
+		}
+		if !seen[id] {
+			seen[id] = true
+			found = append(found, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	var personID string
+	if len(found) == 0 {
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO persons (is_self) VALUES ($1) RETURNING id
+		`, isSelf).Scan(&personID); err != nil {
+			return "", fmt.Errorf("create person: %w", err)
+		}
+	} else {
+		personID = found[0]
+		if merged := found[1:]; len(merged) > 0 {
+			for _, q := range []string{
+				`UPDATE person_identifiers SET person_id = $1 WHERE person_id = ANY($2)`,
+				`UPDATE messages SET person_id = $1 WHERE person_id = ANY($2)`,
+				`UPDATE group_members SET person_id = $1 WHERE person_id = ANY($2)`,
+				`DELETE FROM persons WHERE id = ANY($2)`,
+			} {
+				if _, err := tx.Exec(ctx, q, personID, merged); err != nil {
+					return "", fmt.Errorf("merge persons: %w", err)
+				}
+			}
+		}
+		if _, err := tx.Exec(ctx, `
+			UPDATE persons SET is_self = is_self OR $2, updated_at = now() WHERE id = $1
+		`, personID, isSelf); err != nil {
+			return "", fmt.Errorf("update person: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO person_identifiers (kind, value, person_id)
+		SELECT k, v, $3 FROM unnest($1::text[], $2::text[]) AS t(k, v)
+		ON CONFLICT (kind, value) DO NOTHING
+	`, kinds, values, personID); err != nil {
+		return "", fmt.Errorf("link identifiers: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return personID, nil
+}
+
+// BackfillPersons links contacts' numbers to their UUIDs and assigns a
+// person to every message stored without one. It returns the number of
+// messages updated.
+func (s *Store) BackfillPersons(ctx context.Context) (int64, error) {
+	contacts, err := s.ListContacts(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list contacts: %w", err)
+	}
+	for _, c := range contacts {
+		if c.SourceUUID == "" || c.PhoneNumber == "" {
+			continue
+		}
+		uuid := c.SourceUUID
+		if _, err := s.ResolvePerson(ctx, c.PhoneNumber, &uuid, false); err != nil {
+			return 0, fmt.Errorf("link contact %s: %w", ShortID(c.SourceUUID), err)
+		}
+	}
+
+	type sender struct {
+		id       string
+		uuid     *string
+		outgoing bool
+	}
+	rows, err := s.pool.Query(ctx, `
+		SELECT DISTINCT COALESCE(sender_id, ''), source_uuid, is_outgoing
+		FROM messages
+		WHERE person_id IS NULL
+	`)
+	if err != nil {
+		return 0, err
+	}
+	var senders []sender
+	for rows.Next() {
+		var snd sender
+		if err := rows.Scan(&snd.id, &snd.uuid, &snd.outgoing); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		senders = append(senders, snd)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, snd := range senders {
+		personID, err := s.ResolvePerson(ctx, snd.id, snd.uuid, snd.outgoing)
+		if err != nil {
+			return total, fmt.Errorf("resolve sender %s: %w", ShortID(snd.id), err)
+		}
+		if personID == "" {
+			continue
+		}
+		tag, err := s.pool.Exec(ctx, `
+			UPDATE messages SET person_id = $1
+			WHERE person_id IS NULL
+			AND COALESCE(sender_id, '') = $2
+			AND source_uuid IS NOT DISTINCT FROM $3
+			AND is_outgoing = $4
+		`, personID, snd.id, snd.uuid, snd.outgoing)
+		if err != nil {
+			return total, fmt.Errorf("backfill messages: %w", err)
+		}
+		total += tag.RowsAffected()
+	}
+	return total, nil
+}
+
+// ListPersons returns every person with their identifiers, oldest first.
+func (s *Store) ListPersons(ctx context.Context) ([]Person, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT p.id, p.is_self, p.created_at,
+			COALESCE(array_agg(i.kind ORDER BY i.kind, i.value) FILTER (WHERE i.kind IS NOT NULL), '{}'),
+			COALESCE(array_agg(i.value ORDER BY i.kind, i.value) FILTER (WHERE i.kind IS NOT NULL), '{}')
+		FROM persons p
+		LEFT JOIN person_identifiers i ON i.person_id = p.id
+		GROUP BY p.id
+		ORDER BY p.created_at
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var persons []Person
+	for rows.Next() {
+		var p Person
+		var kinds, values []string
+		if err := rows.Scan(&p.ID, &p.IsSelf, &p.CreatedAt, &kinds, &values); err != nil {
+			return nil, err
+		}
+		p.Identifiers = make([]PersonIdentifier, len(kinds))
+		for i := range kinds {
+			p.Identifiers[i] = PersonIdentifier{Kind: kinds[i], Value: values[i]}
+		}
+		persons = append(persons, p)
+	}
+	return persons, rows.Err()
+}
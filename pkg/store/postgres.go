@@ -11,7 +11,10 @@ import (
 )
 
 type Store struct {
-	pool *pgxpool.Pool
+	pool           *pgxpool.Pool
+	searchLangs    []string
+	tsConfigs      map[string]bool
+	embeddingModel string
 }
 
 func NewStore(ctx context.Context, connString string) (*Store, error) {
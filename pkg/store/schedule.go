@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// GetScheduleRun returns the last run of job, or nil if it never ran.
+func (s *Store) GetScheduleRun(ctx context.Context, job string) (*ScheduleRun, error) {
+	var r ScheduleRun
+	err := s.pool.QueryRow(ctx, `
+		SELECT job, scheduled_for, started_at, finished_at, last_error
+		FROM schedule_runs WHERE job = $1
+	`, job).Scan(&r.Job, &r.ScheduledFor, &r.StartedAt, &r.FinishedAt, &r.LastError)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *Store) SaveScheduleRun(ctx context.Context, r ScheduleRun) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO schedule_runs (job, scheduled_for, started_at, finished_at, last_error)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (job) DO UPDATE SET
+			scheduled_for = EXCLUDED.scheduled_for,
+			started_at = EXCLUDED.started_at,
+			finished_at = EXCLUDED.finished_at,
+			last_error = EXCLUDED.last_error,
+			updated_at = now()
+	`, r.Job, r.ScheduledFor, r.StartedAt, r.FinishedAt, r.LastError)
+	return err
+}
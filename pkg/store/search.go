@@ -0,0 +1,420 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SearchTypes lists the result types UnifiedSearch can return, in facet order.
+var SearchTypes = []string{"message", "media", "link", "digest", "concept"}
+
+// SearchHit is one typed, ranked result from UnifiedSearch. Data holds the
+// underlying record (SearchResult, AttachmentRecord, URLRecord, DigestRecord
+// or CerebroConcept) so clients can render type-specific detail.
+type SearchHit struct {
+	Type      string    `json:"type"`
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Snippet   string    `json:"snippet"`
+	Rank      float32   `json:"rank"`
+	GroupID   *string   `json:"group_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type UnifiedSearchResult struct {
+	Results []SearchHit    `json:"results"`
+	Facets  map[string]int `json:"facets"`
+	Total   int            `json:"total"`
+}
+
+// UnifiedSearch runs query against every requested type (all when types is
+// empty), merges the hits by rank and reports per-type match counts as facets.
+// Each type's ranks are scaled so its best hit scores 1: ts_rank and trigram
+// similarity are not on comparable scales. Sender and media filters only
+// narrow types that belong to a message.
+func (s *Store) UnifiedSearch(ctx context.Context, query string, types []string, filter SearchFilter, limit int) (*UnifiedSearchResult, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	want := make(map[string]bool)
+	for _, t := range types {
+		want[strings.TrimSpace(t)] = true
+	}
+
+	result := &UnifiedSearchResult{Results: []SearchHit{}, Facets: make(map[string]int)}
+	for _, t := range SearchTypes {
+		if len(want) > 0 && !want[t] {
+			continue
+		}
+
+		var hits []SearchHit
+		var count int
+		var err error
+		switch t {
+		case "message":
+			hits, count, err = s.searchMessageHits(ctx, query, filter, limit)
+		case "media":
+			hits, count, err = s.searchMediaHits(ctx, query, filter, limit)
+		case "link":
+			hits, count, err = s.searchLinkHits(ctx, query, filter, limit)
+		case "digest":
+			hits, count, err = s.searchDigestHits(ctx, query, filter, limit)
+		case "concept":
+			hits, count, err = s.searchConceptHits(ctx, query, filter, limit)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("search %s: %w", t, err)
+		}
+		normalizeRanks(hits)
+		result.Facets[t] = count
+		result.Total += count
+		result.Results = append(result.Results, hits...)
+	}
+
+	sort.SliceStable(result.Results, func(i, j int) bool {
+		a, b := result.Results[i], result.Results[j]
+		if a.Rank != b.Rank {
+			return a.Rank > b.Rank
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	})
+	if len(result.Results) > limit {
+		result.Results = result.Results[:limit]
+	}
+	return result, nil
+}
+
+// normalizeRanks divides one type's ranks by its top rank.
+func normalizeRanks(hits []SearchHit) {
+	var top float32
+	for _, h := range hits {
+		if h.Rank > top {
+			top = h.Rank
+		}
+	}
+	if top <= 0 {
+		return
+	}
+	for i := range hits {
+		hits[i].Rank /= top
+	}
+}
+
+func (s *Store) searchMessageHits(ctx context.Context, query string, filter SearchFilter, limit int) ([]SearchHit, int, error) {
+	results, err := s.FilteredFullTextSearch(ctx, query, filter, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids, err := s.LoadIdentities(ctx)
+	if err != nil {
+		log.Printf("Search: failed to load contact names: %v", err)
+	}
+
+	var hits []SearchHit
+	for _, r := range results {
+		hit := SearchHit{
+			Type:      "message",
+			ID:        r.ID,
+			Title:     ids.Sender(r.SenderID, r.SourceUUID, r.IsOutgoing),
+			Snippet:   snippet(r.Content),
+			GroupID:   r.GroupID,
+			CreatedAt: r.CreatedAt,
+			Data:      r,
+		}
+		if r.Rank != nil {
+			hit.Rank = *r.Rank
+		}
+		hits = append(hits, hit)
+	}
+
+	fuzzy := len(results) > 0 && results[0].Fuzzy
+	match := "m.tsv @@ " + s.tsQuery("$1", filter.Language)
+	if fuzzy {
+		match = "$1 <% m.content"
+	}
+	conditions, args := appendSearchFilter([]string{match}, []any{query}, filter)
+
+	var count int
+	countQuery := "SELECT COUNT(*) FROM messages m WHERE " + strings.Join(conditions, " AND ")
+	if err := s.pool.QueryRow(ctx, countQuery, args...).Scan(&count); err != nil {
+		return nil, 0, err
+	}
+	return hits, count, nil
+}
+
+func (s *Store) searchMediaHits(ctx context.Context, query string, filter SearchFilter, limit int) ([]SearchHit, int, error) {
+	// Vision analyses are generated in English, so analysis_tsv stays english.
+	conditions, args := appendSearchFilter(nil, []any{query}, filter)
+	where := fmt.Sprintf(`analysis_tsv @@ plainto_tsquery('english', $1)
+		AND message_id IN (SELECT m.id FROM messages m WHERE %s)`, strings.Join(conditions, " AND "))
+
+	var count int
+	if err := s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM attachments WHERE "+where, args...).Scan(&count); err != nil {
+		return nil, 0, err
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT %s, ts_rank(analysis_tsv, plainto_tsquery('english', $1)) AS rank
+		FROM attachments
+		WHERE %s
+		ORDER BY rank DESC
+		LIMIT $%d
+	`, attachmentCols, where, len(args)+1)
+	rows, err := s.pool.Query(ctx, sqlQuery, append(args, limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var a AttachmentRecord
+		var rank float32
+		if err := rows.Scan(
+			&a.ID, &a.MessageID, &a.SignalAttachmentID, &a.ContentType, &a.Filename, &a.Size,
+			&a.LocalPath, &a.Downloaded, &a.ThumbnailPath, &a.Analyzed, &a.Analysis, &a.CreatedAt,
+			&rank,
+		); err != nil {
+			return nil, 0, err
+		}
+		title, text := analysisText(a.Analysis)
+		if title == "" {
+			title = a.Filename
+		}
+		hits = append(hits, SearchHit{
+			Type:      "media",
+			ID:        a.ID,
+			Title:     title,
+			Snippet:   snippet(text),
+			Rank:      rank,
+			CreatedAt: a.CreatedAt,
+			Data:      a,
+		})
+	}
+	return hits, count, nil
+}
+
+func (s *Store) searchLinkHits(ctx context.Context, query string, filter SearchFilter, limit int) ([]SearchHit, int, error) {
+	tsq := s.tsQuery("$1", filter.Language)
+	conditions, args := appendSearchFilter([]string{"doc @@ " + tsq}, []any{query}, filter)
+	from := fmt.Sprintf(`urls u
+		JOIN messages m ON u.message_id = m.id
+		CROSS JOIN LATERAL to_tsvector('%s', COALESCE(u.title, '') || ' ' || COALESCE(u.description, '') || ' ' || u.url) AS doc`,
+		s.DefaultSearchLanguage())
+	where := strings.Join(conditions, " AND ")
+
+	var count int
+	if err := s.pool.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", from, where), args...).Scan(&count); err != nil {
+		return nil, 0, err
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT u.id, u.message_id, u.url, u.domain, COALESCE(u.title,''), COALESCE(u.description,''),
+			COALESCE(u.image_url,''), u.fetched, u.created_at, m.group_id,
+			ts_rank(doc, %s) AS rank
+		FROM %s
+		WHERE %s
+		ORDER BY rank DESC
+		LIMIT $%d
+	`, tsq, from, where, len(args)+1)
+	rows, err := s.pool.Query(ctx, sqlQuery, append(args, limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var u URLRecord
+		var groupID *string
+		var rank float32
+		if err := rows.Scan(
+			&u.ID, &u.MessageID, &u.URL, &u.Domain, &u.Title, &u.Description,
+			&u.ImageURL, &u.Fetched, &u.CreatedAt, &groupID, &rank,
+		); err != nil {
+			return nil, 0, err
+		}
+		title := u.Title
+		if title == "" {
+			title = u.URL
+		}
+		hits = append(hits, SearchHit{
+			Type:      "link",
+			ID:        u.ID,
+			Title:     title,
+			Snippet:   snippet(u.Description),
+			Rank:      rank,
+			GroupID:   groupID,
+			CreatedAt: u.CreatedAt,
+			Data:      u,
+		})
+	}
+	return hits, count, nil
+}
+
+func (s *Store) searchDigestHits(ctx context.Context, query string, filter SearchFilter, limit int) ([]SearchHit, int, error) {
+	tsq := s.tsQuery("$1", filter.Language)
+	conditions := []string{"doc @@ " + tsq}
+	args := []any{query}
+	if filter.GroupID != nil {
+		args = append(args, *filter.GroupID)
+		conditions = append(conditions, fmt.Sprintf("d.group_id = $%d", len(args)))
+	}
+	if filter.After != nil {
+		args = append(args, *filter.After)
+		conditions = append(conditions, fmt.Sprintf("d.period_end > $%d", len(args)))
+	}
+	if filter.Before != nil {
+		args = append(args, *filter.Before)
+		conditions = append(conditions, fmt.Sprintf("d.period_start < $%d", len(args)))
+	}
+	from := fmt.Sprintf(`digests d
+		CROSS JOIN LATERAL to_tsvector('%s', COALESCE(d.title, '') || ' ' || COALESCE(d.summary, '') || ' ' || COALESCE(d.topics::text, '')) AS doc`,
+		s.DefaultSearchLanguage())
+	where := strings.Join(conditions, " AND ")
+
+	var count int
+	if err := s.pool.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", from, where), args...).Scan(&count); err != nil {
+		return nil, 0, err
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT d.id, d.title, d.summary, d.topics, d.decisions, d.action_items,
+			d.period_start, d.period_end, d.period_kind, d.group_id, d.llm_provider, d.llm_model, d.token_count, d.created_at,
+			ts_rank(doc, %s) AS rank
+		FROM %s
+		WHERE %s
+		ORDER BY rank DESC
+		LIMIT $%d
+	`, tsq, from, where, len(args)+1)
+	rows, err := s.pool.Query(ctx, sqlQuery, append(args, limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var d DigestRecord
+		var rank float32
+		if err := rows.Scan(
+			&d.ID, &d.Title, &d.Summary, &d.Topics, &d.Decisions, &d.ActionItems,
+			&d.PeriodStart, &d.PeriodEnd, &d.PeriodKind, &d.GroupID, &d.LLMProvider, &d.LLMModel,
+			&d.TokenCount, &d.CreatedAt, &rank,
+		); err != nil {
+			return nil, 0, err
+		}
+		hits = append(hits, SearchHit{
+			Type:      "digest",
+			ID:        d.ID,
+			Title:     d.Title,
+			Snippet:   snippet(d.Summary),
+			Rank:      rank,
+			GroupID:   d.GroupID,
+			CreatedAt: d.CreatedAt,
+			Data:      d,
+		})
+	}
+	return hits, count, nil
+}
+
+func (s *Store) searchConceptHits(ctx context.Context, query string, filter SearchFilter, limit int) ([]SearchHit, int, error) {
+	tsq := s.tsQuery("$1", filter.Language)
+	conditions := []string{"doc @@ " + tsq}
+	args := []any{query}
+	if filter.GroupID != nil {
+		args = append(args, *filter.GroupID)
+		conditions = append(conditions, fmt.Sprintf("c.group_id = $%d", len(args)))
+	}
+	if filter.After != nil {
+		args = append(args, *filter.After)
+		conditions = append(conditions, fmt.Sprintf("c.last_seen > $%d", len(args)))
+	}
+	if filter.Before != nil {
+		args = append(args, *filter.Before)
+		conditions = append(conditions, fmt.Sprintf("c.first_seen < $%d", len(args)))
+	}
+	from := fmt.Sprintf(`cerebro_concepts c
+		CROSS JOIN LATERAL to_tsvector('%s', c.name || ' ' || c.description) AS doc`,
+		s.DefaultSearchLanguage())
+	where := strings.Join(conditions, " AND ")
+
+	var count int
+	if err := s.pool.QueryRow(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", from, where), args...).Scan(&count); err != nil {
+		return nil, 0, err
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT c.id, c.name, c.category, c.description, c.mention_count, c.first_seen, c.last_seen,
+			c.metadata, c.group_id, c.created_at,
+			ts_rank(doc, %s) AS rank
+		FROM %s
+		WHERE %s
+		ORDER BY rank DESC, c.mention_count DESC
+		LIMIT $%d
+	`, tsq, from, where, len(args)+1)
+	rows, err := s.pool.Query(ctx, sqlQuery, append(args, limit)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var hits []SearchHit
+	for rows.Next() {
+		var c CerebroConcept
+		var rank float32
+		if err := rows.Scan(&c.ID, &c.Name, &c.Category, &c.Description, &c.MentionCount,
+			&c.FirstSeen, &c.LastSeen, &c.Metadata, &c.GroupID, &c.CreatedAt, &rank); err != nil {
+			return nil, 0, err
+		}
+		hits = append(hits, SearchHit{
+			Type:      "concept",
+			ID:        c.ID,
+			Title:     c.Name,
+			Snippet:   snippet(c.Description),
+			Rank:      rank,
+			GroupID:   c.GroupID,
+			CreatedAt: c.LastSeen,
+			Data:      c,
+		})
+	}
+	return hits, count, nil
+}
+
+// analysisText pulls the description and the searchable remainder (objects,
+// visible text, scene) out of a vision analysis.
+func analysisText(analysis []byte) (string, string) {
+	var a struct {
+		Description string `json:"description"`
+		TextContent string `json:"text_content"`
+		Objects     string `json:"objects"`
+		Scene       string `json:"scene"`
+	}
+	if len(analysis) == 0 || json.Unmarshal(analysis, &a) != nil {
+		return "", ""
+	}
+	var parts []string
+	for _, p := range []string{a.Objects, a.TextContent, a.Scene} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return a.Description, strings.Join(parts, " · ")
+}
+
+func snippet(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	r := []rune(s)
+	if len(r) <= 200 {
+		return s
+	}
+	return string(r[:200]) + "…"
+}
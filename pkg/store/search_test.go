@@ -0,0 +1,17 @@
+package store
+
+import "testing"
+
+func TestNormalizeRanks(t *testing.T) {
+	hits := []SearchHit{{Rank: 0.05}, {Rank: 0.1}, {Rank: 0.025}}
+	normalizeRanks(hits)
+	if hits[0].Rank != 0.5 || hits[1].Rank != 1 || hits[2].Rank != 0.25 {
+		t.Errorf("got %v, %v, %v", hits[0].Rank, hits[1].Rank, hits[2].Rank)
+	}
+
+	zero := []SearchHit{{Rank: 0}}
+	normalizeRanks(zero)
+	if zero[0].Rank != 0 {
+		t.Errorf("zero rank changed: %v", zero[0].Rank)
+	}
+}
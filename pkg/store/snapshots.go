@@ -3,10 +3,13 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"log"
 	"time"
 )
 
-func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySnapshot, error) {
+// ComputeDaySnapshot computes the stats of date's day for the group, or for
+// all groups when groupID is nil.
+func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time, groupID *string) (*DaySnapshot, error) {
 	loc := date.Location()
 	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
 	dayEnd := dayStart.Add(24 * time.Hour)
@@ -17,11 +20,12 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 
 	// 1. Message count + active senders
 	err := s.pool.QueryRow(ctx, `
-		SELECT COUNT(*), COUNT(DISTINCT sender_id)
+		SELECT COUNT(*), COUNT(DISTINCT COALESCE(person_id::text, sender_id))
 		FROM messages
 		WHERE created_at >= $1 AND created_at < $2
 		AND (expires_at IS NULL OR expires_at > now())
-	`, dayStart, dayEnd).Scan(&snap.MessageCount, &snap.ActiveSenders)
+		AND ($3::text IS NULL OR group_id = $3 OR ($3 = $4 AND group_id IS NULL))
+	`, dayStart, dayEnd, groupID, DirectGroupID).Scan(&snap.MessageCount, &snap.ActiveSenders)
 	if err != nil {
 		return nil, err
 	}
@@ -36,10 +40,11 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 		FROM messages
 		WHERE created_at >= $1 AND created_at < $2
 		AND (expires_at IS NULL OR expires_at > now())
+		AND ($3::text IS NULL OR group_id = $3 OR ($3 = $4 AND group_id IS NULL))
 		GROUP BY 1
 		ORDER BY COUNT(*) DESC
 		LIMIT 1
-	`, dayStart, dayEnd).Scan(&snap.BusiestHour)
+	`, dayStart, dayEnd, groupID, DirectGroupID).Scan(&snap.BusiestHour)
 
 	// 3. Time-of-day crews
 	type crewRange struct {
@@ -60,23 +65,24 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 			name = "night"
 		}
 		rows, err := s.pool.Query(ctx, `
-			SELECT sender_id, COUNT(*) as cnt
+			SELECT COALESCE(person_id::text, sender_id) AS who, MAX(sender_id), COUNT(*) as cnt
 			FROM messages
 			WHERE created_at >= $1 AND created_at < $2
-			AND EXTRACT(HOUR FROM created_at) >= $3
-			AND EXTRACT(HOUR FROM created_at) < $4
+			AND EXTRACT(HOUR FROM created_at) >= $4
+			AND EXTRACT(HOUR FROM created_at) < $5
 			AND (expires_at IS NULL OR expires_at > now())
-			GROUP BY sender_id
+			AND ($3::text IS NULL OR group_id = $3 OR ($3 = $6 AND group_id IS NULL))
+			GROUP BY who
 			ORDER BY cnt DESC
 			LIMIT 5
-		`, dayStart, dayEnd, cr.hourStart, cr.hourEnd)
+		`, dayStart, dayEnd, groupID, cr.hourStart, cr.hourEnd, DirectGroupID)
 		if err != nil {
 			continue
 		}
 		var members []CrewMember
 		for rows.Next() {
 			var m CrewMember
-			if err := rows.Scan(&m.SenderID, &m.Count); err != nil {
+			if err := rows.Scan(&m.PersonID, &m.SenderID, &m.Count); err != nil {
 				continue
 			}
 			members = append(members, m)
@@ -89,19 +95,20 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 
 	// Night crew part 2: 0-6
 	rows, err := s.pool.Query(ctx, `
-		SELECT sender_id, COUNT(*) as cnt
+		SELECT COALESCE(person_id::text, sender_id) AS who, MAX(sender_id), COUNT(*) as cnt
 		FROM messages
 		WHERE created_at >= $1 AND created_at < $2
 		AND EXTRACT(HOUR FROM created_at) < 6
 		AND (expires_at IS NULL OR expires_at > now())
-		GROUP BY sender_id
+		AND ($3::text IS NULL OR group_id = $3 OR ($3 = $4 AND group_id IS NULL))
+		GROUP BY who
 		ORDER BY cnt DESC
 		LIMIT 5
-	`, dayStart, dayEnd)
+	`, dayStart, dayEnd, groupID, DirectGroupID)
 	if err == nil {
 		for rows.Next() {
 			var m CrewMember
-			if err := rows.Scan(&m.SenderID, &m.Count); err != nil {
+			if err := rows.Scan(&m.PersonID, &m.SenderID, &m.Count); err != nil {
 				continue
 			}
 			snap.Crews["night"] = append(snap.Crews["night"], m)
@@ -111,31 +118,39 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 
 	// 4. Conversation pairs — consecutive messages within 5 min
 	pairRows, err := s.pool.Query(ctx, `
-		WITH ordered AS (
-			SELECT sender_id,
-				LAG(sender_id) OVER (ORDER BY created_at) AS prev_sender,
-				created_at,
-				LAG(created_at) OVER (ORDER BY created_at) AS prev_ts
+		WITH authored AS (
+			SELECT COALESCE(person_id::text, sender_id) AS who, sender_id, created_at
 			FROM messages
 			WHERE created_at >= $1 AND created_at < $2
 			AND (expires_at IS NULL OR expires_at > now())
+			AND ($3::text IS NULL OR group_id = $3 OR ($3 = $4 AND group_id IS NULL))
+		),
+		ordered AS (
+			SELECT who, sender_id,
+				LAG(who) OVER (ORDER BY created_at) AS prev_who,
+				LAG(sender_id) OVER (ORDER BY created_at) AS prev_sender,
+				created_at,
+				LAG(created_at) OVER (ORDER BY created_at) AS prev_ts
+			FROM authored
 		)
 		SELECT
-			LEAST(sender_id, prev_sender) AS a,
-			GREATEST(sender_id, prev_sender) AS b,
+			LEAST(who, prev_who) AS a,
+			GREATEST(who, prev_who) AS b,
+			MAX(CASE WHEN who < prev_who THEN sender_id ELSE prev_sender END),
+			MAX(CASE WHEN who < prev_who THEN prev_sender ELSE sender_id END),
 			COUNT(*) AS cnt
 		FROM ordered
-		WHERE prev_sender IS NOT NULL
-		AND sender_id != prev_sender
+		WHERE prev_who IS NOT NULL
+		AND who != prev_who
 		AND created_at - prev_ts < INTERVAL '5 minutes'
 		GROUP BY a, b
 		ORDER BY cnt DESC
 		LIMIT 5
-	`, dayStart, dayEnd)
+	`, dayStart, dayEnd, groupID, DirectGroupID)
 	if err == nil {
 		for pairRows.Next() {
 			var p ConversationPair
-			if err := pairRows.Scan(&p.SenderA, &p.SenderB, &p.Count); err != nil {
+			if err := pairRows.Scan(&p.PersonA, &p.PersonB, &p.SenderA, &p.SenderB, &p.Count); err != nil {
 				continue
 			}
 			snap.TopPairs = append(snap.TopPairs, p)
@@ -146,14 +161,16 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 	// 5. Verb leader — count words from a verb list per sender
 	verbRow := s.pool.QueryRow(ctx, `
 		WITH words AS (
-			SELECT sender_id, unnest(regexp_split_to_array(lower(content), '\s+')) AS word
+			SELECT COALESCE(person_id::text, sender_id) AS who, sender_id,
+				unnest(regexp_split_to_array(lower(content), '\s+')) AS word
 			FROM messages
 			WHERE created_at >= $1 AND created_at < $2
 			AND content != ''
 			AND (expires_at IS NULL OR expires_at > now())
+			AND ($3::text IS NULL OR group_id = $3 OR ($3 = $4 AND group_id IS NULL))
 		),
 		verb_counts AS (
-			SELECT sender_id, word, COUNT(*) AS cnt
+			SELECT who, MAX(sender_id) AS sender_id, word, COUNT(*) AS cnt
 			FROM words
 			WHERE word IN (
 				'said','think','went','believe','know','want','need','feel','see','make',
@@ -167,40 +184,41 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 				'produce','eat','cover','catch','draw','choose','cause','point','listen','plan',
 				'notice','enjoy','wonder','love','hate','remember','consider','appear','expect','wish'
 			)
-			GROUP BY sender_id, word
+			GROUP BY who, word
 		),
 		leader AS (
-			SELECT sender_id, SUM(cnt)::int AS total,
+			SELECT who, MAX(sender_id) AS sender_id, SUM(cnt)::int AS total,
 				array_agg(word ORDER BY cnt DESC) AS top_words
 			FROM verb_counts
-			GROUP BY sender_id
+			GROUP BY who
 			ORDER BY total DESC
 			LIMIT 1
 		)
-		SELECT sender_id, total, top_words[1:5]
+		SELECT who, sender_id, total, top_words[1:5]
 		FROM leader
-	`, dayStart, dayEnd)
+	`, dayStart, dayEnd, groupID, DirectGroupID)
 
 	var vl VerbLeader
 	var samples []string
-	if err := verbRow.Scan(&vl.SenderID, &vl.Count, &samples); err == nil {
+	if err := verbRow.Scan(&vl.PersonID, &vl.SenderID, &vl.Count, &samples); err == nil {
 		vl.Samples = samples
 		snap.VerbLeader = &vl
 	}
 
 	// 6. Link of the day — prefer fetched links with titles
 	linkRow := s.pool.QueryRow(ctx, `
-		SELECT u.url, COALESCE(u.title, ''), m.sender_id
+		SELECT u.url, COALESCE(u.title, ''), m.sender_id, COALESCE(m.person_id::text, m.sender_id)
 		FROM urls u
 		JOIN messages m ON u.message_id = m.id
 		WHERE m.created_at >= $1 AND m.created_at < $2
 		AND (m.expires_at IS NULL OR m.expires_at > now())
+		AND ($3::text IS NULL OR m.group_id = $3 OR ($3 = $4 AND m.group_id IS NULL))
 		ORDER BY u.fetched DESC, u.created_at DESC
 		LIMIT 1
-	`, dayStart, dayEnd)
+	`, dayStart, dayEnd, groupID, DirectGroupID)
 
 	var ld LinkOfDay
-	if err := linkRow.Scan(&ld.URL, &ld.Title, &ld.SenderID); err == nil {
+	if err := linkRow.Scan(&ld.URL, &ld.Title, &ld.SenderID, &ld.PersonID); err == nil {
 		snap.LinkOfDay = &ld
 	}
 
@@ -209,10 +227,10 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 	var prevSnapshot json.RawMessage
 	err = s.pool.QueryRow(ctx, `
 		SELECT snapshot FROM daily_insights
-		WHERE snapshot_date = $1
+		WHERE snapshot_date = $1 AND group_id IS NOT DISTINCT FROM $2
 		AND snapshot IS NOT NULL AND snapshot != '{}'::jsonb
 		ORDER BY created_at DESC LIMIT 1
-	`, yesterday).Scan(&prevSnapshot)
+	`, yesterday, groupID).Scan(&prevSnapshot)
 	if err == nil && len(prevSnapshot) > 2 {
 		var prevSnap DaySnapshot
 		if json.Unmarshal(prevSnapshot, &prevSnap) == nil {
@@ -223,9 +241,9 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 			var prevQuote string
 			_ = s.pool.QueryRow(ctx, `
 				SELECT quote_content FROM daily_insights
-				WHERE snapshot_date = $1 AND quote_content != ''
+				WHERE snapshot_date = $1 AND group_id IS NOT DISTINCT FROM $2 AND quote_content != ''
 				ORDER BY created_at DESC LIMIT 1
-			`, yesterday).Scan(&prevQuote)
+			`, yesterday, groupID).Scan(&prevQuote)
 			if prevQuote != "" {
 				ref.Quote = prevQuote
 				hasRef = true
@@ -242,10 +260,36 @@ func (s *Store) ComputeDaySnapshot(ctx context.Context, date time.Time) (*DaySna
 		}
 	}
 
+	ids, err := s.LoadIdentities(ctx)
+	if err != nil {
+		log.Printf("Snapshot: failed to load contact names: %v", err)
+	}
+	nameSnapshot(snap, ids)
+
 	return snap, nil
 }
 
-func (s *Store) ComputeWeeklyExtras(ctx context.Context, sundayDate time.Time) (weeklyTotal int, busiestDay string, busiestDayCount int) {
+// nameSnapshot fills in display names next to every person.
+func nameSnapshot(snap *DaySnapshot, ids *Identities) {
+	for crew, members := range snap.Crews {
+		for i := range members {
+			members[i].Name = ids.Name(members[i].PersonID)
+		}
+		snap.Crews[crew] = members
+	}
+	for i := range snap.TopPairs {
+		snap.TopPairs[i].NameA = ids.Name(snap.TopPairs[i].PersonA)
+		snap.TopPairs[i].NameB = ids.Name(snap.TopPairs[i].PersonB)
+	}
+	if snap.VerbLeader != nil {
+		snap.VerbLeader.Name = ids.Name(snap.VerbLeader.PersonID)
+	}
+	if snap.LinkOfDay != nil {
+		snap.LinkOfDay.Name = ids.Name(snap.LinkOfDay.PersonID)
+	}
+}
+
+func (s *Store) ComputeWeeklyExtras(ctx context.Context, sundayDate time.Time, groupID *string) (weeklyTotal int, busiestDay string, busiestDayCount int) {
 	loc := sundayDate.Location()
 	// Monday of this week
 	weekStart := time.Date(sundayDate.Year(), sundayDate.Month(), sundayDate.Day(), 0, 0, 0, 0, loc)
@@ -257,7 +301,8 @@ func (s *Store) ComputeWeeklyExtras(ctx context.Context, sundayDate time.Time) (
 		SELECT COUNT(*) FROM messages
 		WHERE created_at >= $1 AND created_at < $2
 		AND (expires_at IS NULL OR expires_at > now())
-	`, weekStart, weekEnd).Scan(&weeklyTotal)
+		AND ($3::text IS NULL OR group_id = $3 OR ($3 = $4 AND group_id IS NULL))
+	`, weekStart, weekEnd, groupID, DirectGroupID).Scan(&weeklyTotal)
 
 	// Busiest day
 	var busiestDate time.Time
@@ -266,10 +311,11 @@ func (s *Store) ComputeWeeklyExtras(ctx context.Context, sundayDate time.Time) (
 		FROM messages
 		WHERE created_at >= $1 AND created_at < $2
 		AND (expires_at IS NULL OR expires_at > now())
+		AND ($3::text IS NULL OR group_id = $3 OR ($3 = $4 AND group_id IS NULL))
 		GROUP BY d
 		ORDER BY cnt DESC
 		LIMIT 1
-	`, weekStart, weekEnd).Scan(&busiestDate, &busiestDayCount)
+	`, weekStart, weekEnd, groupID, DirectGroupID).Scan(&busiestDate, &busiestDayCount)
 
 	if !busiestDate.IsZero() {
 		busiestDay = busiestDate.Format("Monday")
@@ -278,16 +324,16 @@ func (s *Store) ComputeWeeklyExtras(ctx context.Context, sundayDate time.Time) (
 	return
 }
 
-func (s *Store) GetDailySnapshots(ctx context.Context, days int) ([]DailyInsight, error) {
+// GetDailySnapshots returns the insights with snapshots of the group (nil
+// for those covering all groups), newest first.
+func (s *Store) GetDailySnapshots(ctx context.Context, days int, groupID *string) ([]DailyInsight, error) {
 	rows, err := s.pool.Query(ctx, `
-		SELECT id, overview, themes, COALESCE(quote_content,''), COALESCE(quote_sender,''),
-			quote_created_at, COALESCE(image_path,''), COALESCE(superlatives, '[]'::jsonb),
-			COALESCE(snapshot, '{}'::jsonb), snapshot_date, created_at
+		SELECT `+insightColumns+`
 		FROM daily_insights
-		WHERE snapshot_date IS NOT NULL
+		WHERE snapshot_date IS NOT NULL AND group_id IS NOT DISTINCT FROM $2
 		ORDER BY snapshot_date DESC
 		LIMIT $1
-	`, days)
+	`, days, groupID)
 	if err != nil {
 		return nil, err
 	}
@@ -299,7 +345,7 @@ func (s *Store) GetDailySnapshots(ctx context.Context, days int) ([]DailyInsight
 		if err := rows.Scan(
 			&di.ID, &di.Overview, &di.Themes, &di.QuoteContent, &di.QuoteSender,
 			&di.QuoteCreatedAt, &di.ImagePath, &di.Superlatives,
-			&di.Snapshot, &di.SnapshotDate, &di.CreatedAt,
+			&di.Snapshot, &di.SnapshotDate, &di.GroupID, &di.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -6,12 +6,19 @@ import (
 	"time"
 )
 
-func (s *Store) GetStats(ctx context.Context) (*Stats, error) {
+// insightColumns are the daily_insights columns in DailyInsight field order.
+const insightColumns = `id, overview, themes, COALESCE(quote_content,''), COALESCE(quote_sender,''),
+	quote_created_at, COALESCE(image_path,''), COALESCE(superlatives, '[]'::jsonb),
+	COALESCE(snapshot, '{}'::jsonb), snapshot_date, group_id, created_at`
+
+// GetStats returns the dashboard stats for the group, or across all groups
+// when groupID is nil. The group count is always the total.
+func (s *Store) GetStats(ctx context.Context, groupID *string) (*Stats, error) {
 	stats := &Stats{}
 
 	// Total messages
 	err := s.pool.QueryRow(ctx,
-		"SELECT COUNT(*) FROM messages WHERE expires_at IS NULL OR expires_at > now()").
+		"SELECT COUNT(*) FROM messages WHERE (expires_at IS NULL OR expires_at > now()) AND ($1::text IS NULL OR group_id = $1 OR ($1 = $2 AND group_id IS NULL))", groupID, DirectGroupID).
 		Scan(&stats.TotalMessages)
 	if err != nil {
 		return nil, err
@@ -19,7 +26,7 @@ func (s *Store) GetStats(ctx context.Context) (*Stats, error) {
 
 	// Today's messages
 	err = s.pool.QueryRow(ctx,
-		"SELECT COUNT(*) FROM messages WHERE created_at >= CURRENT_DATE AND (expires_at IS NULL OR expires_at > now())").
+		"SELECT COUNT(*) FROM messages WHERE created_at >= CURRENT_DATE AND (expires_at IS NULL OR expires_at > now()) AND ($1::text IS NULL OR group_id = $1 OR ($1 = $2 AND group_id IS NULL))", groupID, DirectGroupID).
 		Scan(&stats.TodayMessages)
 	if err != nil {
 		return nil, err
@@ -32,28 +39,23 @@ func (s *Store) GetStats(ctx context.Context) (*Stats, error) {
 	}
 
 	// Total URLs
-	err = s.pool.QueryRow(ctx, "SELECT COUNT(*) FROM urls").Scan(&stats.TotalURLs)
+	err = s.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM urls u
+		WHERE $1::text IS NULL OR EXISTS (SELECT 1 FROM messages m WHERE m.id = u.message_id AND (m.group_id = $1 OR ($1 = $2 AND m.group_id IS NULL)))
+	`, groupID, DirectGroupID).Scan(&stats.TotalURLs)
 	if err != nil {
 		return nil, err
 	}
 
 	// Latest digest
-	var d DigestRecord
-	err = s.pool.QueryRow(ctx, `
-		SELECT id, title, summary, topics, decisions, action_items,
-			period_start, period_end, group_id, llm_provider, llm_model, token_count, created_at
-		FROM digests ORDER BY created_at DESC LIMIT 1
-	`).Scan(
-		&d.ID, &d.Title, &d.Summary, &d.Topics, &d.Decisions, &d.ActionItems,
-		&d.PeriodStart, &d.PeriodEnd, &d.GroupID, &d.LLMProvider, &d.LLMModel,
-		&d.TokenCount, &d.CreatedAt,
-	)
+	d, err := scanDigest(s.pool.QueryRow(ctx,
+		"SELECT "+digestColumns+" FROM digests WHERE $1::text IS NULL OR group_id = $1 ORDER BY created_at DESC LIMIT 1", groupID))
 	if err == nil {
-		stats.LatestDigest = &d
+		stats.LatestDigest = d
 	}
 
 	// Latest daily insight (includes cached superlatives)
-	insight, err := s.GetLatestInsight(ctx)
+	insight, err := s.GetLatestInsight(ctx, groupID)
 	if err == nil && insight != nil {
 		stats.DailyInsight = insight
 
@@ -68,23 +70,24 @@ func (s *Store) GetStats(ctx context.Context) (*Stats, error) {
 
 	// Fallback: compute live if no cached superlatives
 	if len(stats.Superlatives) == 0 {
-		stats.Superlatives = s.GetSuperlatives(ctx)
+		stats.Superlatives = s.GetSuperlatives(ctx, groupID)
 	}
 
 	return stats, nil
 }
 
-func (s *Store) GetLatestInsight(ctx context.Context) (*DailyInsight, error) {
+// GetLatestInsight returns the group's latest insight; a nil groupID means
+// the insights covering all groups.
+func (s *Store) GetLatestInsight(ctx context.Context, groupID *string) (*DailyInsight, error) {
 	var di DailyInsight
 	err := s.pool.QueryRow(ctx, `
-		SELECT id, overview, themes, COALESCE(quote_content,''), COALESCE(quote_sender,''),
-			quote_created_at, COALESCE(image_path,''), COALESCE(superlatives, '[]'::jsonb),
-			COALESCE(snapshot, '{}'::jsonb), snapshot_date, created_at
-		FROM daily_insights ORDER BY created_at DESC LIMIT 1
-	`).Scan(
+		SELECT `+insightColumns+`
+		FROM daily_insights WHERE group_id IS NOT DISTINCT FROM $1
+		ORDER BY created_at DESC LIMIT 1
+	`, groupID).Scan(
 		&di.ID, &di.Overview, &di.Themes, &di.QuoteContent, &di.QuoteSender,
 		&di.QuoteCreatedAt, &di.ImagePath, &di.Superlatives,
-		&di.Snapshot, &di.SnapshotDate, &di.CreatedAt,
+		&di.Snapshot, &di.SnapshotDate, &di.GroupID, &di.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -92,24 +95,25 @@ func (s *Store) GetLatestInsight(ctx context.Context) (*DailyInsight, error) {
 	return &di, nil
 }
 
-func (s *Store) SaveDailyInsight(ctx context.Context, overview string, themes json.RawMessage, quoteContent, quoteSender string, superlatives json.RawMessage, snapshot json.RawMessage, snapshotDate *time.Time) (string, error) {
+func (s *Store) SaveDailyInsight(ctx context.Context, overview string, themes json.RawMessage, quoteContent, quoteSender string, superlatives json.RawMessage, snapshot json.RawMessage, snapshotDate *time.Time, groupID *string) (string, error) {
 	query := `
-		INSERT INTO daily_insights (overview, themes, quote_content, quote_sender, superlatives, snapshot, snapshot_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO daily_insights (overview, themes, quote_content, quote_sender, superlatives, snapshot, snapshot_date, group_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id
 	`
 	var id string
-	err := s.pool.QueryRow(ctx, query, overview, themes, quoteContent, quoteSender, superlatives, snapshot, snapshotDate).Scan(&id)
+	err := s.pool.QueryRow(ctx, query, overview, themes, quoteContent, quoteSender, superlatives, snapshot, snapshotDate, groupID).Scan(&id)
 	return id, err
 }
 
-func (s *Store) GetLatestPicOfDay(ctx context.Context) (string, error) {
+func (s *Store) GetLatestPicOfDay(ctx context.Context, groupID *string) (string, error) {
 	var imagePath string
 	err := s.pool.QueryRow(ctx, `
 		SELECT image_path FROM daily_insights
 		WHERE image_path IS NOT NULL AND image_path != ''
+		AND group_id IS NOT DISTINCT FROM $1
 		ORDER BY created_at DESC LIMIT 1
-	`).Scan(&imagePath)
+	`, groupID).Scan(&imagePath)
 	return imagePath, err
 }
 
@@ -118,13 +122,14 @@ func (s *Store) SetInsightImagePath(ctx context.Context, id, imagePath string) e
 	return err
 }
 
-func (s *Store) GetRandomQuote(ctx context.Context) (string, string, error) {
+func (s *Store) GetRandomQuote(ctx context.Context, groupID *string) (string, string, error) {
 	var content, sender string
 	err := s.pool.QueryRow(ctx, `
 		SELECT content, sender_id FROM messages
 		WHERE content != '' AND LENGTH(content) > 20
 		AND (expires_at IS NULL OR expires_at > now())
+		AND ($1::text IS NULL OR group_id = $1 OR ($1 = $2 AND group_id IS NULL))
 		ORDER BY random() LIMIT 1
-	`).Scan(&content, &sender)
+	`, groupID, DirectGroupID).Scan(&content, &sender)
 	return content, sender, err
 }
@@ -6,8 +6,11 @@ import (
 	"log"
 )
 
-// GetSuperlatives computes fun stats from the last 30 days of messages.
-func (s *Store) GetSuperlatives(ctx context.Context) []Superlative {
+// GetSuperlatives computes fun stats from the last 30 days of messages in
+// the group, or in all groups when groupID is nil. Winners are persons, so someone seen under both a number and a UUID counts
+// once. MAX(sender_id) keeps one of their raw IDs, which for the account
+// owner is "self".
+func (s *Store) GetSuperlatives(ctx context.Context, groupID *string) []Superlative {
 	var results []Superlative
 
 	type query struct {
@@ -18,175 +21,195 @@ func (s *Store) GetSuperlatives(ctx context.Context) []Superlative {
 	}
 
 	// 1. The Novelist — longest single message
-	var novelistSender string
+	var novelistPerson, novelistSender string
 	var novelistLen int
 	err := s.pool.QueryRow(ctx, `
-		SELECT sender_id, LENGTH(content) as len
+		SELECT COALESCE(person_id::text, sender_id), sender_id, LENGTH(content) as len
 		FROM messages
 		WHERE LENGTH(content) > 0 AND created_at > NOW() - INTERVAL '30 days'
 		AND (expires_at IS NULL OR expires_at > now())
+		AND ($1::text IS NULL OR group_id = $1 OR ($1 = $2 AND group_id IS NULL))
 		ORDER BY LENGTH(content) DESC LIMIT 1
-	`).Scan(&novelistSender, &novelistLen)
+	`, groupID, DirectGroupID).Scan(&novelistPerson, &novelistSender, &novelistLen)
 	if err == nil {
 		results = append(results, Superlative{
-			Label:  "The Novelist",
-			Icon:   "fa-book-open",
-			Winner: novelistSender,
-			Value:  fmt.Sprintf("%d chars", novelistLen),
+			Label:    "The Novelist",
+			Icon:     "fa-book-open",
+			Winner:   novelistSender,
+			PersonID: novelistPerson,
+			Value:    fmt.Sprintf("%d chars", novelistLen),
 		})
 	}
 
 	// 2. The Chatterbox — most messages
-	var chatterSender string
+	var chatterPerson, chatterSender string
 	var chatterCount int
 	err = s.pool.QueryRow(ctx, `
-		SELECT sender_id, COUNT(*) as cnt
+		SELECT COALESCE(person_id::text, sender_id) AS who, MAX(sender_id), COUNT(*) as cnt
 		FROM messages
 		WHERE created_at > NOW() - INTERVAL '30 days'
 		AND (expires_at IS NULL OR expires_at > now())
-		GROUP BY sender_id ORDER BY cnt DESC LIMIT 1
-	`).Scan(&chatterSender, &chatterCount)
+		AND ($1::text IS NULL OR group_id = $1 OR ($1 = $2 AND group_id IS NULL))
+		GROUP BY who ORDER BY cnt DESC LIMIT 1
+	`, groupID, DirectGroupID).Scan(&chatterPerson, &chatterSender, &chatterCount)
 	if err == nil {
 		results = append(results, Superlative{
-			Label:  "The Chatterbox",
-			Icon:   "fa-comments",
-			Winner: chatterSender,
-			Value:  fmt.Sprintf("%d messages", chatterCount),
+			Label:    "The Chatterbox",
+			Icon:     "fa-comments",
+			Winner:   chatterSender,
+			PersonID: chatterPerson,
+			Value:    fmt.Sprintf("%d messages", chatterCount),
 		})
 	}
 
 	// 3. The Shutterbug — most media shared
-	var shutterSender string
+	var shutterPerson, shutterSender string
 	var shutterCount int
 	err = s.pool.QueryRow(ctx, `
-		SELECT m.sender_id, COUNT(*) as cnt
+		SELECT COALESCE(m.person_id::text, m.sender_id) AS who, MAX(m.sender_id), COUNT(*) as cnt
 		FROM attachments a JOIN messages m ON a.message_id = m.id
 		WHERE m.created_at > NOW() - INTERVAL '30 days'
 		AND (m.expires_at IS NULL OR m.expires_at > now())
-		GROUP BY m.sender_id ORDER BY cnt DESC LIMIT 1
-	`).Scan(&shutterSender, &shutterCount)
+		AND ($1::text IS NULL OR m.group_id = $1 OR ($1 = $2 AND m.group_id IS NULL))
+		GROUP BY who ORDER BY cnt DESC LIMIT 1
+	`, groupID, DirectGroupID).Scan(&shutterPerson, &shutterSender, &shutterCount)
 	if err == nil {
 		results = append(results, Superlative{
-			Label:  "The Shutterbug",
-			Icon:   "fa-image",
-			Winner: shutterSender,
-			Value:  fmt.Sprintf("%d attachments", shutterCount),
+			Label:    "The Shutterbug",
+			Icon:     "fa-image",
+			Winner:   shutterSender,
+			PersonID: shutterPerson,
+			Value:    fmt.Sprintf("%d attachments", shutterCount),
 		})
 	}
 
 	// 4. The Screamer — highest uppercase ratio (min 10 messages, min 10 char avg)
-	var screamerSender string
+	var screamerPerson, screamerSender string
 	var screamerRatio float64
 	err = s.pool.QueryRow(ctx, `
-		SELECT sender_id,
+		SELECT COALESCE(person_id::text, sender_id) AS who, MAX(sender_id),
 			SUM(LENGTH(REGEXP_REPLACE(content, '[^A-Z]', '', 'g')))::float /
 			GREATEST(SUM(LENGTH(content)), 1) as caps_ratio
 		FROM messages
 		WHERE LENGTH(content) > 10
 		AND created_at > NOW() - INTERVAL '30 days'
 		AND (expires_at IS NULL OR expires_at > now())
-		GROUP BY sender_id
+		AND ($1::text IS NULL OR group_id = $1 OR ($1 = $2 AND group_id IS NULL))
+		GROUP BY who
 		HAVING COUNT(*) > 5
 		ORDER BY caps_ratio DESC LIMIT 1
-	`).Scan(&screamerSender, &screamerRatio)
+	`, groupID, DirectGroupID).Scan(&screamerPerson, &screamerSender, &screamerRatio)
 	if err == nil {
 		results = append(results, Superlative{
-			Label:  "The Screamer",
-			Icon:   "fa-bell-ring",
-			Winner: screamerSender,
-			Value:  fmt.Sprintf("%.0f%% CAPS", screamerRatio*100),
+			Label:    "The Screamer",
+			Icon:     "fa-bell-ring",
+			Winner:   screamerSender,
+			PersonID: screamerPerson,
+			Value:    fmt.Sprintf("%.0f%% CAPS", screamerRatio*100),
 		})
 	}
 
 	// 5. The Minimalist — shortest average message length (min 10 messages)
-	var minSender string
+	var minPerson, minSender string
 	var minAvg int
 	err = s.pool.QueryRow(ctx, `
-		SELECT sender_id, AVG(LENGTH(content))::int as avg_len
+		SELECT COALESCE(person_id::text, sender_id) AS who, MAX(sender_id), AVG(LENGTH(content))::int as avg_len
 		FROM messages
 		WHERE content != ''
 		AND created_at > NOW() - INTERVAL '30 days'
 		AND (expires_at IS NULL OR expires_at > now())
-		GROUP BY sender_id
+		AND ($1::text IS NULL OR group_id = $1 OR ($1 = $2 AND group_id IS NULL))
+		GROUP BY who
 		HAVING COUNT(*) > 10
 		ORDER BY avg_len ASC LIMIT 1
-	`).Scan(&minSender, &minAvg)
+	`, groupID, DirectGroupID).Scan(&minPerson, &minSender, &minAvg)
 	if err == nil {
 		results = append(results, Superlative{
-			Label:  "The Minimalist",
-			Icon:   "fa-compress",
-			Winner: minSender,
-			Value:  fmt.Sprintf("avg %d chars", minAvg),
+			Label:    "The Minimalist",
+			Icon:     "fa-compress",
+			Winner:   minSender,
+			PersonID: minPerson,
+			Value:    fmt.Sprintf("avg %d chars", minAvg),
 		})
 	}
 
 	// 6. The Marathon — longest streak of consecutive messages
-	var streakSender string
+	var streakPerson, streakSender string
 	var streakLen int
 	err = s.pool.QueryRow(ctx, `
-		WITH ranked AS (
-			SELECT sender_id,
-				ROW_NUMBER() OVER (ORDER BY created_at) -
-				ROW_NUMBER() OVER (PARTITION BY sender_id ORDER BY created_at) AS grp
+		WITH authored AS (
+			SELECT COALESCE(person_id::text, sender_id) AS who, sender_id, created_at
 			FROM messages
 			WHERE created_at > NOW() - INTERVAL '30 days'
 			AND (expires_at IS NULL OR expires_at > now())
+			AND ($1::text IS NULL OR group_id = $1 OR ($1 = $2 AND group_id IS NULL))
+		),
+		ranked AS (
+			SELECT who, sender_id,
+				ROW_NUMBER() OVER (ORDER BY created_at) -
+				ROW_NUMBER() OVER (PARTITION BY who ORDER BY created_at) AS grp
+			FROM authored
 		),
 		streaks AS (
-			SELECT sender_id, COUNT(*) as streak_len
+			SELECT who, MAX(sender_id) AS sender_id, COUNT(*) as streak_len
 			FROM ranked
-			GROUP BY sender_id, grp
+			GROUP BY who, grp
 		)
-		SELECT sender_id, MAX(streak_len) as longest
+		SELECT who, MAX(sender_id), MAX(streak_len) as longest
 		FROM streaks
-		GROUP BY sender_id
+		GROUP BY who
 		ORDER BY longest DESC LIMIT 1
-	`).Scan(&streakSender, &streakLen)
+	`, groupID, DirectGroupID).Scan(&streakPerson, &streakSender, &streakLen)
 	if err == nil && streakLen > 1 {
 		results = append(results, Superlative{
-			Label:  "The Marathon",
-			Icon:   "fa-bolt",
-			Winner: streakSender,
-			Value:  fmt.Sprintf("%d in a row", streakLen),
+			Label:    "The Marathon",
+			Icon:     "fa-bolt",
+			Winner:   streakSender,
+			PersonID: streakPerson,
+			Value:    fmt.Sprintf("%d in a row", streakLen),
 		})
 	}
 
 	// 7. The Curator — most links shared
-	var curatorSender string
+	var curatorPerson, curatorSender string
 	var curatorCount int
 	err = s.pool.QueryRow(ctx, `
-		SELECT m.sender_id, COUNT(*) as cnt
+		SELECT COALESCE(m.person_id::text, m.sender_id) AS who, MAX(m.sender_id), COUNT(*) as cnt
 		FROM urls u JOIN messages m ON u.message_id = m.id
 		WHERE m.created_at > NOW() - INTERVAL '30 days'
 		AND (m.expires_at IS NULL OR m.expires_at > now())
-		GROUP BY m.sender_id ORDER BY cnt DESC LIMIT 1
-	`).Scan(&curatorSender, &curatorCount)
+		AND ($1::text IS NULL OR m.group_id = $1 OR ($1 = $2 AND m.group_id IS NULL))
+		GROUP BY who ORDER BY cnt DESC LIMIT 1
+	`, groupID, DirectGroupID).Scan(&curatorPerson, &curatorSender, &curatorCount)
 	if err == nil {
 		results = append(results, Superlative{
-			Label:  "The Curator",
-			Icon:   "fa-link",
-			Winner: curatorSender,
-			Value:  fmt.Sprintf("%d links", curatorCount),
+			Label:    "The Curator",
+			Icon:     "fa-link",
+			Winner:   curatorSender,
+			PersonID: curatorPerson,
+			Value:    fmt.Sprintf("%d links", curatorCount),
 		})
 	}
 
 	// 8. The Director — most videos shared
-	var directorSender string
+	var directorPerson, directorSender string
 	var directorCount int
 	err = s.pool.QueryRow(ctx, `
-		SELECT m.sender_id, COUNT(*) as cnt
+		SELECT COALESCE(m.person_id::text, m.sender_id) AS who, MAX(m.sender_id), COUNT(*) as cnt
 		FROM attachments a JOIN messages m ON a.message_id = m.id
 		WHERE a.content_type LIKE 'video/%'
 		AND m.created_at > NOW() - INTERVAL '30 days'
 		AND (m.expires_at IS NULL OR m.expires_at > now())
-		GROUP BY m.sender_id ORDER BY cnt DESC LIMIT 1
-	`).Scan(&directorSender, &directorCount)
+		AND ($1::text IS NULL OR m.group_id = $1 OR ($1 = $2 AND m.group_id IS NULL))
+		GROUP BY who ORDER BY cnt DESC LIMIT 1
+	`, groupID, DirectGroupID).Scan(&directorPerson, &directorSender, &directorCount)
 	if err == nil {
 		results = append(results, Superlative{
-			Label:  "The Director",
-			Icon:   "fa-film",
-			Winner: directorSender,
-			Value:  fmt.Sprintf("%d videos", directorCount),
+			Label:    "The Director",
+			Icon:     "fa-film",
+			Winner:   directorSender,
+			PersonID: directorPerson,
+			Value:    fmt.Sprintf("%d videos", directorCount),
 		})
 	}
 
@@ -194,5 +217,15 @@ func (s *Store) GetSuperlatives(ctx context.Context) []Superlative {
 		log.Println("Superlatives: no data available")
 	}
 
+	// Winners are stored for display; keep the raw ID alongside the name
+	ids, err := s.LoadIdentities(ctx)
+	if err != nil {
+		log.Printf("Superlatives: failed to load contact names: %v", err)
+	}
+	for i := range results {
+		results[i].WinnerID = results[i].Winner
+		results[i].Winner = ids.Name(results[i].PersonID)
+	}
+
 	return results
 }
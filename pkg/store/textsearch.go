@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const defaultSearchLanguage = "english"
+
+var regconfigName = regexp.MustCompile(`^[a-z_]+$`)
+
+// SetSearchLanguages sets the text-search configurations used for full-text
+// queries. The first entry is the default for messages whose language was not
+// detected. Names must be Postgres regconfig names (english, spanish,
+// simple...); ones missing from pg_ts_config are dropped with a warning. It
+// returns the configurations kept.
+func (s *Store) SetSearchLanguages(ctx context.Context, langs []string) ([]string, error) {
+	rows, err := s.pool.Query(ctx, `SELECT cfgname FROM pg_ts_config`)
+	if err != nil {
+		return nil, fmt.Errorf("list text search configurations: %w", err)
+	}
+	known, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("list text search configurations: %w", err)
+	}
+	s.tsConfigs = make(map[string]bool, len(known))
+	for _, c := range known {
+		s.tsConfigs[c] = true
+	}
+
+	var configs []string
+	for _, l := range langs {
+		l = strings.ToLower(strings.TrimSpace(l))
+		if l == "" {
+			continue
+		}
+		if !s.IsSearchLanguage(l) {
+			log.Printf("Warning: unknown text search configuration %q, ignoring it", l)
+			continue
+		}
+		configs = append(configs, l)
+	}
+	s.searchLangs = configs
+	return configs, nil
+}
+
+// IsSearchLanguage reports whether name is a text-search configuration
+// Postgres knows. Before SetSearchLanguages has loaded them it only checks
+// that name is a plausible regconfig name.
+func (s *Store) IsSearchLanguage(name string) bool {
+	if !regconfigName.MatchString(name) {
+		return false
+	}
+	return s.tsConfigs == nil || s.tsConfigs[name]
+}
+
+// DefaultSearchLanguage returns the configuration new messages are indexed with.
+func (s *Store) DefaultSearchLanguage() string {
+	if len(s.searchLangs) == 0 {
+		return defaultSearchLanguage
+	}
+	return s.searchLangs[0]
+}
+
+// tsQuery returns a tsquery expression over the query text in the given
+// placeholder, OR-ing one plainto_tsquery per configured language so messages
+// indexed under any of them can match. A non-nil override restricts the query
+// to that single configuration.
+func (s *Store) tsQuery(placeholder string, override *string) string {
+	configs := s.searchLangs
+	if override != nil && s.IsSearchLanguage(*override) {
+		configs = []string{*override}
+	}
+	if len(configs) == 0 {
+		configs = []string{defaultSearchLanguage}
+	}
+
+	parts := make([]string, len(configs))
+	for i, c := range configs {
+		parts[i] = fmt.Sprintf("plainto_tsquery('%s', %s)", c, placeholder)
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, " || ") + ")"
+}
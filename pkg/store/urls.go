@@ -2,6 +2,10 @@ package store
 
 import (
 	"context"
+	"fmt"
+	"strings"
+
+	pgvector "github.com/pgvector/pgvector-go"
 )
 
 func (s *Store) SaveURL(ctx context.Context, u URLRecord) (string, error) {
@@ -104,3 +108,63 @@ func (s *Store) MarkURLFetched(ctx context.Context, id, title, description, imag
 	_, err := s.pool.Exec(ctx, query, id, title, description, imageURL)
 	return err
 }
+
+// SearchURLs ranks link previews against query, either by keyword match over
+// title, description and URL, or (semantic) by cosine similarity to embedding.
+// Similarity holds the rank in both modes.
+func (s *Store) SearchURLs(ctx context.Context, query string, embedding []float32, threshold float64, domain *string, limit int) ([]URLRecord, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var conditions []string
+	var args []any
+	var rank string
+	if embedding != nil {
+		args = append(args, pgvector.NewVector(embedding), s.embeddingModel, threshold)
+		conditions = append(conditions, "embedding IS NOT NULL", "embedding_model = $2", "1 - (embedding <=> $1) > $3")
+		rank = "1 - (embedding <=> $1)"
+	} else {
+		args = append(args, query)
+		doc := fmt.Sprintf("to_tsvector('%s', COALESCE(title, '') || ' ' || COALESCE(description, '') || ' ' || url)",
+			s.DefaultSearchLanguage())
+		tsq := s.tsQuery("$1", nil)
+		conditions = append(conditions, doc+" @@ "+tsq)
+		rank = fmt.Sprintf("ts_rank(%s, %s)", doc, tsq)
+	}
+	if domain != nil {
+		args = append(args, *domain)
+		conditions = append(conditions, fmt.Sprintf("domain = $%d", len(args)))
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT id, message_id, url, domain, COALESCE(title,''), COALESCE(description,''), COALESCE(image_url,''), fetched, created_at,
+			%s AS rank
+		FROM urls
+		WHERE %s
+		ORDER BY rank DESC
+		LIMIT $%d
+	`, rank, strings.Join(conditions, " AND "), len(args)+1)
+	args = append(args, limit)
+
+	rows, err := s.pool.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var urls []URLRecord
+	for rows.Next() {
+		var u URLRecord
+		var rank float64
+		if err := rows.Scan(
+			&u.ID, &u.MessageID, &u.URL, &u.Domain, &u.Title, &u.Description,
+			&u.ImageURL, &u.Fetched, &u.CreatedAt, &rank,
+		); err != nil {
+			return nil, err
+		}
+		u.Similarity = &rank
+		urls = append(urls, u)
+	}
+	return urls, nil
+}
@@ -0,0 +1,53 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+func (s *Store) SaveUsage(ctx context.Context, u UsageRecord) error {
+	_, err := s.pool.Exec(ctx, `
+		INSERT INTO llm_usage (feature, provider, model, input_tokens, output_tokens, cost_usd, success)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, u.Feature, u.Provider, u.Model, u.InputTokens, u.OutputTokens, u.CostUSD, u.Success)
+	return err
+}
+
+// GetUsageSummary aggregates calls since the given time by feature, provider and model.
+func (s *Store) GetUsageSummary(ctx context.Context, since time.Time) ([]UsageSummary, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT feature, provider, model, COUNT(*), COUNT(*) FILTER (WHERE NOT success),
+			COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+			COALESCE(SUM(cost_usd), 0)::float8
+		FROM llm_usage
+		WHERE created_at >= $1
+		GROUP BY feature, provider, model
+		ORDER BY SUM(cost_usd) DESC, feature
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summary []UsageSummary
+	for rows.Next() {
+		var u UsageSummary
+		if err := rows.Scan(&u.Feature, &u.Provider, &u.Model, &u.Calls, &u.Failures,
+			&u.InputTokens, &u.OutputTokens, &u.CostUSD); err != nil {
+			return nil, err
+		}
+		summary = append(summary, u)
+	}
+	return summary, nil
+}
+
+// GetUsageCost returns the estimated spend since the given time; an empty
+// feature sums every feature.
+func (s *Store) GetUsageCost(ctx context.Context, feature string, since time.Time) (float64, error) {
+	var cost float64
+	err := s.pool.QueryRow(ctx, `
+		SELECT COALESCE(SUM(cost_usd), 0)::float8 FROM llm_usage
+		WHERE created_at >= $1 AND ($2 = '' OR feature = $2)
+	`, since, feature).Scan(&cost)
+	return cost, err
+}
@@ -0,0 +1,55 @@
+package usage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Budget caps estimated spend for one feature per day or month. Feature "*"
+// applies to the total across all features.
+type Budget struct {
+	Feature string  `json:"feature"`
+	Period  string  `json:"period"` // "day" or "month"
+	Limit   float64 `json:"limit_usd"`
+}
+
+// ParseBudgets parses "feature=amount/period,..." e.g. "vision=1/day,*=50/month".
+// The period defaults to month.
+func ParseBudgets(s string) ([]Budget, error) {
+	var budgets []Budget
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		feature, rest, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid budget %q (want feature=amount/period)", entry)
+		}
+		amount, period, _ := strings.Cut(rest, "/")
+		period = strings.TrimSpace(period)
+		if period == "" {
+			period = "month"
+		}
+		if period != "day" && period != "month" {
+			return nil, fmt.Errorf("invalid budget period %q (want day or month)", period)
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimPrefix(amount, "$")), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid budget amount in %q", entry)
+		}
+		budgets = append(budgets, Budget{Feature: strings.TrimSpace(feature), Period: period, Limit: limit})
+	}
+	return budgets, nil
+}
+
+// PeriodStart returns the start of the budget period containing t.
+func PeriodStart(period string, t time.Time) time.Time {
+	y, m, d := t.Date()
+	if period == "day" {
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,149 @@
+// Package usage records LLM, vision and image-generation calls with their
+// estimated cost, and enforces per-feature spending budgets.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"signal-sideband/pkg/llm"
+	"signal-sideband/pkg/store"
+)
+
+// Features recorded in the ledger. The LLM use cases share llm's names.
+const (
+	FeatureDigest     = llm.UseDigest
+	FeatureInsights   = llm.UseInsights
+	FeatureExtraction = llm.UseExtraction
+	FeatureEnrichment = llm.UseEnrichment
+	FeatureAsk        = llm.UseAsk
+	FeatureVision     = "vision"
+	FeaturePicOfDay   = "picofday"
+)
+
+// Ledger writes one llm_usage row per call and checks budgets before calls.
+// A nil *Ledger is valid and records nothing.
+type Ledger struct {
+	store   *store.Store
+	prices  PriceTable
+	budgets []Budget
+}
+
+func NewLedger(s *store.Store, prices PriceTable, budgets []Budget) *Ledger {
+	if prices == nil {
+		prices, _ = ParsePrices("")
+	}
+	return &Ledger{store: s, prices: prices, budgets: budgets}
+}
+
+// Allow returns an error wrapping llm.ErrBudgetExceeded if any budget that
+// covers feature is spent for the current period.
+func (l *Ledger) Allow(ctx context.Context, feature string) error {
+	if l == nil {
+		return nil
+	}
+	now := time.Now()
+	for _, b := range l.budgets {
+		if b.Feature != feature && b.Feature != "*" {
+			continue
+		}
+		scope := b.Feature
+		if scope == "*" {
+			scope = ""
+		}
+		spent, err := l.store.GetUsageCost(ctx, scope, PeriodStart(b.Period, now))
+		if err != nil {
+			return fmt.Errorf("check budget: %w", err)
+		}
+		if spent >= b.Limit {
+			return fmt.Errorf("%w: %s spent $%.2f of $%.2f this %s", llm.ErrBudgetExceeded, b.Feature, spent, b.Limit, b.Period)
+		}
+	}
+	return nil
+}
+
+// Record stores one call. callErr marks the row as failed.
+func (l *Ledger) Record(ctx context.Context, feature, provider, model string, inputTokens, outputTokens int, callErr error) {
+	if l == nil {
+		return
+	}
+	rec := store.UsageRecord{
+		Feature:      feature,
+		Provider:     provider,
+		Model:        model,
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		CostUSD:      l.prices.Cost(model, inputTokens, outputTokens),
+		Success:      callErr == nil,
+	}
+	// Record even when the caller's context was cancelled mid-call
+	if err := l.store.SaveUsage(context.WithoutCancel(ctx), rec); err != nil {
+		log.Printf("usage: record %s call failed: %v", feature, err)
+	}
+}
+
+// BudgetStatus reports spend against one budget for the current period.
+type BudgetStatus struct {
+	Budget
+	Since    time.Time `json:"since"`
+	Spent    float64   `json:"spent_usd"`
+	Exceeded bool      `json:"exceeded"`
+}
+
+// Status returns every configured budget with its current spend.
+func (l *Ledger) Status(ctx context.Context) ([]BudgetStatus, error) {
+	statuses := []BudgetStatus{}
+	if l == nil {
+		return statuses, nil
+	}
+	now := time.Now()
+	for _, b := range l.budgets {
+		scope := b.Feature
+		if scope == "*" {
+			scope = ""
+		}
+		since := PeriodStart(b.Period, now)
+		spent, err := l.store.GetUsageCost(ctx, scope, since)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, BudgetStatus{Budget: b, Since: since, Spent: spent, Exceeded: spent >= b.Limit})
+	}
+	return statuses, nil
+}
+
+// Meter wraps p so every call is budget-checked and recorded under feature.
+func (l *Ledger) Meter(feature string, p llm.Provider) llm.Provider {
+	if l == nil || p == nil {
+		return p
+	}
+	return &meteredProvider{next: p, ledger: l, feature: feature}
+}
+
+type meteredProvider struct {
+	next    llm.Provider
+	ledger  *Ledger
+	feature string
+}
+
+func (m *meteredProvider) Name() string         { return m.next.Name() }
+func (m *meteredProvider) Unwrap() llm.Provider { return m.next }
+
+func (m *meteredProvider) Complete(ctx context.Context, req llm.CompletionRequest) (*llm.CompletionResponse, error) {
+	if err := m.ledger.Allow(ctx, m.feature); err != nil {
+		return nil, err
+	}
+	resp, err := m.next.Complete(ctx, req)
+	if err != nil {
+		m.ledger.Record(ctx, m.feature, m.next.Name(), "", 0, 0, err)
+		return nil, err
+	}
+	provider := resp.Provider
+	if provider == "" {
+		provider = m.next.Name()
+	}
+	m.ledger.Record(ctx, m.feature, provider, resp.Model, resp.InputTokens, resp.OutputTokens, nil)
+	return resp, nil
+}
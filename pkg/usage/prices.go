@@ -0,0 +1,81 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Price is the USD cost per million input and output tokens.
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// DefaultPrices covers the models the app uses out of the box. Override or
+// extend with LLM_PRICES.
+var DefaultPrices = map[string]Price{
+	"claude-sonnet-4-5":      {Input: 3, Output: 15},
+	"gpt-4o":                 {Input: 2.5, Output: 10},
+	"gpt-4o-mini":            {Input: 0.15, Output: 0.6},
+	"grok-3-mini-fast":       {Input: 0.6, Output: 4},
+	"grok-3-mini":            {Input: 0.3, Output: 0.5},
+	"grok-2-vision":          {Input: 2, Output: 10},
+	"sonar":                  {Input: 1, Output: 1},
+	"gemini-2.5-flash-image": {Input: 0.3, Output: 30},
+}
+
+// PriceTable maps model names (or name prefixes) to prices.
+type PriceTable map[string]Price
+
+// ParsePrices parses "model=in/out,model2=in/out" (USD per 1M tokens) on top
+// of DefaultPrices.
+func ParsePrices(s string) (PriceTable, error) {
+	table := PriceTable{}
+	for k, v := range DefaultPrices {
+		table[k] = v
+	}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, rates, ok := strings.Cut(entry, "=")
+		in, out, ok2 := strings.Cut(rates, "/")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("invalid price %q (want model=input/output)", entry)
+		}
+		inPrice, err1 := strconv.ParseFloat(strings.TrimSpace(in), 64)
+		outPrice, err2 := strconv.ParseFloat(strings.TrimSpace(out), 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid price %q", entry)
+		}
+		table[strings.TrimSpace(model)] = Price{Input: inPrice, Output: outPrice}
+	}
+	return table, nil
+}
+
+// Cost estimates the USD cost of a call. Models are matched exactly, then by
+// the longest prefix, so dated names like "gpt-4o-2024-08-06" find "gpt-4o".
+// Unknown models cost 0.
+func (t PriceTable) Cost(model string, inputTokens, outputTokens int) float64 {
+	p, ok := t[model]
+	if !ok {
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+		for _, k := range keys {
+			if strings.HasPrefix(model, k) {
+				p, ok = t[k], true
+				break
+			}
+		}
+	}
+	if !ok {
+		return 0
+	}
+	return (float64(inputTokens)*p.Input + float64(outputTokens)*p.Output) / 1e6
+}
@@ -0,0 +1,57 @@
+package usage
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPriceTableCost(t *testing.T) {
+	prices, err := ParsePrices("my-local-model=0/0, gpt-4o=5/20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Override wins, and dated model names match by prefix
+	if got := prices.Cost("gpt-4o-2024-08-06", 1_000_000, 100_000); math.Abs(got-7) > 1e-9 {
+		t.Fatalf("gpt-4o cost = %v, want 7", got)
+	}
+	// Longest prefix: gpt-4o-mini must not be priced as gpt-4o
+	if got := prices.Cost("gpt-4o-mini-2024-07-18", 1_000_000, 0); math.Abs(got-0.15) > 1e-9 {
+		t.Fatalf("gpt-4o-mini cost = %v, want 0.15", got)
+	}
+	if got := prices.Cost("unknown", 1000, 1000); got != 0 {
+		t.Fatalf("unknown model cost = %v", got)
+	}
+	if _, err := ParsePrices("bad"); err == nil {
+		t.Fatal("expected error for malformed price")
+	}
+}
+
+func TestParseBudgets(t *testing.T) {
+	budgets, err := ParseBudgets("vision=1.5/day, *=$50, digest=10/month")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(budgets) != 3 {
+		t.Fatalf("got %d budgets", len(budgets))
+	}
+	if b := budgets[0]; b.Feature != "vision" || b.Period != "day" || b.Limit != 1.5 {
+		t.Fatalf("vision budget = %+v", b)
+	}
+	if b := budgets[1]; b.Feature != "*" || b.Period != "month" || b.Limit != 50 {
+		t.Fatalf("total budget = %+v", b)
+	}
+	if _, err := ParseBudgets("vision=1/week"); err == nil {
+		t.Fatal("expected error for unknown period")
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	now := time.Date(2026, 3, 17, 15, 4, 5, 0, time.UTC)
+	if got := PeriodStart("day", now); !got.Equal(time.Date(2026, 3, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("day start = %v", got)
+	}
+	if got := PeriodStart("month", now); !got.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("month start = %v", got)
+	}
+}
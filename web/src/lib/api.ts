@@ -1,4 +1,4 @@
-import type { Stats, PaginatedResponse, MessageRecord, SearchResult, GroupWithCount, DigestRecord, URLRecord, AttachmentRecord, MediaSearchResult, CerebroGraph, CerebroConceptDetail, CerebroExtraction, ContactRecord, DailyInsight } from './types.ts'
+import type { Stats, PaginatedResponse, MessageRecord, SearchResult, GroupWithCount, GroupDetail, DigestRecord, DigestLens, URLRecord, AttachmentRecord, MediaSearchResult, CerebroGraph, CerebroConceptDetail, CerebroExtraction, ContactRecord, DailyInsight } from './types.ts'
 
 const BASE = '/api'
 const TOKEN_KEY = 'auth_token'
@@ -55,8 +55,13 @@ export async function login(password: string): Promise<string> {
   return data.token
 }
 
-export function getStats() {
-  return fetchJSON<Stats>(`${BASE}/stats`)
+// groupQuery scopes a request to one group; without it the server covers all groups.
+function groupQuery(groupId?: string, sep = '?') {
+  return groupId ? `${sep}group_id=${encodeURIComponent(groupId)}` : ''
+}
+
+export function getStats(groupId?: string) {
+  return fetchJSON<Stats>(`${BASE}/stats${groupQuery(groupId)}`)
 }
 
 export function getMessages(params: Record<string, string> = {}) {
@@ -86,6 +91,10 @@ export function getGroups() {
   return fetchJSON<GroupWithCount[]>(`${BASE}/groups`)
 }
 
+export function getGroup(groupId: string) {
+  return fetchJSON<GroupDetail>(`${BASE}/groups/${encodeURIComponent(groupId)}`)
+}
+
 export function getDigests(params: Record<string, string> = {}) {
   const qs = new URLSearchParams(params).toString()
   return fetchJSON<PaginatedResponse<DigestRecord>>(`${BASE}/digests?${qs}`)
@@ -103,6 +112,10 @@ export function generateDigest(periodStart: string, periodEnd: string, groupId?:
   })
 }
 
+export function getLenses() {
+  return fetchJSON<DigestLens[]>(`${BASE}/lenses`)
+}
+
 export function getURLs(params: Record<string, string> = {}) {
   const qs = new URLSearchParams(params).toString()
   return fetchJSON<PaginatedResponse<URLRecord>>(`${BASE}/urls?${qs}`)
@@ -121,22 +134,22 @@ export function mediaThumbnailURL(id: string) {
   return `${BASE}/media/${id}/thumb`
 }
 
-export function picOfDayURL() {
-  return `${BASE}/potd`
+export function picOfDayURL(groupId?: string) {
+  return `${BASE}/potd${groupQuery(groupId)}`
 }
 
-export function generateInsight() {
-  return fetchJSON<any>(`${BASE}/insights/generate`, {
+export function generateInsight(groupId?: string) {
+  return fetchJSON<any>(`${BASE}/insights/generate${groupQuery(groupId)}`, {
     method: 'POST',
   })
 }
 
-export function getSnapshots(days = 7) {
-  return fetchJSON<DailyInsight[]>(`${BASE}/snapshots?days=${days}`)
+export function getSnapshots(days = 7, groupId?: string) {
+  return fetchJSON<DailyInsight[]>(`${BASE}/snapshots?days=${days}${groupQuery(groupId, '&')}`)
 }
 
-export function generatePicOfDay() {
-  return fetchJSON<{ image_path: string; insight_id: string }>(`${BASE}/potd/generate`, {
+export function generatePicOfDay(groupId?: string) {
+  return fetchJSON<{ image_path: string; insight_id: string }>(`${BASE}/potd/generate${groupQuery(groupId)}`, {
     method: 'POST',
   })
 }
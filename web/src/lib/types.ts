@@ -9,6 +9,7 @@ export interface DailyInsight {
   superlatives?: Superlative[]
   snapshot: DaySnapshot | null
   snapshot_date: string | null
+  group_id?: string
   created_at: string
 }
 
@@ -16,10 +17,10 @@ export interface DaySnapshot {
   message_count: number
   active_senders: number
   busiest_hour: number
-  crews: Record<string, { sender_id: string; count: number }[]>
-  top_pairs: { sender_a: string; sender_b: string; count: number }[]
-  verb_leader: { sender_id: string; count: number; samples: string[] } | null
-  link_of_day: { url: string; title: string; sender_id: string } | null
+  crews: Record<string, { person_id?: string; sender_id: string; name?: string; count: number }[]>
+  top_pairs: { person_a?: string; person_b?: string; sender_a: string; sender_b: string; name_a?: string; name_b?: string; count: number }[]
+  verb_leader: { person_id?: string; sender_id: string; name?: string; count: number; samples: string[] } | null
+  link_of_day: { url: string; title: string; sender_id: string; person_id?: string; name?: string } | null
   yesterday_ref: { quote?: string; link?: { url: string; title: string; sender_id: string } } | null
   is_weekly: boolean
   weekly_total?: number
@@ -41,6 +42,8 @@ export interface Superlative {
   label: string
   icon: string
   winner: string
+  winner_id?: string
+  person_id?: string
   value: string
 }
 
@@ -86,13 +89,43 @@ export interface GroupWithCount {
   group_id: string
   name: string
   description: string
-  avatar_path: string
+  avatar_url?: string
   member_count: number
   message_count: number
   created_at: string
   updated_at: string
 }
 
+export interface GroupMember {
+  member_id: string
+  person_id?: string
+  name: string
+  is_admin: boolean
+  joined_at: string
+  left_at?: string
+  source_uuid?: string
+  phone_number?: string
+  profile_name?: string
+  alias?: string
+  avatar_url?: string
+  message_count: number
+}
+
+export interface GroupDetail extends GroupWithCount {
+  members: GroupMember[]
+}
+
+export interface DigestLens {
+  name: string
+  label: string
+  system_prompt: string
+  temperature: number
+  schema_variant: 'standard' | 'brief' | 'custom'
+  builtin: boolean
+  created_at: string
+  updated_at: string
+}
+
 export interface DigestRecord {
   id: string
   title: string
@@ -102,6 +135,7 @@ export interface DigestRecord {
   action_items: string[]
   period_start: string
   period_end: string
+  period_kind: 'daily' | 'weekly' | 'monthly' | 'custom'
   group_id: string | null
   llm_provider: string
   llm_model: string
@@ -145,6 +179,7 @@ export interface ContactRecord {
   phone_number: string
   profile_name: string
   alias: string
+  avatar_url?: string
   sender_id: string
 }
 
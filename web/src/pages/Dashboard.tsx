@@ -1,7 +1,7 @@
 import { useState, useMemo } from 'react'
 import { useQuery, useQueryClient } from '@tanstack/react-query'
 import { useNavigate } from 'react-router-dom'
-import { getStats, getMessages, getSnapshots, generateDigest, generateInsight } from '../lib/api.ts'
+import { getStats, getMessages, getSnapshots, getLenses, getGroups, generateDigest, generateInsight } from '../lib/api.ts'
 import { useContacts } from '../lib/useContacts.ts'
 import type { DailyInsight, DaySnapshot } from '../lib/types.ts'
 import Card from '../components/Card.tsx'
@@ -20,18 +20,36 @@ const CREW_META: Record<string, { icon: string; label: string }> = {
   night:     { icon: 'fa-moon-stars',  label: 'Night' },
 }
 
+const LENS_META: Record<string, { icon: string; desc: string }> = {
+  default:    { icon: 'fa-newspaper',     desc: 'Straight newsletter' },
+  gondor:     { icon: 'fa-shield-halved', desc: 'Chronicles of the Citadel' },
+  confucius:  { icon: 'fa-book-open',     desc: 'The Master says...' },
+  'city-wok': { icon: 'fa-fire',          desc: 'Goddamn Mongorians!' },
+}
+
 export default function Dashboard() {
   const navigate = useNavigate()
   const queryClient = useQueryClient()
   const { resolveName } = useContacts()
-  const { data: stats, isLoading } = useQuery({ queryKey: ['stats'], queryFn: getStats })
+  // Insights and stats are per group; default to the busiest one
+  const { data: groups, isLoading: groupsLoading } = useQuery({ queryKey: ['groups'], queryFn: getGroups })
+  const [pickedGroup, setPickedGroup] = useState<string>('')
+  const groupId = pickedGroup || [...(groups ?? [])].sort((a, b) => b.message_count - a.message_count)[0]?.group_id
+
+  const { data: stats, isLoading } = useQuery({
+    queryKey: ['stats', groupId],
+    queryFn: () => getStats(groupId),
+    enabled: !groupsLoading,
+  })
   const { data: recent } = useQuery({
-    queryKey: ['messages', 'recent'],
-    queryFn: () => getMessages({ limit: '5' }),
+    queryKey: ['messages', 'recent', groupId],
+    queryFn: () => getMessages({ limit: '5', ...(groupId && { group_id: groupId }) }),
+    enabled: !groupsLoading,
   })
   const { data: snapshots } = useQuery({
-    queryKey: ['snapshots'],
-    queryFn: () => getSnapshots(7),
+    queryKey: ['snapshots', groupId],
+    queryFn: () => getSnapshots(7, groupId),
+    enabled: !groupsLoading,
   })
 
   const [selectedDate, setSelectedDate] = useState<string>(format(new Date(), 'yyyy-MM-dd'))
@@ -58,12 +76,13 @@ export default function Dashboard() {
     ) ?? null
   }, [snapshots, selectedDate])
 
-  const lenses = [
-    { id: 'default', label: 'Standard', icon: 'fa-newspaper', desc: 'Straight newsletter' },
-    { id: 'gondor', label: 'Gondor', icon: 'fa-shield-halved', desc: 'Chronicles of the Citadel' },
-    { id: 'confucius', label: 'Confucius', icon: 'fa-book-open', desc: 'The Master says...' },
-    { id: 'city-wok', label: 'City Wok', icon: 'fa-fire', desc: 'Goddamn Mongorians!' },
-  ]
+  const { data: lensRecords } = useQuery({ queryKey: ['lenses'], queryFn: getLenses })
+  const lenses = useMemo(() => (lensRecords ?? []).map(l => ({
+    id: l.name,
+    label: l.label || l.name,
+    icon: LENS_META[l.name]?.icon ?? 'fa-glasses',
+    desc: LENS_META[l.name]?.desc ?? `${l.schema_variant} digest`,
+  })), [lensRecords])
 
   const showError = (msg: string) => {
     setError(msg)
@@ -74,7 +93,7 @@ export default function Dashboard() {
     setGeneratingInsight(true)
     setError(null)
     try {
-      await generateInsight()
+      await generateInsight(groupId)
       queryClient.invalidateQueries({ queryKey: ['stats'] })
       queryClient.invalidateQueries({ queryKey: ['snapshots'] })
     } catch (e: any) {
@@ -94,7 +113,7 @@ export default function Dashboard() {
       await generateDigest(
         format(yesterday, "yyyy-MM-dd'T'HH:mm:ssxxx"),
         format(now, "yyyy-MM-dd'T'HH:mm:ssxxx"),
-        undefined,
+        groupId,
         lens === 'default' ? undefined : lens,
       )
       queryClient.invalidateQueries({ queryKey: ['stats'] })
@@ -107,7 +126,7 @@ export default function Dashboard() {
     }
   }
 
-  if (isLoading) return <LoadingSpinner />
+  if (isLoading || groupsLoading) return <LoadingSpinner />
 
   const statCards = [
     { label: 'Total Messages', value: stats?.total_messages ?? 0, icon: 'fa-comments' },
@@ -118,7 +137,21 @@ export default function Dashboard() {
 
   return (
     <div>
-      <h2 className="text-2xl font-semibold tracking-tight mb-6">Dashboard</h2>
+      <div className="flex items-center justify-between gap-4 mb-6 flex-wrap">
+        <h2 className="text-2xl font-semibold tracking-tight">Dashboard</h2>
+        {groups && groups.length > 0 && (
+          <select
+            value={groupId ?? ''}
+            onChange={e => setPickedGroup(e.target.value)}
+            className="px-3 py-2 rounded-lg border border-apple-border bg-apple-card text-sm"
+          >
+            {groups.map(g => (
+              <option key={g.group_id} value={g.group_id}>{g.name || g.group_id}</option>
+            ))}
+            <option value="direct">Direct messages</option>
+          </select>
+        )}
+      </div>
 
       {/* Error banner */}
       {error && (
@@ -279,7 +312,7 @@ export default function Dashboard() {
                 <i className={`fawsb ${s.icon} text-apple-blue text-lg mb-2 block`} />
                 <p className="text-xs font-semibold text-apple-blue uppercase tracking-wide">{s.label}</p>
                 <p className="text-sm text-apple-text font-medium mt-1 truncate" title={s.winner}>
-                  {s.winner === 'self' ? 'You' : s.winner}
+                  {(s.winner_id ?? s.winner) === 'self' ? 'You' : s.winner}
                 </p>
                 <p className="text-xs text-apple-secondary mt-0.5">{s.value}</p>
               </Card>
@@ -426,7 +459,7 @@ function SnapshotCard({
                   <div className="space-y-1">
                     {members.slice(0, 3).map((m, i) => (
                       <div key={i} className="flex items-center justify-between text-xs">
-                        <span className="text-apple-text truncate">{resolveName(m.sender_id)}</span>
+                        <span className="text-apple-text truncate">{m.name || resolveName(m.sender_id)}</span>
                         <span className="text-apple-secondary font-mono ml-2">{m.count}</span>
                       </div>
                     ))}
@@ -449,7 +482,7 @@ function SnapshotCard({
             {snap.top_pairs.map((pair, i) => (
               <div key={i} className="flex items-center justify-between text-sm">
                 <span className="text-apple-text">
-                  {resolveName(pair.sender_a)} <span className="text-apple-secondary mx-1">&harr;</span> {resolveName(pair.sender_b)}
+                  {pair.name_a || resolveName(pair.sender_a)} <span className="text-apple-secondary mx-1">&harr;</span> {pair.name_b || resolveName(pair.sender_b)}
                 </span>
                 <span className="text-xs text-apple-secondary font-mono">{pair.count} exchanges</span>
               </div>
@@ -466,7 +499,7 @@ function SnapshotCard({
             <h3 className="text-lg font-medium">Verb Champion</h3>
           </div>
           <p className="text-sm text-apple-text">
-            <span className="font-medium">{resolveName(snap.verb_leader.sender_id)}</span>
+            <span className="font-medium">{snap.verb_leader.name || resolveName(snap.verb_leader.sender_id)}</span>
             <span className="text-apple-secondary"> &mdash; {snap.verb_leader.count} verbs</span>
           </p>
           {snap.verb_leader.samples.length > 0 && (
@@ -493,7 +526,7 @@ function SnapshotCard({
             {snap.link_of_day.title || snap.link_of_day.url}
           </a>
           <p className="text-xs text-apple-secondary mt-1">
-            shared by {resolveName(snap.link_of_day.sender_id)}
+            shared by {snap.link_of_day.name || resolveName(snap.link_of_day.sender_id)}
           </p>
         </Card>
       )}
@@ -1,7 +1,7 @@
 import { useState } from 'react'
 import { useQuery } from '@tanstack/react-query'
 import { useNavigate } from 'react-router-dom'
-import { getDigests } from '../lib/api.ts'
+import { getDigests, getGroups } from '../lib/api.ts'
 import Card from '../components/Card.tsx'
 import Badge from '../components/Badge.tsx'
 import LoadingSpinner from '../components/LoadingSpinner.tsx'
@@ -9,21 +9,67 @@ import EmptyState from '../components/EmptyState.tsx'
 import Pagination from '../components/Pagination.tsx'
 import { format } from 'date-fns'
 
+const KINDS = [
+  { value: '', label: 'All' },
+  { value: 'weekly', label: 'Weekly' },
+  { value: 'monthly', label: 'Monthly' },
+  { value: 'daily', label: 'Daily' },
+  { value: 'custom', label: 'Custom' },
+]
+
 export default function Digests() {
   const navigate = useNavigate()
   const [offset, setOffset] = useState(0)
+  const [kind, setKind] = useState('')
+  const [groupId, setGroupId] = useState('')
   const limit = 12
 
+  const { data: groups } = useQuery({ queryKey: ['groups'], queryFn: getGroups })
   const { data, isLoading } = useQuery({
-    queryKey: ['digests', offset],
-    queryFn: () => getDigests({ limit: String(limit), offset: String(offset) }),
+    queryKey: ['digests', kind, groupId, offset],
+    queryFn: () => getDigests({
+      limit: String(limit),
+      offset: String(offset),
+      ...(kind && { period_kind: kind }),
+      ...(groupId && { group_id: groupId }),
+    }),
   })
+  const groupName = (id: string | null) => groups?.find(g => g.group_id === id)?.name
 
   if (isLoading) return <LoadingSpinner />
 
   return (
     <div>
-      <h2 className="text-2xl font-semibold tracking-tight mb-6">Digests</h2>
+      <div className="flex items-center justify-between gap-4 mb-6 flex-wrap">
+        <h2 className="text-2xl font-semibold tracking-tight">Digests</h2>
+        <div className="flex gap-1 flex-wrap">
+          {groups && groups.length > 1 && (
+            <select
+              value={groupId}
+              onChange={e => { setGroupId(e.target.value); setOffset(0) }}
+              className="px-3 py-1.5 mr-2 rounded-lg border border-apple-border bg-apple-card text-sm"
+            >
+              <option value="">All groups</option>
+              {groups.map(g => (
+                <option key={g.group_id} value={g.group_id}>{g.name || g.group_id}</option>
+              ))}
+            </select>
+          )}
+          {KINDS.map(k => (
+            <button
+              key={k.value}
+              onClick={() => { setKind(k.value); setOffset(0) }}
+              className={`px-3 py-1.5 rounded-lg text-sm font-medium transition-all
+                ${kind === k.value
+                  ? 'bg-apple-blue text-white shadow-sm'
+                  : 'bg-apple-card border border-apple-border text-apple-text hover:border-apple-blue/40'
+                }`}
+            >
+              {k.label}
+            </button>
+          ))}
+        </div>
+      </div>
 
       {(!data?.data || data.data.length === 0) ? (
         <EmptyState
@@ -44,12 +90,16 @@ export default function Digests() {
                   {digest.summary.slice(0, 180)}...
                 </p>
                 <div className="flex items-center gap-2 flex-wrap mb-2">
+                  {(digest.period_kind === 'weekly' || digest.period_kind === 'monthly') && (
+                    <Badge variant="green">{digest.period_kind}</Badge>
+                  )}
                   {(digest.topics || []).slice(0, 3).map((topic: string) => (
                     <Badge key={topic}>{topic}</Badge>
                   ))}
                 </div>
                 <p className="text-xs text-apple-secondary">
                   {format(new Date(digest.period_start), 'MMM d')} – {format(new Date(digest.period_end), 'MMM d, yyyy')}
+                  {!groupId && groupName(digest.group_id) && <span> · {groupName(digest.group_id)}</span>}
                 </p>
               </Card>
             ))}